@@ -578,7 +578,22 @@ func (b *SimulatedBackend) EstimateGas(ctx context.Context, call ethereum.CallMs
 				}
 				return 0, result.Err
 			}
-			// Otherwise, the specified gas cap is too low
+			// Otherwise, the specified gas cap is too low. If the call would
+			// succeed within the block gas limit, report the gas it needs.
+			if ceiling := b.pendingBlock.GasLimit(); ceiling > cap {
+				if failed, _, err := executable(ceiling); err == nil && !failed {
+					lo, hi := cap, ceiling
+					for lo+1 < hi {
+						mid := (hi + lo) / 2
+						if failed, _, err := executable(mid); err != nil || failed {
+							lo = mid
+						} else {
+							hi = mid
+						}
+					}
+					return 0, fmt.Errorf("gas required exceeds allowance (%d), would need %d", cap, hi)
+				}
+			}
 			return 0, fmt.Errorf("gas required exceeds allowance (%d)", cap)
 		}
 	}
@@ -814,6 +829,12 @@ func (m callMsg) Gas() uint64                  { return m.CallMsg.Gas }
 func (m callMsg) Value() *big.Int              { return m.CallMsg.Value }
 func (m callMsg) Data() []byte                 { return m.CallMsg.Data }
 func (m callMsg) AccessList() types.AccessList { return m.CallMsg.AccessList }
+func (m callMsg) BlobGasFeeCap() *big.Int      { return nil }
+func (m callMsg) BlobHashes() []common.Hash    { return nil }
+func (m callMsg) GasPayer() *common.Address    { return nil }
+func (m callMsg) Size() uint64                 { return 0 }
+
+func (m callMsg) SetCodeAuthorizations() []types.SetCodeAuthorization { return nil }
 
 // filterBackend implements filters.Backend to support filtering for logs without
 // taking bloom-bits acceleration structures into account.
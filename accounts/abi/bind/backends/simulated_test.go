@@ -506,6 +506,15 @@ func TestEstimateGas(t *testing.T) {
 			Value:    nil,
 			Data:     common.Hex2Bytes("e09fface"),
 		}, 21275, nil, nil},
+
+		{"Valid above allowance", ethereum.CallMsg{
+			From:     addr,
+			To:       &contractAddr,
+			Gas:      21100,
+			GasPrice: big.NewInt(0),
+			Value:    nil,
+			Data:     common.Hex2Bytes("e09fface"),
+		}, 0, errors.New("gas required exceeds allowance (21100), would need 21275"), nil},
 	}
 	for _, c := range cases {
 		got, err := sim.EstimateGas(context.Background(), c.message)
@@ -579,7 +588,7 @@ func TestEstimateGasWithPrice(t *testing.T) {
 			GasPrice: big.NewInt(2e14), // gascost = 4.2ether
 			Value:    big.NewInt(100000000000),
 			Data:     nil,
-		}, 21000, errors.New("gas required exceeds allowance (10999)")}, // 10999=(2.2ether-1000wei)/(2e14)
+		}, 21000, errors.New("gas required exceeds allowance (10999), would need 21000")}, // 10999=(2.2ether-1000wei)/(2e14)
 
 		{"EstimateEIP1559WithHighFees", ethereum.CallMsg{
 			From:      addr,
@@ -599,7 +608,7 @@ func TestEstimateGasWithPrice(t *testing.T) {
 			GasTipCap: big.NewInt(1),
 			Value:     big.NewInt(1e17 + 1), // the remaining balance for fee is 2.1ether
 			Data:      nil,
-		}, params.TxGas, errors.New("gas required exceeds allowance (20999)")}, // 20999=(2.2ether-0.1ether-1wei)/(1e14)
+		}, params.TxGas, errors.New("gas required exceeds allowance (20999), would need 21000")}, // 20999=(2.2ether-0.1ether-1wei)/(1e14)
 	}
 	for i, c := range cases {
 		got, err := sim.EstimateGas(context.Background(), c.message)
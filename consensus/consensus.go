@@ -90,7 +90,7 @@ type Engine interface {
 	// Note: The block header and state database might be updated to reflect any
 	// consensus rules that happen at finalization (e.g. block rewards).
 	Finalize(chain ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction,
-		uncles []*types.Header)
+		uncles []*types.Header, withdrawals []*types.Withdrawal)
 
 	// FinalizeAndAssemble runs any post-transaction state modifications (e.g. block
 	// rewards) and assembles the final block.
@@ -98,7 +98,7 @@ type Engine interface {
 	// Note: The block header and state database might be updated to reflect any
 	// consensus rules that happen at finalization (e.g. block rewards).
 	FinalizeAndAssemble(chain ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction,
-		uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error)
+		uncles []*types.Header, receipts []*types.Receipt, withdrawals []*types.Withdrawal) (*types.Block, error)
 
 	// Seal generates a new sealing request for the given input block and pushes
 	// the result into the given channel.
@@ -121,6 +121,17 @@ type Engine interface {
 	Close() error
 }
 
+// ReceiptProcessor is a consensus engine that derives additional outputs from
+// the receipts of a processed block, e.g. the deposit requests of EIP-6110
+// parsed from the logs of the deposit contract.
+type ReceiptProcessor interface {
+	Engine
+
+	// ProcessReceipts parses the receipts of a processed block and returns the
+	// data derived from them. An error marks the block as invalid.
+	ProcessReceipts(chain ChainHeaderReader, header *types.Header, receipts []*types.Receipt) ([][]byte, error)
+}
+
 // PoW is a consensus engine based on proof-of-work.
 type PoW interface {
 	Engine
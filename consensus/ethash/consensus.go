@@ -305,6 +305,10 @@ func (ethash *Ethash) verifyHeader(chain consensus.ChainHeaderReader, header, pa
 	if diff := new(big.Int).Sub(header.Number, parent.Number); diff.Cmp(big.NewInt(1)) != 0 {
 		return consensus.ErrInvalidNumber
 	}
+	// Withdrawals are only credited by the beacon chain
+	if header.WithdrawalsHash != nil {
+		return fmt.Errorf("invalid withdrawalsHash: have %x, expected nil", header.WithdrawalsHash)
+	}
 	// Verify the engine specific seal securing the block
 	if seal {
 		if err := ethash.verifySeal(chain, header, false); err != nil {
@@ -590,17 +594,20 @@ func (ethash *Ethash) Prepare(chain consensus.ChainHeaderReader, header *types.H
 
 // Finalize implements consensus.Engine, accumulating the block and uncle rewards,
 // setting the final state on the header
-func (ethash *Ethash) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header) {
+func (ethash *Ethash) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, withdrawals []*types.Withdrawal) {
 	// Accumulate any block and uncle rewards and commit the final state root
-	accumulateRewards(chain.Config(), state, header, uncles)
+	accumulateRewards(ethash.rewardPolicy(), chain.Config(), state, header, uncles)
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
 }
 
 // FinalizeAndAssemble implements consensus.Engine, accumulating the block and
 // uncle rewards, setting the final state and assembling the block.
-func (ethash *Ethash) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
+func (ethash *Ethash) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt, withdrawals []*types.Withdrawal) (*types.Block, error) {
+	if len(withdrawals) > 0 {
+		return nil, errors.New("ethash does not support withdrawals")
+	}
 	// Finalize block
-	ethash.Finalize(chain, header, state, txs, uncles)
+	ethash.Finalize(chain, header, state, txs, uncles, nil)
 
 	// Header seems complete, assemble into a block and return
 	return types.NewBlock(header, txs, uncles, receipts, trie.NewStackTrie(nil)), nil
@@ -639,11 +646,37 @@ var (
 	big32 = big.NewInt(32)
 )
 
-// AccumulateRewards credits the coinbase of the given block with the mining
-// reward. The total reward consists of the static block reward and rewards for
-// included uncles. The coinbase of each uncle block is also rewarded.
-func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header, uncles []*types.Header) {
-	// Select the correct block reward based on chain progression
+// RewardPolicy determines the mining rewards credited when finalizing a block,
+// allowing custom chains to change their issuance without forking the engine.
+type RewardPolicy interface {
+	// BlockReward returns the static reward for mining the given block.
+	BlockReward(config *params.ChainConfig, header *types.Header) *big.Int
+
+	// UncleReward returns the reward of the miner of an uncle included in the
+	// given block.
+	UncleReward(config *params.ChainConfig, header *types.Header, uncle *types.Header) *big.Int
+
+	// InclusionReward returns the extra reward for including an uncle in the
+	// given block.
+	InclusionReward(config *params.ChainConfig, header *types.Header) *big.Int
+
+	// Recipient returns the account credited with the block and inclusion
+	// rewards of the given block.
+	Recipient(config *params.ChainConfig, header *types.Header) common.Address
+}
+
+// DefaultRewardPolicy is the issuance of Ethereum mainnet, subject to the reward
+// overrides scheduled in the ethash section of the chain config.
+type DefaultRewardPolicy struct{}
+
+// BlockReward implements RewardPolicy, selecting the block reward based on chain
+// progression.
+func (DefaultRewardPolicy) BlockReward(config *params.ChainConfig, header *types.Header) *big.Int {
+	if config.Ethash != nil {
+		if reward := config.Ethash.RewardAt(header.Number); reward != nil {
+			return reward.BlockReward
+		}
+	}
 	blockReward := FrontierBlockReward
 	if config.IsByzantium(header.Number) {
 		blockReward = ByzantiumBlockReward
@@ -651,18 +684,44 @@ func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header
 	if config.IsConstantinople(header.Number) {
 		blockReward = ConstantinopleBlockReward
 	}
+	return blockReward
+}
+
+// UncleReward implements RewardPolicy, rewarding the uncle's miner with 8-d
+// eighths of the block reward, d being the distance from the uncle to the block.
+func (p DefaultRewardPolicy) UncleReward(config *params.ChainConfig, header *types.Header, uncle *types.Header) *big.Int {
+	r := new(big.Int).Add(uncle.Number, big8)
+	r.Sub(r, header.Number)
+	r.Mul(r, p.BlockReward(config, header))
+	return r.Div(r, big8)
+}
+
+// InclusionReward implements RewardPolicy, rewarding the inclusion of an uncle
+// with a 32nd of the block reward.
+func (p DefaultRewardPolicy) InclusionReward(config *params.ChainConfig, header *types.Header) *big.Int {
+	return new(big.Int).Div(p.BlockReward(config, header), big32)
+}
+
+// Recipient implements RewardPolicy, crediting the coinbase unless the chain
+// config redirects the rewards.
+func (DefaultRewardPolicy) Recipient(config *params.ChainConfig, header *types.Header) common.Address {
+	if config.Ethash != nil {
+		if reward := config.Ethash.RewardAt(header.Number); reward != nil && reward.Recipient != nil {
+			return *reward.Recipient
+		}
+	}
+	return header.Coinbase
+}
+
+// AccumulateRewards credits the recipient of the given block with the mining
+// reward. The total reward consists of the static block reward and rewards for
+// included uncles. The coinbase of each uncle block is also rewarded.
+func accumulateRewards(policy RewardPolicy, config *params.ChainConfig, state *state.StateDB, header *types.Header, uncles []*types.Header) {
 	// Accumulate the rewards for the miner and any included uncles
-	reward := new(big.Int).Set(blockReward)
-	r := new(big.Int)
+	reward := new(big.Int).Set(policy.BlockReward(config, header))
 	for _, uncle := range uncles {
-		r.Add(uncle.Number, big8)
-		r.Sub(r, header.Number)
-		r.Mul(r, blockReward)
-		r.Div(r, big8)
-		state.AddBalance(uncle.Coinbase, r)
-
-		r.Div(blockReward, big32)
-		reward.Add(reward, r)
+		state.AddBalance(uncle.Coinbase, policy.UncleReward(config, header, uncle))
+		reward.Add(reward, policy.InclusionReward(config, header))
 	}
-	state.AddBalance(header.Coinbase, reward)
+	state.AddBalance(policy.Recipient(config, header), reward)
 }
@@ -27,6 +27,9 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/params"
 )
@@ -186,3 +189,85 @@ func BenchmarkDifficultyCalculator(b *testing.B) {
 		}
 	})
 }
+
+// Tests that the mining rewards follow the overrides scheduled in the chain
+// config and that custom reward policies are honoured.
+func TestAccumulateRewards(t *testing.T) {
+	var (
+		coinbase = common.HexToAddress("0xc0ffee")
+		uncleCb  = common.HexToAddress("0x0dd")
+		treasury = common.HexToAddress("0x7ea5")
+		config   = *params.AllEthashProtocolChanges
+	)
+	config.Ethash = &params.EthashConfig{
+		Rewards: []*params.EthashReward{
+			{Block: big.NewInt(10), BlockReward: big.NewInt(800)},
+			{Block: big.NewInt(20), BlockReward: big.NewInt(1600), Recipient: &treasury},
+		},
+	}
+	tests := []struct {
+		number   int64
+		policy   RewardPolicy
+		receiver common.Address
+		reward   *big.Int // Block reward plus inclusion reward
+		uncle    *big.Int // Reward of the uncle one block behind
+	}{
+		{5, DefaultRewardPolicy{}, coinbase, new(big.Int).Add(ConstantinopleBlockReward, new(big.Int).Div(ConstantinopleBlockReward, big32)), new(big.Int).Div(new(big.Int).Mul(ConstantinopleBlockReward, big.NewInt(7)), big8)},
+		{15, DefaultRewardPolicy{}, coinbase, big.NewInt(800 + 25), big.NewInt(700)},
+		{25, DefaultRewardPolicy{}, treasury, big.NewInt(1600 + 50), big.NewInt(1400)},
+		{25, fixedRewardPolicy{}, coinbase, big.NewInt(1 + 1), big.NewInt(1)},
+	}
+	for i, tt := range tests {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		header := &types.Header{Number: big.NewInt(tt.number), Coinbase: coinbase}
+		uncle := &types.Header{Number: big.NewInt(tt.number - 1), Coinbase: uncleCb}
+
+		accumulateRewards(tt.policy, &config, statedb, header, []*types.Header{uncle})
+		if have := statedb.GetBalance(tt.receiver); have.Cmp(tt.reward) != 0 {
+			t.Errorf("test %d: block reward mismatch: have %v, want %v", i, have, tt.reward)
+		}
+		if have := statedb.GetBalance(uncleCb); have.Cmp(tt.uncle) != 0 {
+			t.Errorf("test %d: uncle reward mismatch: have %v, want %v", i, have, tt.uncle)
+		}
+	}
+}
+
+// Tests that the engine credits the rewards of the policy it is configured with
+// when finalizing blocks.
+func TestConfiguredRewardPolicy(t *testing.T) {
+	engine := New(Config{PowMode: ModeFake, RewardPolicy: fixedRewardPolicy{}}, nil, false)
+	defer engine.Close()
+
+	var (
+		coinbase = common.HexToAddress("0xc0ffee")
+		db       = rawdb.NewMemoryDatabase()
+		gspec    = &core.Genesis{Config: params.TestChainConfig}
+		genesis  = gspec.MustCommit(db)
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, engine, db, 2, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(coinbase)
+	})
+	statedb, err := state.New(blocks[1].Root(), state.NewDatabase(db), nil)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	if have := statedb.GetBalance(coinbase); have.Cmp(big.NewInt(2)) != 0 {
+		t.Errorf("coinbase balance mismatch: have %v, want 2", have)
+	}
+}
+
+// fixedRewardPolicy is a reward policy paying a single wei for everything.
+type fixedRewardPolicy struct{}
+
+func (fixedRewardPolicy) BlockReward(*params.ChainConfig, *types.Header) *big.Int {
+	return big.NewInt(1)
+}
+func (fixedRewardPolicy) UncleReward(*params.ChainConfig, *types.Header, *types.Header) *big.Int {
+	return big.NewInt(1)
+}
+func (fixedRewardPolicy) InclusionReward(*params.ChainConfig, *types.Header) *big.Int {
+	return big.NewInt(1)
+}
+func (fixedRewardPolicy) Recipient(config *params.ChainConfig, header *types.Header) common.Address {
+	return header.Coinbase
+}
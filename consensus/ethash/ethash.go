@@ -428,6 +428,11 @@ type Config struct {
 	// be block header JSON objects instead of work package arrays.
 	NotifyFull bool
 
+	// RewardPolicy determines the mining rewards credited when finalizing
+	// blocks, allowing private networks to change their issuance beyond the
+	// overrides of the chain config. DefaultRewardPolicy is used if nil.
+	RewardPolicy RewardPolicy `toml:"-"`
+
 	Log log.Logger `toml:"-"`
 }
 
@@ -649,6 +654,22 @@ func (ethash *Ethash) SetThreads(threads int) {
 	}
 }
 
+// SetRewardPolicy replaces the mining rewards credited when finalizing blocks,
+// e.g. of the fake engines created without a config. It must be called before
+// the engine is used.
+func (ethash *Ethash) SetRewardPolicy(policy RewardPolicy) {
+	ethash.config.RewardPolicy = policy
+}
+
+// rewardPolicy returns the issuance of the chain, falling back to the default
+// one subject to the overrides of the chain config.
+func (ethash *Ethash) rewardPolicy() RewardPolicy {
+	if ethash.config.RewardPolicy == nil {
+		return DefaultRewardPolicy{}
+	}
+	return ethash.config.RewardPolicy
+}
+
 // Hashrate implements PoW, returning the measured rate of the search invocations
 // per second over the last minute.
 // Note the returned hashrate includes local hashrate, but also includes the total
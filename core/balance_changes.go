@@ -0,0 +1,101 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// BalanceChangeReason describes why a balance was changed during a transaction.
+type BalanceChangeReason byte
+
+const (
+	BalanceChangeGasPurchase      BalanceChangeReason = iota // Sender paying for the gas limit upfront
+	BalanceChangeTransfer                                    // Value transfer of the transaction itself
+	BalanceChangeGasRefund                                   // Sender refunded for the gas left over
+	BalanceChangeCoinbase                                    // Coinbase credited with the transaction fee
+	BalanceChangeInternalTransfer                            // Transfer made during execution (calls, self-destructs)
+	BalanceChangeOperatorFee                                 // Operator fee charged to the payer and credited to its vault
+)
+
+func (r BalanceChangeReason) String() string {
+	switch r {
+	case BalanceChangeGasPurchase:
+		return "gas"
+	case BalanceChangeTransfer:
+		return "value"
+	case BalanceChangeGasRefund:
+		return "refund"
+	case BalanceChangeCoinbase:
+		return "coinbase"
+	case BalanceChangeInternalTransfer:
+		return "internal-transfer"
+	case BalanceChangeOperatorFee:
+		return "operator-fee"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(r))
+	}
+}
+
+// BalanceChange is a single balance mutation made during a transaction.
+type BalanceChange struct {
+	Address common.Address      // Account whose balance changed
+	Delta   *big.Int            // Amount added, negative for debits
+	Reason  BalanceChangeReason // Cause of the change
+}
+
+// balanceRecorder is a vm.StateDB recording every balance mutation made
+// through it, attributed to the reason currently set.
+type balanceRecorder struct {
+	vm.StateDB
+
+	reason   BalanceChangeReason
+	transfer bool // Whether the transaction's own value transfer happened
+	changes  []BalanceChange
+}
+
+// AddBalance records the credit and adds amount to the account.
+func (r *balanceRecorder) AddBalance(addr common.Address, amount *big.Int) {
+	r.changes = append(r.changes, BalanceChange{Address: addr, Delta: new(big.Int).Set(amount), Reason: r.reason})
+	r.StateDB.AddBalance(addr, amount)
+}
+
+// SubBalance records the debit and subtracts amount from the account.
+func (r *balanceRecorder) SubBalance(addr common.Address, amount *big.Int) {
+	r.changes = append(r.changes, BalanceChange{Address: addr, Delta: new(big.Int).Neg(amount), Reason: r.reason})
+	r.StateDB.SubBalance(addr, amount)
+}
+
+// wrapTransfer wraps a transfer function to attribute the first transfer of the
+// execution, always made by the top level call or creation, to the transaction
+// value and all further ones to internal transfers.
+func (r *balanceRecorder) wrapTransfer(transfer vm.TransferFunc) vm.TransferFunc {
+	return func(db vm.StateDB, sender, recipient common.Address, amount *big.Int) {
+		reason := r.reason
+		if r.transfer {
+			r.reason = BalanceChangeInternalTransfer
+		} else {
+			r.reason, r.transfer = BalanceChangeTransfer, true
+		}
+		transfer(db, sender, recipient, amount)
+		r.reason = reason
+	}
+}
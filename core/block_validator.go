@@ -17,8 +17,11 @@
 package core
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -65,6 +68,27 @@ func (v *BlockValidator) ValidateBody(block *types.Block) error {
 	if hash := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); hash != header.TxHash {
 		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, header.TxHash)
 	}
+	// Withdrawals are present only after Shanghai, committed to by the header
+	if header.WithdrawalsHash != nil {
+		if block.Withdrawals() == nil {
+			return errors.New("missing withdrawals in block body")
+		}
+		if hash := types.DeriveSha(block.Withdrawals(), trie.NewStackTrie(nil)); hash != *header.WithdrawalsHash {
+			return fmt.Errorf("withdrawals root hash mismatch: have %x, want %x", hash, *header.WithdrawalsHash)
+		}
+	} else if block.Withdrawals() != nil {
+		return errors.New("withdrawals present in block body")
+	}
+	// The blob gas used by the header must match the blobs of the transactions
+	if header.BlobGasUsed != nil {
+		var blobGasUsed uint64
+		for _, tx := range block.Transactions() {
+			blobGasUsed += tx.BlobGas()
+		}
+		if blobGasUsed != *header.BlobGasUsed {
+			return fmt.Errorf("blob gas used mismatch: have %d, want %d", blobGasUsed, *header.BlobGasUsed)
+		}
+	}
 	if !v.bc.HasBlockAndState(block.ParentHash(), block.NumberU64()-1) {
 		if !v.bc.HasBlock(block.ParentHash(), block.NumberU64()-1) {
 			return consensus.ErrUnknownAncestor
@@ -102,6 +126,103 @@ func (v *BlockValidator) ValidateState(block *types.Block, statedb *state.StateD
 	return nil
 }
 
+// ReceiptMismatch describes an inconsistency between the receipts of a block and
+// its header, or within the receipts themselves.
+type ReceiptMismatch struct {
+	Index int         // Index of the offending transaction, -1 for block level fields
+	Field string      // Mismatching field: "gasUsed", "logsBloom", "receiptsRoot", "cumulativeGasUsed" or "bloom"
+	Have  interface{} // Value derived from the receipts
+	Want  interface{} // Value expected by the header or the other receipts
+}
+
+func (m ReceiptMismatch) String() string {
+	if m.Index < 0 {
+		return fmt.Sprintf("%s mismatch: have %v, want %v", m.Field, m.Have, m.Want)
+	}
+	return fmt.Sprintf("tx %d %s mismatch: have %v, want %v", m.Index, m.Field, m.Have, m.Want)
+}
+
+// ReceiptMismatchError is returned by VerifyReceipts, listing every mismatch
+// found between the receipts of a block and its header.
+type ReceiptMismatchError struct {
+	Mismatches []ReceiptMismatch
+}
+
+func (e *ReceiptMismatchError) Error() string {
+	msgs := make([]string, len(e.Mismatches))
+	for i, m := range e.Mismatches {
+		msgs[i] = m.String()
+	}
+	return "receipts mismatch header: " + strings.Join(msgs, "; ")
+}
+
+// VerifyReceipts recomputes the cumulative gas, bloom and receipt root of the
+// given receipts and compares them to the header. Unlike ValidateState, which
+// stops at the first difference, it collects all of them into a
+// *ReceiptMismatchError, pinning each to the offending transaction where
+// possible: receipts whose cumulative gas or bloom is inconsistent with their
+// own contents, and receipts with logs missing from the header bloom.
+func VerifyReceipts(header *types.Header, receipts types.Receipts) error {
+	var (
+		mismatches []ReceiptMismatch
+		cumulative uint64
+	)
+	for i, receipt := range receipts {
+		cumulative += receipt.GasUsed
+		if receipt.CumulativeGasUsed != cumulative {
+			mismatches = append(mismatches, ReceiptMismatch{Index: i, Field: "cumulativeGasUsed", Have: receipt.CumulativeGasUsed, Want: cumulative})
+		}
+		if bloom := types.BytesToBloom(types.LogsBloom(receipt.Logs)); receipt.Bloom != bloom {
+			mismatches = append(mismatches, ReceiptMismatch{Index: i, Field: "bloom", Have: hexutil.Bytes(receipt.Bloom.Bytes()), Want: hexutil.Bytes(bloom.Bytes())})
+		}
+		if !bloomCoversLogs(header.Bloom, receipt.Logs) {
+			mismatches = append(mismatches, ReceiptMismatch{Index: i, Field: "logsBloom", Have: hexutil.Bytes(receipt.Bloom.Bytes()), Want: hexutil.Bytes(header.Bloom.Bytes())})
+		}
+	}
+	if header.GasUsed != cumulative {
+		mismatches = append(mismatches, ReceiptMismatch{Index: -1, Field: "gasUsed", Have: cumulative, Want: header.GasUsed})
+	}
+	if bloom := types.CreateBloom(receipts); bloom != header.Bloom {
+		mismatches = append(mismatches, ReceiptMismatch{Index: -1, Field: "logsBloom", Have: hexutil.Bytes(bloom.Bytes()), Want: hexutil.Bytes(header.Bloom.Bytes())})
+	}
+	if root := types.DeriveSha(receipts, trie.NewStackTrie(nil)); root != header.ReceiptHash {
+		mismatches = append(mismatches, ReceiptMismatch{Index: -1, Field: "receiptsRoot", Have: root, Want: header.ReceiptHash})
+	}
+	if len(mismatches) > 0 {
+		return &ReceiptMismatchError{Mismatches: mismatches}
+	}
+	return nil
+}
+
+// verifyCumulativeGas checks the cumulative gas used by a block after its index-th
+// transaction against the gas used declared by the header and against the
+// expected receipt of the transaction, if any.
+func verifyCumulativeGas(header *types.Header, expected types.Receipts, index int, cumulative uint64) error {
+	if index < len(expected) && expected[index].CumulativeGasUsed != cumulative {
+		return &ReceiptMismatchError{Mismatches: []ReceiptMismatch{{Index: index, Field: "cumulativeGasUsed", Have: cumulative, Want: expected[index].CumulativeGasUsed}}}
+	}
+	if cumulative > header.GasUsed {
+		return &ReceiptMismatchError{Mismatches: []ReceiptMismatch{{Index: index, Field: "gasUsed", Have: cumulative, Want: header.GasUsed}}}
+	}
+	return nil
+}
+
+// bloomCoversLogs reports whether the addresses and topics of all the given
+// logs are present in the bloom.
+func bloomCoversLogs(bloom types.Bloom, logs []*types.Log) bool {
+	for _, log := range logs {
+		if !bloom.Test(log.Address.Bytes()) {
+			return false
+		}
+		for _, topic := range log.Topics {
+			if !bloom.Test(topic.Bytes()) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 // CalcGasLimit computes the gas limit of the next block after parent. It aims
 // to keep the baseline gas close to the provided target, and increase it towards
 // the target if the baseline gas is lower.
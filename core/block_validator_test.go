@@ -18,6 +18,7 @@ package core
 
 import (
 	"encoding/json"
+	"errors"
 	"math/big"
 	"runtime"
 	"testing"
@@ -33,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 // Tests that simple header verification works, for both good and bad blocks.
@@ -371,6 +373,117 @@ func testHeaderConcurrentAbortion(t *testing.T, threads int) {
 	}
 }
 
+// TestVerifyReceipts tests that the receipts of a processed block are checked
+// against its header, reporting every mismatch with the offending transaction.
+func TestVerifyReceipts(t *testing.T) {
+	var (
+		config   = params.TestChainConfig
+		signer   = types.LatestSigner(config)
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		contract = common.HexToAddress("0xc0de")
+		// PUSH1 0xaa PUSH1 32 PUSH1 0 LOG1 STOP
+		alloc = GenesisAlloc{contract: {Code: common.FromHex("0x60aa60206000a100"), Balance: new(big.Int)}}
+	)
+	blockchain, block := newProcessTestChain(t, config, alloc, func(b *BlockGen) {
+		for _, to := range []common.Address{contract, common.HexToAddress("0x2222")} {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(transitionTestSender), to, new(big.Int), 100000, b.BaseFee(), nil), signer, key)
+			b.AddTx(tx)
+		}
+	})
+	defer blockchain.Stop()
+
+	statedb, err := blockchain.State()
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	result, err := blockchain.Processor().(*StateProcessor).ProcessWithOptions(block, statedb, vm.Config{}, ProcessOptions{VerifyReceipts: true})
+	if err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	receipts := result.Receipts
+
+	// Tamper with the header and a receipt, expecting all differences reported
+	header := block.Header()
+	header.Bloom = types.Bloom{}
+	header.GasUsed++
+	receipts[1].CumulativeGasUsed++
+
+	err = VerifyReceipts(header, receipts)
+	var mismatch *ReceiptMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("error type mismatch: have %T (%v), want *ReceiptMismatchError", err, err)
+	}
+	want := []struct {
+		index int
+		field string
+	}{
+		{0, "logsBloom"},
+		{1, "cumulativeGasUsed"},
+		{-1, "gasUsed"},
+		{-1, "logsBloom"},
+		{-1, "receiptsRoot"},
+	}
+	if len(mismatch.Mismatches) != len(want) {
+		t.Fatalf("mismatch count mismatch: have %d, want %d: %v", len(mismatch.Mismatches), len(want), err)
+	}
+	for i, m := range mismatch.Mismatches {
+		if m.Index != want[i].index || m.Field != want[i].field {
+			t.Errorf("mismatch %d: have tx %d %s, want tx %d %s", i, m.Index, m.Field, want[i].index, want[i].field)
+		}
+	}
+}
+
+// TestWithdrawals tests that the withdrawals of post-merge blocks are credited
+// to their recipients in gwei, and that the body is checked against the
+// withdrawals root of the header.
+func TestWithdrawals(t *testing.T) {
+	var (
+		config    = *params.TestChainConfig
+		recipient = common.HexToAddress("0x2222")
+		engine    = beacon.New(ethash.NewFaker())
+	)
+	config.ShanghaiBlock = big.NewInt(0)
+
+	blockchain, _ := newProcessTestChain(t, &config, nil, func(b *BlockGen) {})
+	defer blockchain.Stop()
+
+	withdrawals := []*types.Withdrawal{
+		{Index: 0, Validator: 1, Address: recipient, Amount: 10},
+		{Index: 1, Validator: 2, Address: recipient, Amount: 5},
+	}
+	genesis := blockchain.Genesis()
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     big.NewInt(1),
+		GasLimit:   genesis.GasLimit(),
+		Difficulty: common.Big0,
+		BaseFee:    genesis.BaseFee(),
+	}
+	block := types.NewBlockWithWithdrawals(header, nil, nil, nil, withdrawals, trie.NewStackTrie(nil))
+
+	statedb, err := blockchain.State()
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	if _, _, _, err := NewStateProcessor(&config, blockchain, engine).Process(block, statedb, vm.Config{}); err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	if have, want := statedb.GetBalance(recipient), big.NewInt(15*params.GWei); have.Cmp(want) != 0 {
+		t.Errorf("recipient balance mismatch: have %v, want %v", have, want)
+	}
+	// Validate the body against the withdrawals root
+	validator := NewBlockValidator(&config, blockchain, engine)
+	if err := validator.ValidateBody(block); err != nil {
+		t.Errorf("valid withdrawals rejected: %v", err)
+	}
+	if err := validator.ValidateBody(block.WithWithdrawals(withdrawals[:1])); err == nil {
+		t.Errorf("mismatching withdrawals accepted")
+	}
+	if err := validator.ValidateBody(block.WithWithdrawals(nil)); err == nil {
+		t.Errorf("missing withdrawals accepted")
+	}
+}
+
 func TestCalcGasLimit(t *testing.T) {
 	for i, tc := range []struct {
 		pGasLimit uint64
@@ -18,6 +18,7 @@
 package core
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -130,6 +131,8 @@ type CacheConfig struct {
 	TrieTimeLimit       time.Duration // Time limit after which to flush the current in-memory trie to disk
 	SnapshotLimit       int           // Memory allowance (MB) to use for caching snapshot entries in memory
 	Preimages           bool          // Whether to store preimage of trie key to the disk
+	PrefetchWorkers     int           // Number of goroutines warming the state of blocks while processing them (0 = disabled)
+	ProcessedCacheLimit int           // Number of recently processed blocks whose outcome is cached (0 = disabled)
 
 	SnapshotWait bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
 }
@@ -251,7 +254,12 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	bc.forker = NewForkChoice(bc, shouldPreserve)
 	bc.validator = NewBlockValidator(chainConfig, bc, engine)
 	bc.prefetcher = newStatePrefetcher(chainConfig, bc, engine)
-	bc.processor = NewStateProcessor(chainConfig, bc, engine)
+	processor := NewStateProcessor(chainConfig, bc, engine)
+	processor.PrefetchWorkers = cacheConfig.PrefetchWorkers
+	if cacheConfig.ProcessedCacheLimit > 0 {
+		processor.CacheResults(cacheConfig.ProcessedCacheLimit)
+	}
+	bc.processor = processor
 
 	var err error
 	bc.hc, err = NewHeaderChain(db, chainConfig, engine, bc.insertStopped)
@@ -2327,9 +2335,33 @@ func (bc *BlockChain) maintainTxIndex(ancients uint64) {
 	}
 }
 
-// reportBlock logs a bad block error.
+// BadBlockReport summarizes the circumstances in which a block was rejected.
+// It is persisted along with the bad block to help diagnosing consensus splits
+// after the fact.
+type BadBlockReport struct {
+	Version     string              `json:"version"`     // Client version rejecting the block
+	ChainConfig *params.ChainConfig `json:"chainConfig"` // Chain configuration in effect
+	Number      uint64              `json:"number"`      // Number of the rejected block
+	Hash        common.Hash         `json:"hash"`        // Hash of the rejected block
+	Error       string              `json:"error"`       // Reason the block was rejected
+	Time        time.Time           `json:"time"`        // Local time of the rejection
+}
+
+// reportBlock logs a bad block error and persists it along with the receipts
+// computed locally and a report of the failure.
 func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, err error) {
-	rawdb.WriteBadBlock(bc.db, block)
+	report, encErr := json.Marshal(&BadBlockReport{
+		Version:     params.VersionWithMeta,
+		ChainConfig: bc.chainConfig,
+		Number:      block.NumberU64(),
+		Hash:        block.Hash(),
+		Error:       err.Error(),
+		Time:        time.Now(),
+	})
+	if encErr != nil {
+		log.Warn("Failed to encode bad block report", "err", encErr)
+	}
+	rawdb.WriteBadBlockWithReport(bc.db, block, receipts, report)
 
 	var receiptString string
 	for i, receipt := range receipts {
@@ -17,6 +17,7 @@
 package core
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
@@ -617,6 +618,43 @@ func testBadHashes(t *testing.T, full bool) {
 	}
 }
 
+// Tests that rejected blocks are persisted along with a report of the failure.
+func TestBadBlockReport(t *testing.T) {
+	db, blockchain, err := newCanonical(ethash.NewFaker(), 0, true)
+	if err != nil {
+		t.Fatalf("failed to create pristine chain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	blocks := makeBlockChain(blockchain.CurrentBlock(), 2, ethash.NewFaker(), db, 10)
+	BadHashes[blocks[1].Hash()] = true
+	defer delete(BadHashes, blocks[1].Hash())
+
+	if _, err := blockchain.InsertChain(blocks); !errors.Is(err, ErrBannedHash) {
+		t.Fatalf("error mismatch: have: %v, want: %v", err, ErrBannedHash)
+	}
+	if rawdb.ReadBadBlock(db, blocks[1].Hash()) == nil {
+		t.Fatalf("bad block not persisted")
+	}
+	_, blob := rawdb.ReadBadBlockReport(db, blocks[1].Hash())
+	var report BadBlockReport
+	if err := json.Unmarshal(blob, &report); err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+	if report.Hash != blocks[1].Hash() || report.Number != blocks[1].NumberU64() {
+		t.Errorf("block mismatch: have #%d [%x], want #%d [%x]", report.Number, report.Hash, blocks[1].NumberU64(), blocks[1].Hash())
+	}
+	if report.Error != ErrBannedHash.Error() {
+		t.Errorf("error mismatch: have %q, want %q", report.Error, ErrBannedHash.Error())
+	}
+	if report.Version != params.VersionWithMeta {
+		t.Errorf("version mismatch: have %q, want %q", report.Version, params.VersionWithMeta)
+	}
+	if report.ChainConfig == nil || report.ChainConfig.ChainID.Cmp(blockchain.Config().ChainID) != 0 {
+		t.Errorf("chain config mismatch: have %v, want %v", report.ChainConfig, blockchain.Config())
+	}
+}
+
 // Tests that bad hashes are detected on boot, and the chain rolled back to a
 // good state prior to the bad hash.
 func TestReorgBadHeaderHashes(t *testing.T) { testReorgBadHashes(t, false) }
@@ -39,10 +39,11 @@ type BlockGen struct {
 	header  *types.Header
 	statedb *state.StateDB
 
-	gasPool  *GasPool
-	txs      []*types.Transaction
-	receipts []*types.Receipt
-	uncles   []*types.Header
+	gasPool     *GasPool
+	txs         []*types.Transaction
+	receipts    []*types.Receipt
+	uncles      []*types.Header
+	withdrawals []*types.Withdrawal
 
 	config *params.ChainConfig
 	engine consensus.Engine
@@ -109,6 +110,9 @@ func (b *BlockGen) AddTxWithChain(bc *BlockChain, tx *types.Transaction) {
 	}
 	b.txs = append(b.txs, tx)
 	b.receipts = append(b.receipts, receipt)
+	if b.header.BlobGasUsed != nil {
+		*b.header.BlobGasUsed += tx.BlobGas()
+	}
 }
 
 // GetBalance returns the balance of the given address at the generated block.
@@ -180,6 +184,12 @@ func (b *BlockGen) AddUncle(h *types.Header) {
 	b.uncles = append(b.uncles, h)
 }
 
+// AddWithdrawal adds a withdrawal to the generated block. Withdrawals are only
+// supported by consensus engines crediting them, i.e. after the merge.
+func (b *BlockGen) AddWithdrawal(w *types.Withdrawal) {
+	b.withdrawals = append(b.withdrawals, w)
+}
+
 // PrevBlock returns a previously generated block by number. It panics if
 // num is greater or equal to the number of the block being generated.
 // For index -1, PrevBlock returns the parent block given to GenerateChain.
@@ -248,16 +258,18 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, engine conse
 				}
 			}
 		}
-		if config.DAOForkSupport && config.DAOForkBlock != nil && config.DAOForkBlock.Cmp(b.header.Number) == 0 {
-			misc.ApplyDAOHardFork(statedb)
-		}
+		ApplyIrregularStateChanges(config, b.header.Number, statedb)
+
+		// Update the system contracts before executing any transaction
+		ApplySystemCalls(config, b.header, NewEVMBlockContext(b.header, nil, &b.header.Coinbase), statedb, vm.Config{})
+
 		// Execute any user modifications to the block
 		if gen != nil {
 			gen(i, b)
 		}
 		if b.engine != nil {
 			// Finalize and seal the block
-			block, _ := b.engine.FinalizeAndAssemble(chainreader, b.header, statedb, b.txs, b.uncles, b.receipts)
+			block, _ := b.engine.FinalizeAndAssemble(chainreader, b.header, statedb, b.txs, b.uncles, b.receipts, b.withdrawals)
 
 			// Write state changes to db
 			root, err := statedb.Commit(config.IsEIP158(b.header.Number))
@@ -312,9 +324,23 @@ func makeHeader(chain consensus.ChainReader, parent *types.Block, state *state.S
 			header.GasLimit = CalcGasLimit(parentGasLimit, parentGasLimit)
 		}
 	}
+	if chain.Config().IsCancun(header.Number) {
+		header.ExcessBlobGas, header.BlobGasUsed = calcExcessBlobGas(parent.Header()), new(uint64)
+	}
 	return header
 }
 
+// calcExcessBlobGas derives the excess blob gas of a block from its parent,
+// treating the blob gas fields of a pre-Cancun parent as zero.
+func calcExcessBlobGas(parent *types.Header) *uint64 {
+	var parentExcessBlobGas, parentBlobGasUsed uint64
+	if parent.ExcessBlobGas != nil && parent.BlobGasUsed != nil {
+		parentExcessBlobGas, parentBlobGasUsed = *parent.ExcessBlobGas, *parent.BlobGasUsed
+	}
+	excessBlobGas := misc.CalcExcessBlobGas(parentExcessBlobGas, parentBlobGasUsed)
+	return &excessBlobGas
+}
+
 // makeHeaderChain creates a deterministic chain of headers rooted at parent.
 func makeHeaderChain(parent *types.Header, n int, engine consensus.Engine, db ethdb.Database, seed int) []*types.Header {
 	blocks := makeBlockChain(types.NewBlockWithHeader(parent), n, engine, db, seed)
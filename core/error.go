@@ -18,7 +18,10 @@ package core
 
 import (
 	"errors"
+	"fmt"
+	"math/big"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
@@ -59,6 +62,10 @@ var (
 	// by a transaction is higher than what's left in the block.
 	ErrGasLimitReached = errors.New("gas limit reached")
 
+	// ErrBlobGasLimitReached is returned by the blob gas pool if the amount of
+	// blob gas required by a transaction is higher than what's left in the block.
+	ErrBlobGasLimitReached = errors.New("blob gas limit reached")
+
 	// ErrInsufficientFundsForTransfer is returned if the transaction sender doesn't
 	// have enough funds for transfer(topmost call only).
 	ErrInsufficientFundsForTransfer = errors.New("insufficient funds for transfer")
@@ -74,6 +81,14 @@ var (
 	// than required to start the invocation.
 	ErrIntrinsicGas = errors.New("intrinsic gas too low")
 
+	// ErrFloorDataGas is returned if the transaction is specified to use less gas
+	// than required for the data floor cost (EIP-7623).
+	ErrFloorDataGas = errors.New("insufficient gas for floor data gas cost")
+
+	// ErrMaxInitCodeSizeExceeded is returned if creation transaction provides
+	// the init code bigger than the init code size limit.
+	ErrMaxInitCodeSizeExceeded = errors.New("max initcode size exceeded")
+
 	// ErrTxTypeNotSupported is returned if a transaction is not supported in the
 	// current network configuration.
 	ErrTxTypeNotSupported = types.ErrTxTypeNotSupported
@@ -94,6 +109,95 @@ var (
 	// the base fee of the block.
 	ErrFeeCapTooLow = errors.New("max fee per gas less than block base fee")
 
+	// ErrBlobFeeCapTooLow is returned if the transaction blob fee cap is less
+	// than the blob gas price of the block.
+	ErrBlobFeeCapTooLow = errors.New("max fee per blob gas less than block blob gas fee")
+
+	// ErrMissingBlobBaseFee is returned if a blob transaction is executed on a
+	// block without a blob gas price, i.e. one missing the excess blob gas.
+	ErrMissingBlobBaseFee = errors.New("blob transaction on block without blob base fee")
+
+	// ErrMissingBlobHashes is returned if a blob transaction carries no blobs.
+	ErrMissingBlobHashes = errors.New("blob transaction missing blob hashes")
+
+	// ErrInvalidBlobHashVersion is returned if a blob hash of a transaction
+	// is not a supported versioned hash.
+	ErrInvalidBlobHashVersion = errors.New("invalid blob hash version")
+
 	// ErrSenderNoEOA is returned if the sender of a transaction is a contract.
 	ErrSenderNoEOA = errors.New("sender not an eoa")
+
+	// ErrEmptyAuthList is returned if a set code transaction carries no
+	// authorizations.
+	ErrEmptyAuthList = errors.New("set code transaction with empty auth list")
+
+	// ErrNotFakeMessage is returned if a message derived from a real transaction
+	// is applied without fee checks.
+	ErrNotFakeMessage = errors.New("fee checks skipped for non-fake message")
+
+	// ErrRecipientDenied is returned by the transaction pool and the miner if
+	// a transaction is sent to, or calls into, an address on the node's
+	// denylist.
+	ErrRecipientDenied = errors.New("recipient denied")
+
+	// ErrTooManyTransactions is returned if a block contains more transactions
+	// than the processor is configured to execute.
+	ErrTooManyTransactions = errors.New("too many transactions in block")
+
+	// ErrGasLimitTooHigh is returned if the gas limit of a transaction exceeds
+	// the per-transaction cap (EIP-7825).
+	ErrGasLimitTooHigh = errors.New("transaction gas limit too high")
+
+	// ErrGasCapExceeded is returned if the gas limit of a message exceeds the
+	// cap of a non-consensus execution.
+	ErrGasCapExceeded = errors.New("message gas limit exceeds gas cap")
 )
+
+// EIP-7702 authorization errors. They are only informational: an invalid
+// authorization is skipped without aborting the transaction.
+var (
+	ErrAuthorizationWrongChainID       = errors.New("EIP-7702 authorization chain ID mismatch")
+	ErrAuthorizationNonceOverflow      = errors.New("EIP-7702 authorization nonce > 64 bit")
+	ErrAuthorizationInvalidSignature   = errors.New("EIP-7702 authorization has invalid signature")
+	ErrAuthorizationDestinationHasCode = errors.New("EIP-7702 authorization destination is a contract")
+	ErrAuthorizationNonceMismatch      = errors.New("EIP-7702 authorization nonce does not match current account nonce")
+)
+
+// InsufficientFundsError is returned if the sender of a transaction cannot
+// cover the upfront cost of its gas or its value transfer. It wraps either
+// ErrInsufficientFunds or ErrInsufficientFundsForTransfer, so callers can
+// keep matching those with errors.Is while rendering the amounts involved.
+type InsufficientFundsError struct {
+	Err     error          // ErrInsufficientFunds or ErrInsufficientFundsForTransfer
+	Address common.Address // Sender of the transaction
+	Have    *big.Int       // Balance of the sender
+	Want    *big.Int       // Amount required from the sender
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("%v: address %v have %v want %v", e.Err, e.Address.Hex(), e.Have, e.Want)
+}
+
+// Unwrap returns the sentinel error describing the failed check.
+func (e *InsufficientFundsError) Unwrap() error {
+	return e.Err
+}
+
+// NonceError is returned if the nonce of a transaction does not match the
+// nonce of its sender. It wraps either ErrNonceTooLow or ErrNonceTooHigh, and
+// carries the nonce expected by the state so tooling can resubmit with it.
+type NonceError struct {
+	Err     error          // ErrNonceTooLow or ErrNonceTooHigh
+	Address common.Address // Sender of the transaction
+	Have    uint64         // Nonce of the transaction
+	Want    uint64         // Nonce of the sender in the state
+}
+
+func (e *NonceError) Error() string {
+	return fmt.Sprintf("%v: address %v, tx: %d state: %d", e.Err, e.Address.Hex(), e.Have, e.Want)
+}
+
+// Unwrap returns the sentinel error describing the failed check.
+func (e *NonceError) Unwrap() error {
+	return e.Err
+}
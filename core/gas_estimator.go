@@ -0,0 +1,84 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// gasLimitMessage overrides the gas limit of a message.
+type gasLimitMessage struct {
+	Message
+	gas uint64
+}
+
+func (m gasLimitMessage) Gas() uint64 { return m.gas }
+
+// EstimateGas binary searches the lowest gas limit with which msg executes
+// successfully on top of statedb, using msg.Gas() as the upper bound. Every
+// attempt runs on a copy of the state, so statedb itself is not modified.
+//
+// If the message cannot be executed at all (e.g. its nonce is wrong), the
+// consensus error is returned as is. If it fails even with the full allowance,
+// the result of that execution is returned along with its error: reverts
+// surface as vm.ErrExecutionReverted with the revert data in the result, while
+// running out of gas is reported as a vm.ErrOutOfGas wrapping error.
+func EstimateGas(config *params.ChainConfig, blockCtx vm.BlockContext, statedb *state.StateDB, msg Message, cfg vm.Config) (uint64, *ExecutionResult, error) {
+	// Create a helper to execute the message with a given gas limit
+	execute := func(gas uint64) (*ExecutionResult, error) {
+		var (
+			db  = statedb.Copy()
+			evm = vm.NewEVM(blockCtx, vm.TxContext{}, db, config, cfg)
+			lim = gasLimitMessage{Message: msg, gas: gas}
+		)
+		evm.Reset(NewEVMTxContext(lim), db)
+		return ApplyMessage(evm, lim, new(GasPool).AddGas(math.MaxUint64))
+	}
+	hi := msg.Gas()
+	result, err := execute(hi)
+	if err != nil {
+		return 0, nil, err
+	}
+	if result.Failed() {
+		if errors.Is(result.Err, vm.ErrOutOfGas) || errors.Is(result.Err, vm.ErrCodeStoreOutOfGas) {
+			return 0, result, fmt.Errorf("%w: gas required exceeds allowance (%d)", vm.ErrOutOfGas, hi)
+		}
+		return 0, result, result.Err
+	}
+	// Don't seed the lower bound with the gas used: a call may succeed with
+	// less, e.g. when only a nested call runs out of the forwarded gas
+	lo := params.TxGas - 1
+	for lo+1 < hi {
+		mid := lo + (hi-lo)/2
+		attempt, err := execute(mid)
+		if err != nil && !errors.Is(err, ErrIntrinsicGas) && !errors.Is(err, ErrFloorDataGas) {
+			return 0, nil, err
+		}
+		if err != nil || attempt.Failed() {
+			lo = mid
+		} else {
+			hi, result = mid, attempt
+		}
+	}
+	return hi, result, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestEstimateGas(t *testing.T) {
+	var (
+		storer   = common.HexToAddress("0x1111")
+		caller   = common.HexToAddress("0x2222")
+		reverter = common.HexToAddress("0x3333")
+		looper   = common.HexToAddress("0x4444")
+		alloc    = GenesisAlloc{
+			// PUSH1 1 PUSH1 0 SSTORE STOP
+			storer: {Code: common.FromHex("0x600160005500"), Balance: new(big.Int)},
+			// CALL(GAS, 0x1111, 0, 0, 0, 0, 0) POP STOP, forwarding at most 63/64 of the gas
+			caller: {Code: common.FromHex("0x600060006000600060006111115af15000"), Balance: new(big.Int)},
+			// PUSH1 0xaa PUSH1 0 MSTORE8 PUSH1 1 PUSH1 0 REVERT
+			reverter: {Code: common.FromHex("0x60aa60005360016000fd"), Balance: new(big.Int)},
+			// JUMPDEST PUSH1 0 JUMP
+			looper: {Code: common.FromHex("0x5b600056"), Balance: new(big.Int)},
+		}
+		recipient = common.HexToAddress("0xdead")
+	)
+	statedb := newTransitionTestState(alloc)
+	blockCtx := newTransitionTestEVM(params.TestChainConfig, statedb, vm.Config{}).Context
+
+	for i, to := range []*common.Address{&recipient, &storer, &caller} {
+		msg := newTransitionTestMessage(0, to, new(big.Int), 1_000_000, nil)
+		gas, result, err := EstimateGas(params.TestChainConfig, blockCtx, statedb, msg, vm.Config{})
+		if err != nil {
+			t.Fatalf("test %d: failed to estimate gas: %v", i, err)
+		}
+		if result == nil || result.Failed() {
+			t.Fatalf("test %d: missing successful result: %v", i, result)
+		}
+		// The estimate must be exactly the lowest gas limit that succeeds
+		for _, tt := range []struct {
+			gas    uint64
+			failed bool
+		}{{gas, false}, {gas - 1, true}} {
+			msg := newTransitionTestMessage(0, to, new(big.Int), tt.gas, nil)
+			result, err := applyTransitionTestMessage(params.TestChainConfig, statedb.Copy(), msg, vm.Config{})
+			if failed := err != nil || result.Failed(); failed != tt.failed {
+				t.Errorf("test %d: execution with %d gas failure mismatch: have %v, want %v", i, tt.gas, failed, tt.failed)
+			}
+		}
+	}
+	// Reverts and running out of gas at the allowance are reported distinctly
+	msg := newTransitionTestMessage(0, &reverter, new(big.Int), 100000, nil)
+	if _, result, err := EstimateGas(params.TestChainConfig, blockCtx, statedb, msg, vm.Config{}); !errors.Is(err, vm.ErrExecutionReverted) {
+		t.Errorf("revert error mismatch: have %v, want %v", err, vm.ErrExecutionReverted)
+	} else if revert := result.Revert(); len(revert) != 1 || revert[0] != 0xaa {
+		t.Errorf("revert data mismatch: have %x, want aa", revert)
+	}
+	msg = newTransitionTestMessage(0, &looper, new(big.Int), 100000, nil)
+	if _, _, err := EstimateGas(params.TestChainConfig, blockCtx, statedb, msg, vm.Config{}); !errors.Is(err, vm.ErrOutOfGas) {
+		t.Errorf("out of gas error mismatch: have %v, want %v", err, vm.ErrOutOfGas)
+	}
+	// Consensus errors are returned as is
+	msg = newTransitionTestMessage(1, &recipient, new(big.Int), 100000, nil)
+	if _, _, err := EstimateGas(params.TestChainConfig, blockCtx, statedb, msg, vm.Config{}); !errors.Is(err, ErrNonceTooHigh) {
+		t.Errorf("consensus error mismatch: have %v, want %v", err, ErrNonceTooHigh)
+	}
+}
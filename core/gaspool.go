@@ -21,6 +21,28 @@ import (
 	"math"
 )
 
+// GasPooler is the interface of the gas pool transactions are charged against
+// during block processing. It allows experimental pools (e.g. ones metering
+// several dimensions of gas) to be plugged into the state transition, while
+// GasPool remains the default implementation.
+//
+// Gas is added through ReturnGas rather than AddGas: GasPool.AddGas returns
+// the pool so that it can be chained (new(GasPool).AddGas(limit)), and a
+// method of the same name can't have a different signature, so GasPool
+// could not implement the interface without breaking its existing callers.
+type GasPooler interface {
+	// ReturnGas makes gas available for execution, e.g. the block gas limit
+	// before processing starts or the unused gas refunded by a transaction.
+	ReturnGas(amount uint64)
+
+	// SubGas deducts the given amount from the pool, returning an error if
+	// not enough gas is available.
+	SubGas(amount uint64) error
+
+	// Gas returns the amount of gas remaining in the pool.
+	Gas() uint64
+}
+
 // GasPool tracks the amount of gas available during execution of the transactions
 // in a block. The zero value is a pool with zero gas available.
 type GasPool uint64
@@ -34,6 +56,11 @@ func (gp *GasPool) AddGas(amount uint64) *GasPool {
 	return gp
 }
 
+// ReturnGas makes gas available for execution, implementing GasPooler.
+func (gp *GasPool) ReturnGas(amount uint64) {
+	gp.AddGas(amount)
+}
+
 // SubGas deducts the given amount from the pool if enough gas is
 // available and returns an error otherwise.
 func (gp *GasPool) SubGas(amount uint64) error {
@@ -52,3 +79,31 @@ func (gp *GasPool) Gas() uint64 {
 func (gp *GasPool) String() string {
 	return fmt.Sprintf("%d", *gp)
 }
+
+// BlobGasPool tracks the amount of blob gas available for the blob transactions
+// in a block (EIP-4844). The zero value is a pool with zero blob gas available.
+type BlobGasPool uint64
+
+// AddGas makes blob gas available for the block's blob transactions.
+func (gp *BlobGasPool) AddGas(amount uint64) *BlobGasPool {
+	if uint64(*gp) > math.MaxUint64-amount {
+		panic("blob gas pool pushed above uint64")
+	}
+	*(*uint64)(gp) += amount
+	return gp
+}
+
+// SubGas deducts the given amount from the pool if enough blob gas is
+// available and returns an error otherwise.
+func (gp *BlobGasPool) SubGas(amount uint64) error {
+	if uint64(*gp) < amount {
+		return ErrBlobGasLimitReached
+	}
+	*(*uint64)(gp) -= amount
+	return nil
+}
+
+// Gas returns the amount of blob gas remaining in the pool.
+func (gp *BlobGasPool) Gas() uint64 {
+	return uint64(*gp)
+}
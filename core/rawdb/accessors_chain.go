@@ -783,7 +783,7 @@ func ReadBlock(db ethdb.Reader, hash common.Hash, number uint64) *types.Block {
 	if body == nil {
 		return nil
 	}
-	return types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles)
+	return types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles).WithWithdrawals(body.Withdrawals)
 }
 
 // WriteBlock serializes a block into the database, header and body separately.
@@ -857,8 +857,10 @@ func DeleteBlockWithoutNumber(db ethdb.KeyValueWriter, hash common.Hash, number
 const badBlockToKeep = 10
 
 type badBlock struct {
-	Header *types.Header
-	Body   *types.Body
+	Header   *types.Header
+	Body     *types.Body
+	Receipts []*types.ReceiptForStorage `rlp:"optional"` // Receipts computed locally, if any
+	Report   []byte                     `rlp:"optional"` // Opaque diagnostic summary, if any
 }
 
 // badBlockList implements the sort interface to allow sorting a list of
@@ -883,7 +885,7 @@ func ReadBadBlock(db ethdb.Reader, hash common.Hash) *types.Block {
 	}
 	for _, bad := range badBlocks {
 		if bad.Header.Hash() == hash {
-			return types.NewBlockWithHeader(bad.Header).WithBody(bad.Body.Transactions, bad.Body.Uncles)
+			return types.NewBlockWithHeader(bad.Header).WithBody(bad.Body.Transactions, bad.Body.Uncles).WithWithdrawals(bad.Body.Withdrawals)
 		}
 	}
 	return nil
@@ -902,14 +904,45 @@ func ReadAllBadBlocks(db ethdb.Reader) []*types.Block {
 	}
 	var blocks []*types.Block
 	for _, bad := range badBlocks {
-		blocks = append(blocks, types.NewBlockWithHeader(bad.Header).WithBody(bad.Body.Transactions, bad.Body.Uncles))
+		blocks = append(blocks, types.NewBlockWithHeader(bad.Header).WithBody(bad.Body.Transactions, bad.Body.Uncles).WithWithdrawals(bad.Body.Withdrawals))
 	}
 	return blocks
 }
 
+// ReadBadBlockReport retrieves the receipts computed locally for the bad block
+// with the corresponding block hash and the diagnostic report stored with it.
+func ReadBadBlockReport(db ethdb.Reader, hash common.Hash) (types.Receipts, []byte) {
+	blob, err := db.Get(badBlockKey)
+	if err != nil {
+		return nil, nil
+	}
+	var badBlocks badBlockList
+	if err := rlp.DecodeBytes(blob, &badBlocks); err != nil {
+		return nil, nil
+	}
+	for _, bad := range badBlocks {
+		if bad.Header.Hash() == hash {
+			receipts := make(types.Receipts, len(bad.Receipts))
+			for i, receipt := range bad.Receipts {
+				receipts[i] = (*types.Receipt)(receipt)
+				receipts[i].Bloom = types.CreateBloom(types.Receipts{receipts[i]})
+			}
+			return receipts, bad.Report
+		}
+	}
+	return nil, nil
+}
+
 // WriteBadBlock serializes the bad block into the database. If the cumulated
 // bad blocks exceeds the limitation, the oldest will be dropped.
 func WriteBadBlock(db ethdb.KeyValueStore, block *types.Block) {
+	WriteBadBlockWithReport(db, block, nil, nil)
+}
+
+// WriteBadBlockWithReport serializes the bad block into the database along with
+// the receipts computed locally and a diagnostic report. If the cumulated bad
+// blocks exceeds the limitation, the oldest will be dropped.
+func WriteBadBlockWithReport(db ethdb.KeyValueStore, block *types.Block, receipts types.Receipts, report []byte) {
 	blob, err := db.Get(badBlockKey)
 	if err != nil {
 		log.Warn("Failed to load old bad blocks", "error", err)
@@ -926,9 +959,15 @@ func WriteBadBlock(db ethdb.KeyValueStore, block *types.Block) {
 			return
 		}
 	}
+	stored := make([]*types.ReceiptForStorage, len(receipts))
+	for i, receipt := range receipts {
+		stored[i] = (*types.ReceiptForStorage)(receipt)
+	}
 	badBlocks = append(badBlocks, &badBlock{
-		Header: block.Header(),
-		Body:   block.Body(),
+		Header:   block.Header(),
+		Body:     block.Body(),
+		Receipts: stored,
+		Report:   report,
 	})
 	sort.Sort(sort.Reverse(badBlocks))
 	if len(badBlocks) > badBlockToKeep {
@@ -211,7 +211,11 @@ func TestBadBlockStorage(t *testing.T) {
 	} else if entry.Hash() != block.Hash() {
 		t.Fatalf("Retrieved block mismatch: have %v, want %v", entry, block)
 	}
-	// Write one more bad block
+	if receipts, report := ReadBadBlockReport(db, block.Hash()); len(receipts) != 0 || len(report) != 0 {
+		t.Fatalf("Unexpected report for plain bad block: %v %x", receipts, report)
+	}
+	// Write one more bad block, along with a report
+	receipt := &types.Receipt{Status: types.ReceiptStatusFailed, CumulativeGasUsed: 21000, Logs: []*types.Log{}}
 	blockTwo := types.NewBlockWithHeader(&types.Header{
 		Number:      big.NewInt(2),
 		Extra:       []byte("bad block two"),
@@ -219,7 +223,14 @@ func TestBadBlockStorage(t *testing.T) {
 		TxHash:      types.EmptyRootHash,
 		ReceiptHash: types.EmptyRootHash,
 	})
-	WriteBadBlock(db, blockTwo)
+	WriteBadBlockWithReport(db, blockTwo, types.Receipts{receipt}, []byte("report"))
+	receipts, report := ReadBadBlockReport(db, blockTwo.Hash())
+	if len(receipts) != 1 || receipts[0].CumulativeGasUsed != receipt.CumulativeGasUsed || receipts[0].Status != receipt.Status {
+		t.Fatalf("Retrieved receipts mismatch: have %v, want %v", receipts, types.Receipts{receipt})
+	}
+	if string(report) != "report" {
+		t.Fatalf("Retrieved report mismatch: have %q, want %q", report, "report")
+	}
 
 	// Write the block one again, should be filtered out.
 	WriteBadBlock(db, block)
@@ -0,0 +1,69 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// errSimulationState is returned if the EVM of a simulation does not run on top
+// of a state database that can be copied.
+var errSimulationState = errors.New("simulation requires a *state.StateDB")
+
+// SimulateSequence applies msgs in order on top of a copy of the EVM's state,
+// so that every message observes the effects of the previous ones, exactly as
+// if they were included in a block one after the other. The EVM's own state is
+// left untouched and nothing is committed.
+//
+// It returns the result of every message along with the state after the last
+// one, which callers may diff against the original state. If the EVM is
+// configured with RecordBalanceChanges, the results also carry the balance
+// changes of each message. If a message cannot be applied, the results of the
+// preceding ones are returned along with the error.
+func SimulateSequence(evm *vm.EVM, msgs []Message, gp GasPooler) ([]*ExecutionResult, *state.StateDB, error) {
+	parent, ok := evm.StateDB.(*state.StateDB)
+	if !ok {
+		return nil, nil, errSimulationState
+	}
+	var (
+		statedb = parent.Copy()
+		rules   = evm.ChainConfig().Rules(evm.Context.BlockNumber, evm.Context.Random != nil)
+		results = make([]*ExecutionResult, 0, len(msgs))
+	)
+	// Hand the original state back to the EVM once done
+	defer evm.Reset(evm.TxContext, parent)
+
+	for i, msg := range msgs {
+		statedb.Prepare(common.Hash{}, i)
+		evm.Reset(NewEVMTxContext(msg), statedb)
+
+		result, err := ApplyMessage(evm, msg, gp)
+		if err != nil {
+			return results, statedb, fmt.Errorf("could not apply message %d: %w", i, err)
+		}
+		results = append(results, result)
+
+		// Finalise the message like the end of a transaction in a block
+		statedb.Finalise(rules.IsEIP158)
+	}
+	return results, statedb, nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// TestSimulateSequence tests that simulated messages observe the effects of the
+// preceding ones without modifying the original state.
+func TestSimulateSequence(t *testing.T) {
+	var (
+		to      = common.HexToAddress("0x2222")
+		value   = big.NewInt(1000)
+		statedb = newTransitionTestState(nil)
+		evm     = newTransitionTestEVM(params.TestChainConfig, statedb, vm.Config{RecordBalanceChanges: true})
+		gp      = new(GasPool).AddGas(evm.Context.GasLimit)
+	)
+	msgs := []Message{
+		newTransitionTestMessage(0, &to, value, params.TxGas, nil),
+		newTransitionTestMessage(1, &to, value, params.TxGas, nil),
+	}
+	results, post, err := SimulateSequence(evm, msgs, gp)
+	if err != nil {
+		t.Fatalf("failed to simulate sequence: %v", err)
+	}
+	if len(results) != len(msgs) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(results), len(msgs))
+	}
+	for i, result := range results {
+		if result.Failed() || len(result.BalanceChanges) == 0 {
+			t.Errorf("result %d: unexpected outcome: err %v, %d balance changes", i, result.Err, len(result.BalanceChanges))
+		}
+	}
+	if have, want := post.GetBalance(to), new(big.Int).Mul(value, big.NewInt(2)); have.Cmp(want) != 0 {
+		t.Errorf("recipient balance mismatch: have %v, want %v", have, want)
+	}
+	if nonce := post.GetNonce(transitionTestSender); nonce != 2 {
+		t.Errorf("sender nonce mismatch: have %d, want 2", nonce)
+	}
+	if statedb.Exist(to) || statedb.GetNonce(transitionTestSender) != 0 || evm.StateDB != statedb {
+		t.Errorf("original state modified")
+	}
+	// A message that cannot be applied stops the sequence
+	msgs[1] = newTransitionTestMessage(5, &to, value, params.TxGas, nil)
+	results, _, err = SimulateSequence(evm, msgs, gp)
+	if !errors.Is(err, ErrNonceTooHigh) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrNonceTooHigh)
+	}
+	if len(results) != 1 {
+		t.Errorf("result count mismatch: have %d, want 1", len(results))
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// accessRecorder tracks the accounts accessed through a state database, so
+// that transactions executed speculatively on independent copies of the state
+// can be checked for conflicts and merged back.
+type accessRecorder struct {
+	reads   map[common.Address]struct{}     // Accounts looked up, whether existing or not
+	writes  map[common.Address]struct{}     // Accounts modified by finalised transactions
+	created map[common.Address]*stateObject // Latest object (re)created for an address
+}
+
+func newAccessRecorder() *accessRecorder {
+	return &accessRecorder{
+		reads:   make(map[common.Address]struct{}),
+		writes:  make(map[common.Address]struct{}),
+		created: make(map[common.Address]*stateObject),
+	}
+}
+
+// StartAccessRecording starts recording the accounts read, written and
+// created through the state, discarding anything recorded previously.
+func (s *StateDB) StartAccessRecording() {
+	s.recorder = newAccessRecorder()
+}
+
+// StopAccessRecording stops recording account accesses.
+func (s *StateDB) StopAccessRecording() {
+	s.recorder = nil
+}
+
+// AccessedAccounts returns the set of accounts read since access recording was
+// started. Modifying an account implies reading it.
+func (s *StateDB) AccessedAccounts() map[common.Address]struct{} {
+	if s.recorder == nil {
+		return nil
+	}
+	reads := make(map[common.Address]struct{}, len(s.recorder.reads))
+	for addr := range s.recorder.reads {
+		reads[addr] = struct{}{}
+	}
+	return reads
+}
+
+// WrittenAccounts returns the set of accounts modified by the transactions
+// finalised since access recording was started.
+func (s *StateDB) WrittenAccounts() map[common.Address]struct{} {
+	if s.recorder == nil {
+		return nil
+	}
+	writes := make(map[common.Address]struct{}, len(s.recorder.writes))
+	for addr := range s.recorder.writes {
+		writes[addr] = struct{}{}
+	}
+	return writes
+}
+
+// MergeAccounts overwrites the given accounts with their state in src, which
+// must be a copy of s with access recording enabled, onto which transactions
+// were applied and finalised. It is only correct if the merged accounts were
+// not modified in s since the copy was made.
+//
+// Accounts deleted in src are destructed, accounts recreated in src have their
+// storage reset. The changes are journalled, so s needs to be finalised.
+func (s *StateDB) MergeAccounts(src *StateDB, addrs []common.Address) {
+	for _, addr := range addrs {
+		obj := src.stateObjects[addr]
+		if obj == nil {
+			continue
+		}
+		if obj.deleted {
+			s.Suicide(addr)
+			continue
+		}
+		dst := s.getStateObject(addr)
+		if dst == nil || (src.recorder != nil && src.recorder.created[addr] == obj) {
+			dst, _ = s.createObject(addr)
+		}
+		dst.SetBalance(obj.Balance())
+		dst.SetNonce(obj.Nonce())
+		if !bytes.Equal(dst.CodeHash(), obj.CodeHash()) {
+			dst.SetCode(common.BytesToHash(obj.CodeHash()), obj.Code(src.db))
+		}
+		// Slots only read in src are unchanged in s too, so it's safe to copy
+		// them along with the written ones, which may already have been moved
+		// from the pending set into the origin one by hashing the state.
+		for key, value := range obj.originStorage {
+			if _, pending := obj.pendingStorage[key]; !pending {
+				dst.SetState(s.db, key, value)
+			}
+		}
+		for key, value := range obj.pendingStorage {
+			dst.SetState(s.db, key, value)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// checkpoint is an undo layer holding the accounts modified after a checkpoint
+// was taken, as they were at that time. Accounts are copied upon their first
+// modification, so the cost of a checkpoint is proportional to the changes made
+// after it rather than to the size of the state.
+type checkpoint struct {
+	accounts map[common.Address]*accountCheckpoint
+	logSize  uint          // Number of logs when the checkpoint was taken
+	logs     []common.Hash // Transactions which emitted their first log after it
+}
+
+// accountCheckpoint is the state of an account at a checkpoint, including its
+// pending snapshot data.
+type accountCheckpoint struct {
+	object   *stateObject // nil if the account didn't exist
+	destruct bool
+	account  []byte
+	storage  map[common.Hash][]byte
+}
+
+// Checkpoint returns an identifier for the current state, which can be used to
+// return to it later. Unlike Snapshot, checkpoints are meant to be taken between
+// transactions and remain valid across Finalise. They are kept until reverted
+// to or released.
+func (s *StateDB) Checkpoint() int {
+	s.checkpoints = append(s.checkpoints, &checkpoint{
+		accounts: make(map[common.Address]*accountCheckpoint),
+		logSize:  s.logSize,
+	})
+	s.journal.onDirty = s.checkpointAccount
+	return len(s.checkpoints) - 1
+}
+
+// RevertToCheckpoint restores the state recorded by the given checkpoint. All
+// checkpoints taken after it are discarded. It must not be called while a
+// transaction is being executed.
+func (s *StateDB) RevertToCheckpoint(id int) {
+	if id < 0 || id >= len(s.checkpoints) {
+		panic(fmt.Errorf("checkpoint id %v cannot be reverted", id))
+	}
+	s.clearJournalAndRefund()
+
+	// Undo the layers from the newest one, each restoring the accounts first
+	// modified after it to their state at the time it was taken.
+	for i := len(s.checkpoints) - 1; i >= id; i-- {
+		cp := s.checkpoints[i]
+		for addr, account := range cp.accounts {
+			s.restoreAccount(addr, account)
+		}
+		for _, hash := range cp.logs {
+			delete(s.logs, hash)
+		}
+		s.logSize = cp.logSize
+	}
+	s.checkpoints = s.checkpoints[:id+1]
+	s.checkpoints[id] = &checkpoint{
+		accounts: make(map[common.Address]*accountCheckpoint),
+		logSize:  s.logSize,
+	}
+}
+
+// ReleaseCheckpoints discards all checkpoints, which can't be reverted to
+// afterwards, and stops tracking the changes made to the state for them.
+func (s *StateDB) ReleaseCheckpoints() {
+	s.checkpoints = nil
+	s.journal.onDirty = nil
+}
+
+// checkpointAccount records the current state of an account in the latest
+// checkpoint, unless it was already modified since it was taken. It must be
+// called before the account is modified.
+func (s *StateDB) checkpointAccount(addr common.Address) {
+	if len(s.checkpoints) == 0 {
+		return
+	}
+	cp := s.checkpoints[len(s.checkpoints)-1]
+	if _, ok := cp.accounts[addr]; ok {
+		return
+	}
+	account := new(accountCheckpoint)
+	if obj := s.stateObjects[addr]; obj != nil {
+		account.object = obj.deepCopy(s)
+	}
+	if s.snap != nil {
+		hash := crypto.Keccak256Hash(addr[:])
+		_, account.destruct = s.snapDestructs[hash]
+		account.account = s.snapAccounts[hash]
+		if storage := s.snapStorage[hash]; storage != nil {
+			account.storage = make(map[common.Hash][]byte, len(storage))
+			for key, value := range storage {
+				account.storage[key] = value
+			}
+		}
+	}
+	cp.accounts[addr] = account
+}
+
+// restoreAccount reinstates the state of an account recorded by a checkpoint.
+// The account is marked as pending, so the tries get updated to match it.
+func (s *StateDB) restoreAccount(addr common.Address, account *accountCheckpoint) {
+	obj := account.object
+	if obj == nil {
+		// Accounts not existing at the checkpoint are kept as deleted, so they
+		// are removed from the trie if they've been written to it meanwhile.
+		obj = newObject(s, addr, types.StateAccount{})
+		obj.deleted = true
+	}
+	s.stateObjects[addr] = obj
+	s.stateObjectsPending[addr] = struct{}{}
+	s.stateObjectsDirty[addr] = struct{}{}
+
+	if s.snap != nil {
+		if account.destruct {
+			s.snapDestructs[obj.addrHash] = struct{}{}
+		} else {
+			delete(s.snapDestructs, obj.addrHash)
+		}
+		if account.account != nil {
+			s.snapAccounts[obj.addrHash] = account.account
+		} else {
+			delete(s.snapAccounts, obj.addrHash)
+		}
+		if account.storage != nil {
+			s.snapStorage[obj.addrHash] = account.storage
+		} else {
+			delete(s.snapStorage, obj.addrHash)
+		}
+	}
+}
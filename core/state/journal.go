@@ -38,6 +38,8 @@ type journalEntry interface {
 type journal struct {
 	entries []journalEntry         // Current changes tracked by the journal
 	dirties map[common.Address]int // Dirty accounts and the number of changes
+
+	onDirty func(common.Address) // Called with the account of an entry before it is applied
 }
 
 // newJournal creates a new initialized journal.
@@ -51,6 +53,9 @@ func newJournal() *journal {
 func (j *journal) append(entry journalEntry) {
 	j.entries = append(j.entries, entry)
 	if addr := entry.dirtied(); addr != nil {
+		if j.onDirty != nil {
+			j.onDirty(*addr)
+		}
 		j.dirties[*addr]++
 	}
 }
@@ -84,6 +89,19 @@ func (j *journal) length() int {
 	return len(j.entries)
 }
 
+// modified reports whether any entry from the given index onwards changes an
+// account's existence, balance, nonce, code or storage.
+func (j *journal) modified(from int) bool {
+	for _, entry := range j.entries[from:] {
+		switch entry.(type) {
+		case createObjectChange, resetObjectChange, suicideChange,
+			balanceChange, nonceChange, storageChange, codeChange:
+			return true
+		}
+	}
+	return false
+}
+
 type (
 	// Changes to the account trie.
 	createObjectChange struct {
@@ -93,6 +111,9 @@ type (
 		prev         *stateObject
 		prevdestruct bool
 	}
+	createContractChange struct {
+		account *common.Address
+	}
 	suicideChange struct {
 		account     *common.Address
 		prev        bool // whether account had already suicided
@@ -112,6 +133,10 @@ type (
 		account       *common.Address
 		key, prevalue common.Hash
 	}
+	transientStorageChange struct {
+		account       *common.Address
+		key, prevalue common.Hash
+	}
 	codeChange struct {
 		account            *common.Address
 		prevcode, prevhash []byte
@@ -160,6 +185,14 @@ func (ch resetObjectChange) dirtied() *common.Address {
 	return nil
 }
 
+func (ch createContractChange) revert(s *StateDB) {
+	s.getStateObject(*ch.account).created = false
+}
+
+func (ch createContractChange) dirtied() *common.Address {
+	return ch.account
+}
+
 func (ch suicideChange) revert(s *StateDB) {
 	obj := s.getStateObject(*ch.account)
 	if obj != nil {
@@ -213,6 +246,14 @@ func (ch storageChange) dirtied() *common.Address {
 	return ch.account
 }
 
+func (ch transientStorageChange) revert(s *StateDB) {
+	s.setTransientState(*ch.account, ch.key, ch.prevalue)
+}
+
+func (ch transientStorageChange) dirtied() *common.Address {
+	return nil
+}
+
 func (ch refundChange) revert(s *StateDB) {
 	s.refund = ch.prev
 }
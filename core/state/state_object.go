@@ -91,6 +91,10 @@ type stateObject struct {
 	dirtyCode bool // true if the code was updated
 	suicided  bool
 	deleted   bool
+
+	// Flag whether the account was deployed as a contract in the current
+	// transaction, in which case it can still be destroyed after EIP-6780.
+	created bool
 }
 
 // empty returns whether the account is considered empty.
@@ -174,6 +178,9 @@ func (s *stateObject) GetState(db Database, key common.Hash) common.Hash {
 	if s.fakeStorage != nil {
 		return s.fakeStorage[key]
 	}
+	if s.db.touches != nil {
+		s.db.touches.recordSlot(s.address, key)
+	}
 	// If we have a dirty value for this state entry, return it
 	value, dirty := s.dirtyStorage[key]
 	if dirty {
@@ -229,6 +236,9 @@ func (s *stateObject) GetCommittedState(db Database, key common.Hash) common.Has
 			return common.Hash{}
 		}
 	}
+	if s.db.witness != nil {
+		s.db.witness.recordSlot(s.address, key)
+	}
 	var value common.Hash
 	if len(enc) > 0 {
 		_, content, _, err := rlp.Split(enc)
@@ -441,6 +451,7 @@ func (s *stateObject) deepCopy(db *StateDB) *stateObject {
 	stateObject.suicided = s.suicided
 	stateObject.dirtyCode = s.dirtyCode
 	stateObject.deleted = s.deleted
+	stateObject.created = s.created
 	return stateObject
 }
 
@@ -461,6 +472,9 @@ func (s *stateObject) Code(db Database) []byte {
 	if bytes.Equal(s.CodeHash(), emptyCodeHash) {
 		return nil
 	}
+	if s.db.witness != nil {
+		s.db.witness.codes[s.address] = struct{}{}
+	}
 	code, err := db.ContractCode(s.addrHash, common.BytesToHash(s.CodeHash()))
 	if err != nil {
 		s.setError(fmt.Errorf("can't load code hash %x: %v", s.CodeHash(), err))
@@ -479,6 +493,9 @@ func (s *stateObject) CodeSize(db Database) int {
 	if bytes.Equal(s.CodeHash(), emptyCodeHash) {
 		return 0
 	}
+	if s.db.witness != nil {
+		s.db.witness.codes[s.address] = struct{}{}
+	}
 	size, err := db.ContractCodeSize(s.addrHash, common.BytesToHash(s.CodeHash()))
 	if err != nil {
 		s.setError(fmt.Errorf("can't load code size %x: %v", s.CodeHash(), err))
@@ -18,6 +18,7 @@
 package state
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"math/big"
@@ -102,12 +103,29 @@ type StateDB struct {
 	// Per-transaction access list
 	accessList *accessList
 
+	// Transient storage, discarded at the end of every transaction (EIP-1153)
+	transientStorage transientStorage
+
 	// Journal of state modifications. This is the backbone of
 	// Snapshot and RevertToSnapshot.
 	journal        *journal
 	validRevisions []revision
 	nextRevisionId int
 
+	// Undo layers of the checkpoints taken between transactions. Contrary to
+	// snapshots, these survive Finalise and can be returned to across
+	// transactions.
+	checkpoints []*checkpoint
+
+	// Recorder of the accounts accessed, used for speculative execution
+	recorder *accessRecorder
+
+	// Recorder of the pre-state loaded, used for execution witnesses
+	witness *witnessRecorder
+
+	// Recorder of the accounts and slots accessed, used for block access lists
+	touches touchRecorder
+
 	// Measurements gathered during execution for debugging purposes
 	AccountReads         time.Duration
 	AccountHashes        time.Duration
@@ -145,6 +163,7 @@ func New(root common.Hash, db Database, snaps *snapshot.Tree) (*StateDB, error)
 		preimages:           make(map[common.Hash][]byte),
 		journal:             newJournal(),
 		accessList:          newAccessList(),
+		transientStorage:    newTransientStorage(),
 		hasher:              crypto.NewKeccakState(),
 	}
 	if sdb.snaps != nil {
@@ -192,6 +211,9 @@ func (s *StateDB) Error() error {
 
 func (s *StateDB) AddLog(log *types.Log) {
 	s.journal.append(addLogChange{txhash: s.thash})
+	if n := len(s.checkpoints); n > 0 && len(s.logs[s.thash]) == 0 {
+		s.checkpoints[n-1].logs = append(s.checkpoints[n-1].logs, s.thash)
+	}
 
 	log.TxHash = s.thash
 	log.TxIndex = uint(s.txIndex)
@@ -373,6 +395,22 @@ func (s *StateDB) HasSuicided(addr common.Address) bool {
 	return false
 }
 
+// EmptyTouchedAccounts returns the accounts touched since the last Finalise
+// which are empty but not self-destructed, i.e. the ones EIP-161 state
+// clearing deletes when the transaction is finalised. The result is sorted.
+func (s *StateDB) EmptyTouchedAccounts() []common.Address {
+	var accounts []common.Address
+	for addr := range s.journal.dirties {
+		if obj, exist := s.stateObjects[addr]; exist && !obj.deleted && !obj.suicided && obj.empty() {
+			accounts = append(accounts, addr)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return bytes.Compare(accounts[i][:], accounts[j][:]) < 0
+	})
+	return accounts
+}
+
 /*
  * SETTERS
  */
@@ -451,6 +489,17 @@ func (s *StateDB) Suicide(addr common.Address) bool {
 	return true
 }
 
+// Suicide6780 is like Suicide, but only destroys the account if it was deployed
+// as a contract in the current transaction, as mandated by EIP-6780. Otherwise
+// the account is left untouched, it is up to the caller to move its balance.
+func (s *StateDB) Suicide6780(addr common.Address) bool {
+	stateObject := s.getStateObject(addr)
+	if stateObject == nil || !stateObject.created {
+		return false
+	}
+	return s.Suicide(addr)
+}
+
 //
 // Setting, updating & deleting state object methods.
 //
@@ -504,6 +553,19 @@ func (s *StateDB) getStateObject(addr common.Address) *stateObject {
 // flag set. This is needed by the state journal to revert to the correct s-
 // destructed object instead of wiping all knowledge about the state object.
 func (s *StateDB) getDeletedStateObject(addr common.Address) *stateObject {
+	if s.recorder != nil {
+		s.recorder.reads[addr] = struct{}{}
+	}
+	if s.witness != nil {
+		if _, ok := s.witness.accounts[addr]; !ok {
+			s.witness.accounts[addr] = nil
+		}
+	}
+	if s.touches != nil {
+		if _, ok := s.touches[addr]; !ok {
+			s.touches[addr] = nil
+		}
+	}
 	// Prefer live objects if any is available
 	if obj := s.stateObjects[addr]; obj != nil {
 		return obj
@@ -578,6 +640,10 @@ func (s *StateDB) GetOrNewStateObject(addr common.Address) *stateObject {
 func (s *StateDB) createObject(addr common.Address) (newobj, prev *stateObject) {
 	prev = s.getDeletedStateObject(addr) // Note, prev might have been deleted, we need that!
 
+	// Resetting an account isn't journalled with its address, checkpoint it
+	// before the snapshot data gets modified too.
+	s.checkpointAccount(addr)
+
 	var prevdestruct bool
 	if s.snap != nil && prev != nil {
 		_, prevdestruct = s.snapDestructs[prev.addrHash]
@@ -586,6 +652,9 @@ func (s *StateDB) createObject(addr common.Address) (newobj, prev *stateObject)
 		}
 	}
 	newobj = newObject(s, addr, types.StateAccount{})
+	if s.recorder != nil {
+		s.recorder.created[addr] = newobj
+	}
 	if prev == nil {
 		s.journal.append(createObjectChange{account: &addr})
 	} else {
@@ -615,6 +684,18 @@ func (s *StateDB) CreateAccount(addr common.Address) {
 	}
 }
 
+// CreateContract marks the account at addr as a contract created in the current
+// transaction, which EIP-6780 still allows to be destroyed. It is called during
+// the EVM CREATE operation, after the account itself has been created.
+func (s *StateDB) CreateContract(addr common.Address) {
+	obj := s.getStateObject(addr)
+	if obj == nil || obj.created {
+		return
+	}
+	s.journal.append(createContractChange{account: &addr})
+	obj.created = true
+}
+
 func (db *StateDB) ForEachStorage(addr common.Address, cb func(key, value common.Hash) bool) error {
 	so := db.getStateObject(addr)
 	if so == nil {
@@ -647,8 +728,28 @@ func (db *StateDB) ForEachStorage(addr common.Address, cb func(key, value common
 // Copy creates a deep, independent copy of the state.
 // Snapshots of the copied state cannot be applied to the copy.
 func (s *StateDB) Copy() *StateDB {
+	return s.copyInto(new(StateDB))
+}
+
+// CopyFrom overwrites the state with a deep, independent copy of src, which
+// must be backed by the same database. The prefetcher of s, if any, is kept,
+// while its checkpoints and recordings are discarded.
+func (s *StateDB) CopyFrom(src *StateDB) {
+	prefetcher := s.prefetcher
+	src.copyInto(s)
+	s.prefetcher = prefetcher
+}
+
+// OriginalRoot returns the root of the state the database was opened at or
+// last committed, on top of which all pending changes apply.
+func (s *StateDB) OriginalRoot() common.Hash {
+	return s.originalRoot
+}
+
+// copyInto overwrites the given state with a deep, independent copy of s.
+func (s *StateDB) copyInto(state *StateDB) *StateDB {
 	// Copy all the basic fields, initialize the memory ones
-	state := &StateDB{
+	*state = StateDB{
 		db:                  s.db,
 		trie:                s.db.CopyTrie(s.trie),
 		originalRoot:        s.originalRoot,
@@ -710,6 +811,7 @@ func (s *StateDB) Copy() *StateDB {
 	// However, it doesn't cost us much to copy an empty list, so we do it anyway
 	// to not blow up if we ever decide copy it in the middle of a transaction
 	state.accessList = s.accessList.Copy()
+	state.transientStorage = s.transientStorage.Copy()
 
 	// If there's a prefetcher running, make an inactive copy of it that can
 	// only access data but does not actively preload (since the user will not
@@ -769,6 +871,19 @@ func (s *StateDB) RevertToSnapshot(revid int) {
 	s.validRevisions = s.validRevisions[:idx]
 }
 
+// ModifiedSince reports whether any account balance, nonce, code or storage
+// has been changed since the given revision. Changes which were reverted in
+// the meantime are not taken into account.
+func (s *StateDB) ModifiedSince(revid int) bool {
+	idx := sort.Search(len(s.validRevisions), func(i int) bool {
+		return s.validRevisions[i].id >= revid
+	})
+	if idx == len(s.validRevisions) || s.validRevisions[idx].id != revid {
+		panic(fmt.Errorf("revision id %v is not valid", revid))
+	}
+	return s.journal.modified(s.validRevisions[idx].journalIndex)
+}
+
 // GetRefund returns the current value of the refund counter.
 func (s *StateDB) GetRefund() uint64 {
 	return s.refund
@@ -780,6 +895,9 @@ func (s *StateDB) GetRefund() uint64 {
 func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 	addressesToPrefetch := make([][]byte, 0, len(s.journal.dirties))
 	for addr := range s.journal.dirties {
+		if s.recorder != nil {
+			s.recorder.writes[addr] = struct{}{}
+		}
 		obj, exist := s.stateObjects[addr]
 		if !exist {
 			// ripeMD is 'touched' at block 1714175, in tx 0x1237f737031e40bcde4a8b7e717b2d15e3ecadfe49bb1bbc71ee9deb09c6fcf2
@@ -805,6 +923,7 @@ func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 		} else {
 			obj.finalise(true) // Prefetch slots in the background
 		}
+		obj.created = false
 		s.stateObjectsPending[addr] = struct{}{}
 		s.stateObjectsDirty[addr] = struct{}{}
 
@@ -888,11 +1007,14 @@ func (s *StateDB) IntermediateRoot(deleteEmptyObjects bool) common.Hash {
 func (s *StateDB) Prepare(thash common.Hash, ti int) {
 	s.thash = thash
 	s.txIndex = ti
+	s.transientStorage = newTransientStorage()
 }
 
 func (s *StateDB) clearJournalAndRefund() {
 	if len(s.journal.entries) > 0 {
+		onDirty := s.journal.onDirty
 		s.journal = newJournal()
+		s.journal.onDirty = onDirty
 		s.refund = 0
 	}
 	s.validRevisions = s.validRevisions[:0] // Snapshots can be created without journal entires
@@ -1051,3 +1173,30 @@ func (s *StateDB) AddressInAccessList(addr common.Address) bool {
 func (s *StateDB) SlotInAccessList(addr common.Address, slot common.Hash) (addressPresent bool, slotPresent bool) {
 	return s.accessList.Contains(addr, slot)
 }
+
+// SetTransientState sets transient storage for a given account. It
+// adds the change to the journal so that it can be rolled back
+// to its previous value if there is a revert.
+func (s *StateDB) SetTransientState(addr common.Address, key, value common.Hash) {
+	prev := s.GetTransientState(addr, key)
+	if prev == value {
+		return
+	}
+	s.journal.append(transientStorageChange{
+		account:  &addr,
+		key:      key,
+		prevalue: prev,
+	})
+	s.setTransientState(addr, key, value)
+}
+
+// setTransientState is a lower level setter for transient storage. It
+// is called during a revert to prevent modifications to the journal.
+func (s *StateDB) setTransientState(addr common.Address, key, value common.Hash) {
+	s.transientStorage.Set(addr, key, value)
+}
+
+// GetTransientState gets transient storage for a given account.
+func (s *StateDB) GetTransientState(addr common.Address, key common.Hash) common.Hash {
+	return s.transientStorage.Get(addr, key)
+}
@@ -915,3 +915,156 @@ func TestStateDBAccessList(t *testing.T) {
 		t.Fatalf("expected empty, got %d", got)
 	}
 }
+
+// Tests that transient storage is journaled for reverts, copied along with the
+// state and discarded when preparing the next transaction.
+func TestTransientStorage(t *testing.T) {
+	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	key := common.Hash{0x01}
+	value := common.Hash{0x02}
+	addr := common.Address{}
+
+	state.SetTransientState(addr, key, value)
+	if exp, got := 1, state.journal.length(); exp != got {
+		t.Fatalf("journal length mismatch: have %d, want %d", got, exp)
+	}
+	// the retrieved value should equal what was set
+	if got := state.GetTransientState(addr, key); got != value {
+		t.Fatalf("transient storage mismatch: have %x, want %x", got, value)
+	}
+	// revert the transient state being set and then check that the
+	// value is now the empty hash
+	state.journal.revert(state, 0)
+	if got, exp := state.GetTransientState(addr, key), (common.Hash{}); exp != got {
+		t.Fatalf("transient storage mismatch: have %x, want %x", got, exp)
+	}
+	// set transient state and then copy the statedb and ensure that
+	// the transient state is copied
+	state.SetTransientState(addr, key, value)
+	cpy := state.Copy()
+	if got := cpy.GetTransientState(addr, key); got != value {
+		t.Fatalf("transient storage mismatch: have %x, want %x", got, value)
+	}
+	// preparing the next transaction discards the transient state
+	state.Prepare(common.Hash{0x03}, 1)
+	if got, exp := state.GetTransientState(addr, key), (common.Hash{}); exp != got {
+		t.Fatalf("transient storage mismatch: have %x, want %x", got, exp)
+	}
+}
+
+// Tests that after EIP-6780 only accounts created in the current transaction
+// can be destroyed, and that the destruction is reverted with the journal.
+func TestSuicide6780(t *testing.T) {
+	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	existing := common.Address{0x01}
+	state.SetBalance(existing, big.NewInt(1))
+	state.Finalise(true)
+
+	if state.Suicide6780(existing) {
+		t.Fatalf("pre-existing account destroyed")
+	}
+	if state.HasSuicided(existing) || state.GetBalance(existing).Sign() == 0 {
+		t.Fatalf("pre-existing account modified")
+	}
+	// Accounts created without deploying a contract are not new contracts
+	funded := common.Address{0x03}
+	state.AddBalance(funded, big.NewInt(1))
+	if state.Suicide6780(funded) {
+		t.Fatalf("account created by a balance change destroyed")
+	}
+	created := common.Address{0x02}
+	state.CreateAccount(created)
+	state.CreateContract(created)
+	state.SetBalance(created, big.NewInt(1))
+
+	snap := state.Snapshot()
+	if !state.Suicide6780(created) {
+		t.Fatalf("account created in the transaction not destroyed")
+	}
+	if !state.HasSuicided(created) {
+		t.Fatalf("account created in the transaction not marked suicided")
+	}
+	state.RevertToSnapshot(snap)
+	if state.HasSuicided(created) || state.GetBalance(created).Cmp(big.NewInt(1)) != 0 {
+		t.Fatalf("destruction not reverted")
+	}
+	// Once the transaction is over, the account is no longer considered new
+	state.Finalise(true)
+	if state.Suicide6780(created) {
+		t.Fatalf("account created in a previous transaction destroyed")
+	}
+}
+
+// TestCheckpoints tests that reverting to a checkpoint taken between
+// transactions restores the accounts, storage and logs modified afterwards,
+// including accounts created and destroyed since.
+func TestCheckpoints(t *testing.T) {
+	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	var (
+		a = common.Address{0x01}
+		b = common.Address{0x02}
+		c = common.Address{0x03}
+	)
+	state.SetBalance(a, big.NewInt(1))
+	state.SetState(a, common.Hash{0x01}, common.Hash{0x01})
+	state.SetBalance(b, big.NewInt(2))
+	state.Finalise(true)
+	root0 := state.Copy().IntermediateRoot(true)
+	cp0 := state.Checkpoint()
+
+	// Modify and destroy existing accounts, create a new one
+	state.Prepare(common.Hash{0x01}, 0)
+	state.SetState(a, common.Hash{0x01}, common.Hash{0x02})
+	state.Suicide(b)
+	state.SetBalance(c, big.NewInt(3))
+	state.AddLog(&types.Log{Address: c})
+	state.Finalise(true)
+	root1 := state.IntermediateRoot(true)
+	cp1 := state.Checkpoint()
+
+	state.Prepare(common.Hash{0x02}, 1)
+	state.SetBalance(a, big.NewInt(4))
+	state.AddLog(&types.Log{Address: a})
+	state.Finalise(true)
+
+	if cp0 == cp1 {
+		t.Fatalf("checkpoint ids not distinct: %d", cp0)
+	}
+	state.RevertToCheckpoint(cp1)
+	if have := state.GetBalance(a); have.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("balance after revert to checkpoint 1 mismatch: have %v, want 1", have)
+	}
+	if have := len(state.Logs()); have != 1 {
+		t.Errorf("logs after revert to checkpoint 1 mismatch: have %d, want 1", have)
+	}
+	if have := state.IntermediateRoot(true); have != root1 {
+		t.Errorf("root after revert to checkpoint 1 mismatch: have %x, want %x", have, root1)
+	}
+	state.RevertToCheckpoint(cp0)
+	if have := state.GetState(a, common.Hash{0x01}); have != (common.Hash{0x01}) {
+		t.Errorf("storage after revert to checkpoint 0 mismatch: have %x, want %x", have, common.Hash{0x01})
+	}
+	if have := state.GetBalance(b); have.Cmp(big.NewInt(2)) != 0 {
+		t.Errorf("destroyed account not restored: balance %v, want 2", have)
+	}
+	if state.Exist(c) {
+		t.Errorf("created account not removed")
+	}
+	if have := len(state.Logs()); have != 0 {
+		t.Errorf("logs after revert to checkpoint 0 mismatch: have %d, want 0", have)
+	}
+	if have, _ := state.Commit(true); have != root0 {
+		t.Errorf("root after revert to checkpoint 0 mismatch: have %x, want %x", have, root0)
+	}
+	// Released checkpoints can't be reverted to
+	state.ReleaseCheckpoints()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("reverting to a released checkpoint didn't panic")
+		}
+	}()
+	state.RevertToCheckpoint(cp0)
+}
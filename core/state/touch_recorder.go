@@ -0,0 +1,67 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// touchRecorder tracks every account and storage slot accessed through a state
+// database, whether loaded from the database or already cached.
+type touchRecorder map[common.Address]map[common.Hash]struct{}
+
+// recordSlot records the access of a storage slot of an account.
+func (t touchRecorder) recordSlot(addr common.Address, key common.Hash) {
+	if t[addr] == nil {
+		t[addr] = make(map[common.Hash]struct{})
+	}
+	t[addr][key] = struct{}{}
+}
+
+// StartTouchRecording starts recording the accounts and storage slots accessed
+// through the state, discarding anything recorded previously.
+func (s *StateDB) StartTouchRecording() {
+	s.touches = make(touchRecorder)
+}
+
+// StopTouchRecording stops recording the accounts and storage slots accessed.
+func (s *StateDB) StopTouchRecording() {
+	s.touches = nil
+}
+
+// TouchedAccessList returns the accounts and storage slots accessed since touch
+// recording was started as an access list, sorted by address and slot.
+func (s *StateDB) TouchedAccessList() types.AccessList {
+	if s.touches == nil {
+		return nil
+	}
+	list := make(types.AccessList, 0, len(s.touches))
+	for addr, slots := range s.touches {
+		keys := make([]common.Hash, 0, len(slots))
+		for key := range slots {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+		list = append(list, types.AccessTuple{Address: addr, StorageKeys: keys})
+	}
+	sort.Slice(list, func(i, j int) bool { return bytes.Compare(list[i].Address[:], list[j].Address[:]) < 0 })
+	return list
+}
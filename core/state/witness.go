@@ -0,0 +1,143 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Witness is the part of the pre-state accessed while executing transactions,
+// allowing stateless clients to execute them without the full state: the trie
+// nodes proving every account and storage slot accessed against the pre-state
+// root, and the code of the contracts accessed.
+type Witness struct {
+	Root  common.Hash // Pre-state root the nodes prove against
+	Nodes [][]byte    // Account and storage trie nodes, sorted
+	Codes [][]byte    // Contract codes, sorted
+}
+
+// witnessRecorder tracks the parts of the pre-state loaded through a state
+// database, which are later proven against its original root.
+type witnessRecorder struct {
+	accounts map[common.Address]map[common.Hash]struct{} // Accounts and their storage slots loaded
+	codes    map[common.Address]struct{}                 // Accounts whose code was loaded
+}
+
+// recordSlot records the loading of a storage slot of an account.
+func (w *witnessRecorder) recordSlot(addr common.Address, key common.Hash) {
+	if w.accounts[addr] == nil {
+		w.accounts[addr] = make(map[common.Hash]struct{})
+	}
+	w.accounts[addr][key] = struct{}{}
+}
+
+// witnessNodes collects the trie nodes of proofs, deduplicated by hash.
+type witnessNodes map[string][]byte
+
+func (n witnessNodes) Put(key []byte, value []byte) error {
+	n[string(key)] = common.CopyBytes(value)
+	return nil
+}
+
+func (n witnessNodes) Delete(key []byte) error {
+	panic("not supported")
+}
+
+// StartWitnessRecording starts recording the accounts, storage slots and codes
+// loaded through the state, discarding anything recorded previously.
+func (s *StateDB) StartWitnessRecording() {
+	s.witness = &witnessRecorder{
+		accounts: make(map[common.Address]map[common.Hash]struct{}),
+		codes:    make(map[common.Address]struct{}),
+	}
+}
+
+// StopWitnessRecording stops recording the state loaded.
+func (s *StateDB) StopWitnessRecording() {
+	s.witness = nil
+}
+
+// Witness proves the state loaded since witness recording was started against
+// the pre-state root. It returns nil if witness recording is not enabled, and
+// must be called before the state is committed.
+func (s *StateDB) Witness() (*Witness, error) {
+	if s.witness == nil {
+		return nil, nil
+	}
+	tr, err := s.db.OpenTrie(s.originalRoot)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		nodes = make(witnessNodes)
+		codes = make(map[common.Hash][]byte)
+	)
+	for addr, slots := range s.witness.accounts {
+		addrHash := crypto.Keccak256Hash(addr.Bytes())
+		if err := tr.Prove(addrHash[:], 0, nodes); err != nil {
+			return nil, err
+		}
+		// Accounts missing from the pre-state are covered by the proof of absence
+		enc, err := tr.TryGet(addr.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		if len(enc) == 0 {
+			continue
+		}
+		var data types.StateAccount
+		if err := rlp.DecodeBytes(enc, &data); err != nil {
+			return nil, fmt.Errorf("invalid account %x: %v", addr, err)
+		}
+		if _, ok := s.witness.codes[addr]; ok && !bytes.Equal(data.CodeHash, emptyCodeHash) {
+			code, err := s.db.ContractCode(addrHash, common.BytesToHash(data.CodeHash))
+			if err != nil {
+				return nil, err
+			}
+			codes[common.BytesToHash(data.CodeHash)] = code
+		}
+		if len(slots) == 0 || data.Root == emptyRoot {
+			continue
+		}
+		st, err := s.db.OpenStorageTrie(addrHash, data.Root)
+		if err != nil {
+			return nil, err
+		}
+		for key := range slots {
+			if err := st.Prove(crypto.Keccak256(key.Bytes()), 0, nodes); err != nil {
+				return nil, err
+			}
+		}
+	}
+	witness := &Witness{Root: s.originalRoot}
+	for _, node := range nodes {
+		witness.Nodes = append(witness.Nodes, node)
+	}
+	for _, code := range codes {
+		witness.Codes = append(witness.Codes, code)
+	}
+	sort.Slice(witness.Nodes, func(i, j int) bool { return bytes.Compare(witness.Nodes[i], witness.Nodes[j]) < 0 })
+	sort.Slice(witness.Codes, func(i, j int) bool { return bytes.Compare(witness.Codes[i], witness.Codes[j]) < 0 })
+	return witness, nil
+}
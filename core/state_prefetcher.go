@@ -82,6 +82,47 @@ func (p *statePrefetcher) Prefetch(block *types.Block, statedb *state.StateDB, c
 	}
 }
 
+// PrefetchConcurrent warms the state caches for the given block by executing
+// its transactions on the given number of goroutines, each working on its own
+// copy of statedb, ahead of the block being processed on statedb itself. The
+// copies are taken before returning, after which statedb may be modified.
+//
+// The transactions are split among the workers, so each only sees the effects
+// of the ones it executed. Their messages are marked fake to bypass the nonce
+// checks this would otherwise fail; the execution only needs to be close enough
+// to touch the same state. The workers run until done or interrupted.
+func (p *statePrefetcher) PrefetchConcurrent(block *types.Block, statedb *state.StateDB, cfg vm.Config, workers int, interrupt *uint32) {
+	var (
+		header = block.Header()
+		signer = types.MakeSigner(p.config, header.Number)
+		txs    = block.Transactions()
+	)
+	// Nobody should observe the throwaway executions
+	cfg.Debug, cfg.Tracer, cfg.Hooks = false, nil, vm.TxHooks{}
+
+	if workers > len(txs) {
+		workers = len(txs)
+	}
+	for w := 0; w < workers; w++ {
+		go func(w int, statedb *state.StateDB) {
+			evm := vm.NewEVM(NewEVMBlockContext(header, p.bc, nil), vm.TxContext{}, statedb, p.config, cfg)
+			for i := w; i < len(txs); i += workers {
+				if atomic.LoadUint32(interrupt) == 1 {
+					return
+				}
+				msg, err := txs[i].AsMessage(signer, header.BaseFee)
+				if err != nil {
+					return // Also invalid block, bail out
+				}
+				msg = types.NewMessage(msg.From(), msg.To(), msg.Nonce(), msg.Value(), msg.Gas(), msg.GasPrice(), msg.GasFeeCap(), msg.GasTipCap(), msg.Data(), msg.AccessList(), true)
+
+				statedb.Prepare(txs[i].Hash(), i)
+				precacheTransaction(msg, p.config, new(GasPool).AddGas(block.GasLimit()), statedb, header, evm)
+			}
+		}(w, statedb.Copy())
+	}
+}
+
 // precacheTransaction attempts to apply a transaction to the given state database
 // and uses the input parameters for its environment. The goal is not to execute
 // the transaction successfully, rather to warm up touched data slots.
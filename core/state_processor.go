@@ -17,8 +17,12 @@
 package core
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"math/big"
+	"sync/atomic"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
@@ -27,7 +31,23 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+var (
+	processCacheHitMeter  = metrics.NewRegisteredMeter("chain/process/cache/hits", nil)
+	processCacheMissMeter = metrics.NewRegisteredMeter("chain/process/cache/misses", nil)
+
+	// Per transaction phases, only measured if expensive metrics are enabled.
+	// State reads are included in the execution and broken down by the state
+	// timers reported by the blockchain.
+	txExecutionTimer = metrics.NewRegisteredTimer("chain/execution/transactions", nil)
+	txFinaliseTimer  = metrics.NewRegisteredTimer("chain/execution/finalise", nil)
+	txReceiptTimer   = metrics.NewRegisteredTimer("chain/execution/receipts", nil)
 )
 
 // StateProcessor is a basic Processor, which takes care of transitioning
@@ -38,6 +58,32 @@ type StateProcessor struct {
 	config *params.ChainConfig // Chain configuration options
 	bc     *BlockChain         // Canonical block chain
 	engine consensus.Engine    // Consensus engine used for block rewards
+
+	// MaxTxPerBlock, if non-zero, is a node policy limiting the number of
+	// transactions executed per block. Blocks exceeding it are rejected with
+	// ErrTooManyTransactions, unless TruncateAtMaxTx is set (e.g. when building
+	// blocks), in which case processing stops at the limit and the result is
+	// flagged as truncated. In the same building mode, processing also stops
+	// at the first transaction exceeding the gas left in the block, which is
+	// reported in the result instead of failing the block.
+	MaxTxPerBlock   int
+	TruncateAtMaxTx bool
+
+	// PrefetchWorkers, if non-zero, is the number of goroutines warming the
+	// state caches for every block processed through Process, as described
+	// by ProcessOptions.Prefetch.
+	PrefetchWorkers int
+
+	results *lru.Cache // Outcome of recently processed blocks, see CacheResults
+}
+
+// processedBlock is the cached outcome of processing a block.
+type processedBlock struct {
+	preRoot  common.Hash    // Root of the state the block was processed on
+	post     *state.StateDB // State after processing the block
+	receipts types.Receipts
+	logs     []*types.Log
+	gasUsed  uint64
 }
 
 // NewStateProcessor initialises a new StateProcessor.
@@ -49,6 +95,107 @@ func NewStateProcessor(config *params.ChainConfig, bc *BlockChain, engine consen
 	}
 }
 
+// CacheResults enables caching the outcome of the last limit blocks processed
+// through Process: their receipts, logs and post-state. Processing one of them
+// again on top of the same pre-state, e.g. when the fork choice flips back to
+// it, restores the cached post-state instead of re-executing the block. The
+// returned receipts and logs are shared and must not be modified.
+//
+// As results are cached by block hash, Process must always be called with an
+// equivalent vm.Config. Traced executions bypass the cache.
+func (p *StateProcessor) CacheResults(limit int) {
+	p.results, _ = lru.New(limit)
+}
+
+// ProcessOptions contains optional knobs altering how a block is processed.
+// The zero value processes a block exactly as required by consensus.
+type ProcessOptions struct {
+	// NewGasPool, if set, creates the gas pool the block's transactions are
+	// charged against instead of the default GasPool. The pool is created
+	// empty and filled with the block gas limit before processing starts.
+	NewGasPool func() GasPooler
+
+	// Checkpoints, if set, records a state checkpoint after each transaction,
+	// allowing debuggers to return to any intermediate state of the block.
+	// They are kept by the state until released with ReleaseCheckpoints.
+	Checkpoints bool
+
+	// Parallelism, if larger than one, executes the block's transactions
+	// speculatively on that many goroutines before committing them in order,
+	// re-executing those conflicting with earlier ones. It is ignored when
+	// tracing or when transition hooks are set, as they would observe the
+	// speculative executions, and when processing leniently, emitting access
+	// lists or checking gas strictly.
+	Parallelism int
+
+	// Lenient, if set, skips the transactions that cannot be applied instead of
+	// aborting the block, reporting them in the result. The state changes of a
+	// skipped transaction are reverted and it has no receipt.
+	Lenient bool
+
+	// AccessLists, if set, records every account and storage slot accessed by
+	// each applied transaction, returning them in the result as deduplicated
+	// access lists, e.g. for block builders and provers.
+	AccessLists bool
+
+	// OnTransaction, if set, is called in order after each transaction has been
+	// applied, with its index, its receipt and the gas used by the block so far,
+	// allowing the results of huge blocks to be streamed. The receipt is also
+	// part of the result and must not be modified.
+	OnTransaction func(index int, receipt *types.Receipt, cumulativeGas uint64)
+
+	// Prefetch, if non-zero, executes the block's transactions on that many
+	// goroutines against throwaway copies of the state while the block is being
+	// processed, purely to pull the state they touch into the caches ahead of
+	// the actual execution. It is ignored when executing in parallel.
+	Prefetch int
+
+	// VerifyReceipts, if set, checks the produced receipts against the header
+	// of the block once processed, failing with a *ReceiptMismatchError that
+	// details every inconsistency found. See VerifyReceipts.
+	VerifyReceipts bool
+
+	// StrictGas, if set, checks the cumulative gas used after each transaction,
+	// failing with a *ReceiptMismatchError at the first divergent one instead of
+	// only on the block total: a transaction pushing the block past the gas used
+	// declared by its header or, if ExpectedReceipts are given (e.g. retrieved
+	// along with the block), one whose cumulative gas differs from its receipt.
+	// Transactions are executed serially in strict mode.
+	StrictGas        bool
+	ExpectedReceipts types.Receipts
+
+	// Finalize, if set, replaces the consensus engine's finalization (e.g. the
+	// block rewards) applied after the transactions. SkipFinalize omits the
+	// finalization entirely. Both are meant for test harnesses and custom
+	// chains, as the resulting state is not valid for the engine.
+	Finalize     func(header *types.Header, statedb *state.StateDB, txs []*types.Transaction, uncles []*types.Header)
+	SkipFinalize bool
+}
+
+// ProcessResult contains the outcome of processing a block.
+type ProcessResult struct {
+	Receipts types.Receipts // Receipts of all transactions in the block
+	Logs     []*types.Log   // Logs accumulated from all receipts
+	GasUsed  uint64         // Total gas used by the block's transactions
+
+	Checkpoints  []int  // State checkpoint ids after each transaction, if requested
+	Truncated    bool   // Whether processing stopped at the transaction limit
+	GasExhausted int    // Index of the first transaction exceeding the block gas left, -1 if none
+	GasRemaining uint64 // Gas left in the block gas pool after processing
+
+	Failures    []TxFailure        // Transactions skipped when processing leniently
+	AccessLists []types.AccessList // Accounts and slots accessed by each receipt's transaction, if requested
+	Sidecars    [][]byte           // Data derived from the receipts by the consensus engine, if supported
+}
+
+// TxFailure describes a transaction that could not be applied when processing
+// a block leniently.
+type TxFailure struct {
+	Index int         // Index of the transaction in the block
+	Hash  common.Hash // Hash of the transaction
+	Err   error       // Reason the transaction could not be applied
+}
+
 // Process processes the state changes according to the Ethereum rules by running
 // the transaction messages using the statedb and applying any rewards to both
 // the processor (coinbase) and any included uncles.
@@ -57,6 +204,227 @@ func NewStateProcessor(config *params.ChainConfig, bc *BlockChain, engine consen
 // returns the amount of gas that was used in the process. If any of the
 // transactions failed to execute due to insufficient gas it will return an error.
 func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg vm.Config) (types.Receipts, []*types.Log, uint64, error) {
+	if p.results == nil || cfg.Debug || hasTxHooks(cfg.Hooks) {
+		return p.process(block, statedb, cfg)
+	}
+	preRoot := statedb.OriginalRoot()
+	if cached, ok := p.results.Get(block.Hash()); ok {
+		if res := cached.(*processedBlock); res.preRoot == preRoot && res.post.Database() == statedb.Database() {
+			processCacheHitMeter.Mark(1)
+			statedb.CopyFrom(res.post)
+			return res.receipts, res.logs, res.gasUsed, nil
+		}
+	}
+	processCacheMissMeter.Mark(1)
+
+	receipts, logs, usedGas, err := p.process(block, statedb, cfg)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	post := statedb.Copy()
+	post.StopPrefetcher()
+	p.results.Add(block.Hash(), &processedBlock{preRoot: preRoot, post: post, receipts: receipts, logs: logs, gasUsed: usedGas})
+	return receipts, logs, usedGas, nil
+}
+
+// process is Process without the result cache.
+func (p *StateProcessor) process(block *types.Block, statedb *state.StateDB, cfg vm.Config) (types.Receipts, []*types.Log, uint64, error) {
+	result, err := p.ProcessWithOptions(block, statedb, cfg, ProcessOptions{Prefetch: p.PrefetchWorkers})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return result.Receipts, result.Logs, result.GasUsed, nil
+}
+
+// ProcessWithOptions is like Process, but allows the caller to customize the
+// processing through the given options.
+func (p *StateProcessor) ProcessWithOptions(block *types.Block, statedb *state.StateDB, cfg vm.Config, opts ProcessOptions) (*ProcessResult, error) {
+	var (
+		receipts     types.Receipts
+		usedGas      = new(uint64)
+		header       = block.Header()
+		blockHash    = block.Hash()
+		blockNumber  = block.Number()
+		allLogs      []*types.Log
+		checkpoints  []int
+		gp           GasPooler
+		txs          = block.Transactions()
+		truncated    bool
+		gasExhausted = -1
+		failures     []TxFailure
+		accessLists  []types.AccessList
+	)
+	if p.MaxTxPerBlock > 0 && len(txs) > p.MaxTxPerBlock {
+		if !p.TruncateAtMaxTx {
+			return nil, fmt.Errorf("%w: have %d, limit %d", ErrTooManyTransactions, len(txs), p.MaxTxPerBlock)
+		}
+		txs, truncated = txs[:p.MaxTxPerBlock], true
+	}
+	// Since Cancun, the blobs of the transactions are charged against a separate
+	// per-block blob gas pool
+	var blobGp *BlobGasPool
+	if p.config.IsCancun(blockNumber) {
+		blobGp = new(BlobGasPool).AddGas(params.MaxBlobGasPerBlock)
+	}
+	if opts.NewGasPool != nil {
+		gp = opts.NewGasPool()
+	} else {
+		gp = new(GasPool)
+	}
+	gp.ReturnGas(block.GasLimit())
+
+	// Mutate the block and state according to any hard-fork specs
+	ApplyIrregularStateChanges(p.config, block.Number(), statedb)
+
+	// Update the system contracts before executing any transaction
+	ApplySystemCalls(p.config, header, NewEVMBlockContext(header, p.bc, nil), statedb, cfg)
+
+	if opts.Parallelism > 1 && !opts.Lenient && !opts.AccessLists && !opts.StrictGas && !cfg.Debug && !hasTxHooks(cfg.Hooks) {
+		var cps *[]int
+		if opts.Checkpoints {
+			cps = &checkpoints
+		}
+		var err error
+		if receipts, allLogs, err = p.applyTransactionsParallel(block, txs, statedb, cfg, gp, blobGp, usedGas, opts.Parallelism, cps, opts.OnTransaction); err != nil {
+			return nil, err
+		}
+	} else {
+		// Warm up the caches on throwaway states while executing serially
+		if opts.Prefetch > 0 {
+			var interrupt uint32
+			newStatePrefetcher(p.config, p.bc, p.engine).PrefetchConcurrent(block, statedb, cfg, opts.Prefetch, &interrupt)
+			defer atomic.StoreUint32(&interrupt, 1)
+		}
+		blockContext := NewEVMBlockContext(header, p.bc, nil)
+		vmenv := vm.NewEVM(blockContext, vm.TxContext{}, statedb, p.config, cfg)
+		// Iterate over and process the individual transactions
+		for i, tx := range txs {
+			msg, err := tx.AsMessage(types.MakeSigner(p.config, header.Number), header.BaseFee)
+			if err == nil && blobGp != nil {
+				err = blobGp.SubGas(tx.BlobGas())
+			}
+			if err != nil {
+				if opts.Lenient {
+					failures = append(failures, TxFailure{Index: i, Hash: tx.Hash(), Err: err})
+					continue
+				}
+				return nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+			}
+			statedb.Prepare(tx.Hash(), i)
+			if opts.AccessLists {
+				statedb.StartTouchRecording()
+			}
+			snap, gas := statedb.Snapshot(), gp.Gas()
+
+			receipt, err := applyTransaction(msg, p.config, p.bc, nil, gp, statedb, blockNumber, blockHash, tx, usedGas, vmenv)
+			if errors.Is(err, ErrGasLimitReached) {
+				if gasExhausted < 0 {
+					gasExhausted = i
+				}
+				if p.TruncateAtMaxTx {
+					break
+				}
+			}
+			if err != nil {
+				if opts.Lenient {
+					// Roll back whatever the transaction did before failing
+					statedb.RevertToSnapshot(snap)
+					gp.ReturnGas(gas - gp.Gas())
+					if blobGp != nil {
+						blobGp.AddGas(tx.BlobGas())
+					}
+					failures = append(failures, TxFailure{Index: i, Hash: tx.Hash(), Err: err})
+					continue
+				}
+				return nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+			}
+			if opts.StrictGas {
+				if err := verifyCumulativeGas(header, opts.ExpectedReceipts, i, *usedGas); err != nil {
+					return nil, err
+				}
+			}
+			receipts = append(receipts, receipt)
+			allLogs = append(allLogs, receipt.Logs...)
+
+			if opts.AccessLists {
+				accessLists = append(accessLists, statedb.TouchedAccessList())
+			}
+			if opts.Checkpoints {
+				checkpoints = append(checkpoints, statedb.Checkpoint())
+			}
+			if opts.OnTransaction != nil {
+				opts.OnTransaction(i, receipt, *usedGas)
+			}
+		}
+		if opts.AccessLists {
+			statedb.StopTouchRecording()
+		}
+	}
+	// In building mode, processing stops early if the block gas is exhausted
+	if processed := len(receipts) + len(failures); processed < len(txs) {
+		txs = txs[:processed]
+		if gasExhausted < 0 {
+			gasExhausted = processed
+		}
+	}
+	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
+	switch {
+	case opts.SkipFinalize:
+	case opts.Finalize != nil:
+		opts.Finalize(header, statedb, txs, block.Uncles())
+	default:
+		p.engine.Finalize(p.bc, header, statedb, txs, block.Uncles(), block.Withdrawals())
+	}
+	if opts.VerifyReceipts {
+		if err := VerifyReceipts(header, receipts); err != nil {
+			return nil, err
+		}
+	}
+	// Let the consensus engine derive any extra outputs from the receipts
+	var sidecars [][]byte
+	if rp, ok := p.engine.(consensus.ReceiptProcessor); ok {
+		var err error
+		if sidecars, err = rp.ProcessReceipts(p.bc, header, receipts); err != nil {
+			return nil, fmt.Errorf("could not process receipts: %w", err)
+		}
+	}
+	return &ProcessResult{
+		Receipts:     receipts,
+		Logs:         allLogs,
+		GasUsed:      *usedGas,
+		Checkpoints:  checkpoints,
+		Truncated:    truncated,
+		GasExhausted: gasExhausted,
+		GasRemaining: gp.Gas(),
+		Failures:     failures,
+		AccessLists:  accessLists,
+		Sidecars:     sidecars,
+	}, nil
+}
+
+// ProcessLenient is like Process, but skips the transactions that cannot be
+// applied instead of aborting the block, returning the failures along with the
+// results of the applied ones. As the outcome does not correspond to a valid
+// block, it is meant for analysis tools, e.g. block explorers replaying
+// partially-invalid blocks of private networks.
+func (p *StateProcessor) ProcessLenient(block *types.Block, statedb *state.StateDB, cfg vm.Config) (*ProcessResult, error) {
+	return p.ProcessWithOptions(block, statedb, cfg, ProcessOptions{Lenient: true})
+}
+
+// ProcessShard applies the given contiguous shard of a block's transactions on
+// top of statedb, which must hold the state after all preceding shards. The
+// startTxIndex and startCumulativeGas parameters position the shard within
+// the block, so that the produced receipts and logs carry block-wide
+// transaction indices and cumulative gas, and the shard only has access to the
+// block gas not yet consumed.
+//
+// Block level state changes (irregular state changes, system calls and consensus
+// engine finalization) are not applied, they are the responsibility of the
+// caller.
+func (p *StateProcessor) ProcessShard(block *types.Block, txs types.Transactions, statedb *state.StateDB, cfg vm.Config, startTxIndex int, startCumulativeGas uint64) (types.Receipts, []*types.Log, uint64, error) {
+	if startCumulativeGas > block.GasLimit() {
+		return nil, nil, 0, fmt.Errorf("%w: have %d, want at most %d", ErrGasLimitReached, startCumulativeGas, block.GasLimit())
+	}
 	var (
 		receipts    types.Receipts
 		usedGas     = new(uint64)
@@ -64,44 +432,124 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		blockHash   = block.Hash()
 		blockNumber = block.Number()
 		allLogs     []*types.Log
-		gp          = new(GasPool).AddGas(block.GasLimit())
+		gp          = new(GasPool).AddGas(block.GasLimit() - startCumulativeGas)
 	)
-	// Mutate the block and state according to any hard-fork specs
-	if p.config.DAOForkSupport && p.config.DAOForkBlock != nil && p.config.DAOForkBlock.Cmp(block.Number()) == 0 {
-		misc.ApplyDAOHardFork(statedb)
-	}
+	*usedGas = startCumulativeGas
+
 	blockContext := NewEVMBlockContext(header, p.bc, nil)
 	vmenv := vm.NewEVM(blockContext, vm.TxContext{}, statedb, p.config, cfg)
-	// Iterate over and process the individual transactions
-	for i, tx := range block.Transactions() {
+	for i, tx := range txs {
+		index := startTxIndex + i
 		msg, err := tx.AsMessage(types.MakeSigner(p.config, header.Number), header.BaseFee)
 		if err != nil {
-			return nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+			return nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", index, tx.Hash().Hex(), err)
 		}
-		statedb.Prepare(tx.Hash(), i)
+		statedb.Prepare(tx.Hash(), index)
 		receipt, err := applyTransaction(msg, p.config, p.bc, nil, gp, statedb, blockNumber, blockHash, tx, usedGas, vmenv)
 		if err != nil {
-			return nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+			return nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", index, tx.Hash().Hex(), err)
 		}
 		receipts = append(receipts, receipt)
 		allLogs = append(allLogs, receipt.Logs...)
 	}
-	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
-	p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles())
-
 	return receipts, allLogs, *usedGas, nil
 }
 
-func applyTransaction(msg types.Message, config *params.ChainConfig, bc ChainContext, author *common.Address, gp *GasPool, statedb *state.StateDB, blockNumber *big.Int, blockHash common.Hash, tx *types.Transaction, usedGas *uint64, evm *vm.EVM) (*types.Receipt, error) {
+// ValidateGasOnly executes the transactions of a block on a copy of the given
+// state and checks that the gas they use matches the gas used declared in the
+// header, leaving the state untouched. It is meant for dry-run validity checks
+// where the resulting state is of no interest: the consensus engine does not
+// finalize the block, but the transactions are still fully executed, receipts
+// included, so it costs about as much as processing the block.
+func (p *StateProcessor) ValidateGasOnly(block *types.Block, statedb *state.StateDB, cfg vm.Config) error {
+	result, err := p.ProcessWithOptions(block, statedb.Copy(), cfg, ProcessOptions{SkipFinalize: true})
+	if err != nil {
+		return err
+	}
+	if block.GasUsed() != result.GasUsed {
+		return fmt.Errorf("invalid gas used (remote: %d local: %d)", block.GasUsed(), result.GasUsed)
+	}
+	return nil
+}
+
+// ProcessTwiceAndCompare processes the block twice on independent copies of
+// the given state and verifies that both runs produce identical receipts, gas
+// usage and state roots. It is a development safeguard to surface sources of
+// non-determinism (e.g. map iteration in custom precompiles); the passed state
+// itself is left untouched.
+func (p *StateProcessor) ProcessTwiceAndCompare(block *types.Block, statedb *state.StateDB, cfg vm.Config) error {
+	var (
+		roots    [2]common.Hash
+		receipts [2]common.Hash
+		gas      [2]uint64
+	)
+	for i := 0; i < 2; i++ {
+		db := statedb.Copy()
+		result, err := p.ProcessWithOptions(block, db, cfg, ProcessOptions{})
+		if err != nil {
+			return fmt.Errorf("run %d: %w", i, err)
+		}
+		roots[i] = db.IntermediateRoot(p.config.IsEIP158(block.Number()))
+		receipts[i] = types.DeriveSha(result.Receipts, trie.NewStackTrie(nil))
+		gas[i] = result.GasUsed
+	}
+	if gas[0] != gas[1] {
+		return fmt.Errorf("non-deterministic gas usage: %d != %d", gas[0], gas[1])
+	}
+	if receipts[0] != receipts[1] {
+		return fmt.Errorf("non-deterministic receipts: %x != %x", receipts[0], receipts[1])
+	}
+	if roots[0] != roots[1] {
+		return fmt.Errorf("non-deterministic state root: %x != %x", roots[0], roots[1])
+	}
+	return nil
+}
+
+// ProcessWithWitness is like Process, but additionally records the part of the
+// pre-state the block accesses and returns it as an RLP encoded state.Witness,
+// allowing stateless clients to execute the block.
+func (p *StateProcessor) ProcessWithWitness(block *types.Block, statedb *state.StateDB, cfg vm.Config) (types.Receipts, []*types.Log, uint64, []byte, error) {
+	statedb.StartWitnessRecording()
+	defer statedb.StopWitnessRecording()
+
+	// Bypass the result cache, the block needs executing to record its accesses
+	receipts, logs, usedGas, err := p.process(block, statedb, cfg)
+	if err != nil {
+		return nil, nil, 0, nil, err
+	}
+	witness, err := statedb.Witness()
+	if err != nil {
+		return nil, nil, 0, nil, fmt.Errorf("could not create witness: %w", err)
+	}
+	enc, err := rlp.EncodeToBytes(witness)
+	if err != nil {
+		return nil, nil, 0, nil, err
+	}
+	return receipts, logs, usedGas, enc, nil
+}
+
+func applyTransaction(msg types.Message, config *params.ChainConfig, bc ChainContext, author *common.Address, gp GasPooler, statedb *state.StateDB, blockNumber *big.Int, blockHash common.Hash, tx *types.Transaction, usedGas *uint64, evm *vm.EVM) (*types.Receipt, error) {
 	// Create a new context to be used in the EVM environment.
 	txContext := NewEVMTxContext(msg)
 	evm.Reset(txContext, statedb)
 
 	// Apply the transaction to the current state (included in the env).
+	start := time.Now()
 	result, err := ApplyMessage(evm, msg, gp)
 	if err != nil {
 		return nil, err
 	}
+	// A call refused by the node's denylist (only ever configured by the miner)
+	// makes the execution differ from that on other nodes, so the transaction
+	// must be left out of the block
+	if evm.CallDenied() {
+		gp.ReturnGas(result.UsedGas)
+		return nil, fmt.Errorf("%w: internal call", ErrRecipientDenied)
+	}
+	if metrics.EnabledExpensive {
+		txExecutionTimer.UpdateSince(start)
+		start = time.Now()
+	}
 
 	// Update the state with pending changes.
 	var root []byte
@@ -112,6 +560,11 @@ func applyTransaction(msg types.Message, config *params.ChainConfig, bc ChainCon
 	}
 	*usedGas += result.UsedGas
 
+	if metrics.EnabledExpensive {
+		txFinaliseTimer.UpdateSince(start)
+		defer txReceiptTimer.UpdateSince(time.Now())
+	}
+
 	// Create a new receipt for the transaction, storing the intermediate root and gas used
 	// by the tx.
 	receipt := &types.Receipt{Type: tx.Type(), PostState: root, CumulativeGasUsed: *usedGas}
@@ -141,7 +594,7 @@ func applyTransaction(msg types.Message, config *params.ChainConfig, bc ChainCon
 // and uses the input parameters for its environment. It returns the receipt
 // for the transaction, gas used and an error if the transaction failed,
 // indicating the block was invalid.
-func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *common.Address, gp *GasPool, statedb *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64, cfg vm.Config) (*types.Receipt, error) {
+func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *common.Address, gp GasPooler, statedb *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64, cfg vm.Config) (*types.Receipt, error) {
 	msg, err := tx.AsMessage(types.MakeSigner(config, header.Number), header.BaseFee)
 	if err != nil {
 		return nil, err
@@ -151,3 +604,98 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *commo
 	vmenv := vm.NewEVM(blockContext, vm.TxContext{}, statedb, config, cfg)
 	return applyTransaction(msg, config, bc, author, gp, statedb, header.Number, header.Hash(), tx, usedGas, vmenv)
 }
+
+// ApplyTransactionWithEVM attempts to apply a transaction to the given state
+// database using a caller supplied EVM, e.g. one with a tracer attached. The
+// EVM's transaction context is reset for the message, so the same instance may
+// be reused for all transactions of a block, but its block context must match
+// the given block number and hash.
+func ApplyTransactionWithEVM(msg types.Message, config *params.ChainConfig, gp GasPooler, statedb *state.StateDB, blockNumber *big.Int, blockHash common.Hash, tx *types.Transaction, usedGas *uint64, evm *vm.EVM) (*types.Receipt, error) {
+	return applyTransaction(msg, config, nil, nil, gp, statedb, blockNumber, blockHash, tx, usedGas, evm)
+}
+
+// ApplyIrregularStateChanges applies the irregular state changes scheduled at the
+// start of the given block: the DAO hard-fork, if supported, followed by any
+// registered in the chain config.
+func ApplyIrregularStateChanges(config *params.ChainConfig, number *big.Int, statedb *state.StateDB) {
+	if config.DAOForkSupport && config.DAOForkBlock != nil && config.DAOForkBlock.Cmp(number) == 0 {
+		misc.ApplyDAOHardFork(statedb)
+	}
+	if number.IsUint64() {
+		if change := config.IrregularStateChanges[number.Uint64()]; change != nil {
+			change(statedb)
+		}
+	}
+}
+
+// LogMismatchError is returned by VerifyLogs if the logs produced by replaying a
+// transaction differ from the expected ones.
+type LogMismatchError struct {
+	Index int         // Position of the first mismatching log
+	Field string      // Mismatching field: "count", "address", "topics" or "data"
+	Have  interface{} // Value produced by the replay
+	Want  interface{} // Value expected by the caller
+}
+
+func (e *LogMismatchError) Error() string {
+	if e.Field == "count" {
+		return fmt.Sprintf("log count mismatch: have %v, want %v", e.Have, e.Want)
+	}
+	return fmt.Sprintf("log %d %s mismatch: have %v, want %v", e.Index, e.Field, e.Have, e.Want)
+}
+
+// VerifyLogs replays a transaction on top of a copy of the given state and
+// checks that the logs it produces match the expected ones, e.g. taken from a
+// receipt of untrusted origin. The state itself is not modified. A mismatch is
+// reported as a *LogMismatchError, while failing to replay the transaction is
+// returned as is.
+func VerifyLogs(config *params.ChainConfig, bc ChainContext, statedb *state.StateDB, header *types.Header, tx *types.Transaction, expected []*types.Log) error {
+	statedb = statedb.Copy()
+	statedb.Prepare(tx.Hash(), 0)
+
+	receipt, err := ApplyTransaction(config, bc, nil, new(GasPool).AddGas(header.GasLimit), statedb, header, tx, new(uint64), vm.Config{})
+	if err != nil {
+		return err
+	}
+	for i, have := range receipt.Logs {
+		if i >= len(expected) {
+			break
+		}
+		want := expected[i]
+		if have.Address != want.Address {
+			return &LogMismatchError{Index: i, Field: "address", Have: have.Address, Want: want.Address}
+		}
+		if !equalTopics(have.Topics, want.Topics) {
+			return &LogMismatchError{Index: i, Field: "topics", Have: have.Topics, Want: want.Topics}
+		}
+		if !bytes.Equal(have.Data, want.Data) {
+			return &LogMismatchError{Index: i, Field: "data", Have: have.Data, Want: want.Data}
+		}
+	}
+	if have, want := len(receipt.Logs), len(expected); have != want {
+		index := have
+		if want < have {
+			index = want
+		}
+		return &LogMismatchError{Index: index, Field: "count", Have: have, Want: want}
+	}
+	return nil
+}
+
+// equalTopics reports whether two topic lists are identical.
+func equalTopics(a, b []common.Hash) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// hasTxHooks reports whether any of the transition hooks is set.
+func hasTxHooks(hooks vm.TxHooks) bool {
+	return hooks.OnTxStart != nil || hooks.OnTxEnd != nil || hooks.OnGasConsumed != nil || hooks.OnBalanceTransfer != nil
+}
@@ -0,0 +1,231 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// speculativeTx is the outcome of executing a transaction against a private
+// copy of the block's pre-state.
+type speculativeTx struct {
+	statedb *state.StateDB              // Private state the transaction was executed on
+	msg     types.Message               // Message derived from the transaction
+	receipt *types.Receipt              // Receipt with block-local fields to be corrected
+	reads   map[common.Address]struct{} // Accounts read before paying the coinbase
+	err     error                       // Consensus error encountered, if any (the transaction is re-executed)
+}
+
+// conflicts reports whether the transaction read any of the given accounts.
+func (tx *speculativeTx) conflicts(written map[common.Address]struct{}) bool {
+	for addr := range tx.reads {
+		if _, ok := written[addr]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// applyTransactionsParallel applies the given transactions of the block to
+// statedb with the same outcome as applying them one by one, but first executes
+// all of them concurrently on copies of the pre-state, recording the accounts
+// each read.
+// The results are then committed in order; transactions that read an account
+// written by an earlier one, or that failed, are re-executed on the up-to-date
+// state instead, so only errors of the sequential execution abort processing.
+// If set, blobGp is charged with the blobs of each transaction before it is
+// committed, and onTx is called after each transaction was committed.
+//
+// Every transaction credits the coinbase, so reading it only counts as a
+// conflict if it happens before the fee payment, i.e. during execution.
+func (p *StateProcessor) applyTransactionsParallel(block *types.Block, txs types.Transactions, statedb *state.StateDB, cfg vm.Config, gp GasPooler, blobGp *BlobGasPool, usedGas *uint64, workers int, checkpoints *[]int, onTx func(int, *types.Receipt, uint64)) (types.Receipts, []*types.Log, error) {
+	var (
+		header      = block.Header()
+		blockHash   = block.Hash()
+		blockNumber = block.Number()
+		signer      = types.MakeSigner(p.config, header.Number)
+		coinbase    = NewEVMBlockContext(header, p.bc, nil).Coinbase
+		specs       = make([]*speculativeTx, len(txs))
+	)
+	// Execute all transactions speculatively on their own copy of the state
+	for i := range txs {
+		specs[i] = &speculativeTx{statedb: statedb.Copy()}
+		specs[i].statedb.StartAccessRecording()
+	}
+	var (
+		pend  sync.WaitGroup
+		tasks = make(chan int)
+	)
+	for w := 0; w < workers; w++ {
+		pend.Add(1)
+		go func() {
+			defer pend.Done()
+			for i := range tasks {
+				p.speculateTransaction(block, txs[i], i, signer, cfg, specs[i])
+			}
+		}()
+	}
+	for i := range txs {
+		tasks <- i
+	}
+	close(tasks)
+	pend.Wait()
+
+	// Commit the transactions in order, re-executing any conflicting ones
+	var (
+		receipts     types.Receipts
+		allLogs      []*types.Log
+		baseCoinbase = statedb.GetBalance(coinbase)
+		vmenv        = vm.NewEVM(NewEVMBlockContext(header, p.bc, nil), vm.TxContext{}, statedb, p.config, cfg)
+	)
+	statedb.StartAccessRecording()
+	defer statedb.StopAccessRecording()
+
+	for i, tx := range txs {
+		var (
+			spec    = specs[i]
+			receipt *types.Receipt
+		)
+		if blobGp != nil {
+			if err := blobGp.SubGas(tx.BlobGas()); err != nil {
+				return nil, nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+			}
+		}
+		statedb.Prepare(tx.Hash(), i)
+		if spec.err != nil || spec.conflicts(statedb.WrittenAccounts()) {
+			msg, err := tx.AsMessage(signer, header.BaseFee)
+			if err != nil {
+				return nil, nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+			}
+			receipt, err = applyTransaction(msg, p.config, p.bc, nil, gp, statedb, blockNumber, blockHash, tx, usedGas, vmenv)
+			if p.TruncateAtMaxTx && errors.Is(err, ErrGasLimitReached) {
+				break
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+			}
+		} else {
+			err := p.commitSpeculative(spec, statedb, gp, coinbase, baseCoinbase)
+			if p.TruncateAtMaxTx && errors.Is(err, ErrGasLimitReached) {
+				break
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+			}
+			// Finalise the state and fix up the block-wide receipt fields
+			var root []byte
+			if p.config.IsByzantium(blockNumber) {
+				statedb.Finalise(true)
+			} else {
+				root = statedb.IntermediateRoot(p.config.IsEIP158(blockNumber)).Bytes()
+			}
+			*usedGas += spec.receipt.GasUsed
+
+			receipt = spec.receipt
+			receipt.PostState = root
+			receipt.CumulativeGasUsed = *usedGas
+			receipt.Logs = statedb.GetLogs(tx.Hash(), blockHash)
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		}
+		receipts = append(receipts, receipt)
+		allLogs = append(allLogs, receipt.Logs...)
+
+		if checkpoints != nil {
+			*checkpoints = append(*checkpoints, statedb.Checkpoint())
+		}
+		if onTx != nil {
+			onTx(i, receipt, *usedGas)
+		}
+	}
+	return receipts, allLogs, nil
+}
+
+// speculateTransaction executes a transaction on the private state of spec,
+// with a private gas pool, recording the outcome and the accounts read.
+func (p *StateProcessor) speculateTransaction(block *types.Block, tx *types.Transaction, index int, signer types.Signer, cfg vm.Config, spec *speculativeTx) {
+	header := block.Header()
+
+	msg, err := tx.AsMessage(signer, header.BaseFee)
+	if err != nil {
+		spec.err = err
+		return
+	}
+	spec.msg = msg
+
+	// Capture the accounts read right before the coinbase is paid
+	hook := cfg.FeeHook
+	cfg.FeeHook = func(effectivePrice, gasUsed *big.Int) *big.Int {
+		spec.reads = spec.statedb.AccessedAccounts()
+		if hook != nil {
+			return hook(effectivePrice, gasUsed)
+		}
+		return new(big.Int).Mul(effectivePrice, gasUsed)
+	}
+	var (
+		gp      = new(GasPool).AddGas(block.GasLimit())
+		usedGas = new(uint64)
+		vmenv   = vm.NewEVM(NewEVMBlockContext(header, p.bc, nil), vm.TxContext{}, spec.statedb, p.config, cfg)
+	)
+	spec.statedb.Prepare(tx.Hash(), index)
+	spec.receipt, spec.err = applyTransaction(msg, p.config, p.bc, nil, gp, spec.statedb, block.Number(), block.Hash(), tx, usedGas, vmenv)
+	if spec.reads == nil {
+		spec.reads = spec.statedb.AccessedAccounts()
+	}
+}
+
+// commitSpeculative merges the effects of a non-conflicting speculatively
+// executed transaction into statedb, charging the block gas pool and crediting
+// the coinbase with the fee on top of the fees of earlier transactions.
+func (p *StateProcessor) commitSpeculative(spec *speculativeTx, statedb *state.StateDB, gp GasPooler, coinbase common.Address, baseCoinbase *big.Int) error {
+	if err := gp.SubGas(spec.msg.Gas()); err != nil {
+		return err
+	}
+	gp.ReturnGas(spec.msg.Gas() - spec.receipt.GasUsed)
+
+	// If the coinbase was accessed during execution (only possible without
+	// conflict if no earlier transaction paid it anything), its state can be
+	// merged as is, otherwise only the fee is credited.
+	var (
+		written = spec.statedb.WrittenAccounts()
+		addrs   = make([]common.Address, 0, len(written))
+	)
+	for addr := range written {
+		if _, read := spec.reads[addr]; addr != coinbase || read {
+			addrs = append(addrs, addr)
+		}
+	}
+	statedb.MergeAccounts(spec.statedb, addrs)
+	if _, read := spec.reads[coinbase]; !read {
+		statedb.AddBalance(coinbase, new(big.Int).Sub(spec.statedb.GetBalance(coinbase), baseCoinbase))
+	}
+	for _, log := range spec.receipt.Logs {
+		cpy := *log
+		statedb.AddLog(&cpy)
+	}
+	for hash, preimage := range spec.statedb.Preimages() {
+		statedb.AddPreimage(hash, preimage)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// errInvalidRange is returned by ProcessRange if asked to replay an empty range
+// or the genesis block, which has no transactions to replay.
+var errInvalidRange = errors.New("invalid block range")
+
+// RangeOptions customizes the replay of a range of blocks by ProcessRange.
+type RangeOptions struct {
+	// ProcessOptions are applied to every block of the range.
+	ProcessOptions
+
+	// Config is the EVM configuration every block is processed with.
+	Config vm.Config
+
+	// Tracer, if set, creates a fresh tracer for each block of the range, which
+	// is handed to OnBlock along with the results of the block.
+	Tracer func(block *types.Block) vm.EVMLogger
+
+	// OnBlock, if set, is called in order with the results of each replayed
+	// block and its tracer, if any. Returning an error aborts the replay. The
+	// results are not retained once OnBlock returns, so memory usage does not
+	// grow with the length of the range.
+	OnBlock func(block *types.Block, result *ProcessResult, tracer vm.EVMLogger) error
+}
+
+// ProcessRange replays the canonical blocks from and to, inclusive, each on top
+// of the historical post-state of its parent, streaming the results to
+// opts.OnBlock. The states are loaded from the database block by block and
+// dropped once processed, so the parent of every block of the range must be
+// available (i.e. on an archive node, or within the recent blocks).
+//
+// The replay stops between two blocks when ctx is cancelled, returning the
+// context's error.
+func (p *StateProcessor) ProcessRange(ctx context.Context, from, to uint64, opts RangeOptions) error {
+	if from == 0 || from > to {
+		return fmt.Errorf("%w: #%d-#%d", errInvalidRange, from, to)
+	}
+	for number := from; ; number++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		block := p.bc.GetBlockByNumber(number)
+		if block == nil {
+			return fmt.Errorf("canonical block #%d not found", number)
+		}
+		parent := p.bc.GetHeader(block.ParentHash(), number-1)
+		if parent == nil {
+			return fmt.Errorf("parent of block #%d not found", number)
+		}
+		statedb, err := p.bc.StateAt(parent.Root)
+		if err != nil {
+			return fmt.Errorf("state of block #%d unavailable: %w", number-1, err)
+		}
+		var (
+			cfg    = opts.Config
+			tracer vm.EVMLogger
+		)
+		if opts.Tracer != nil {
+			tracer = opts.Tracer(block)
+			cfg.Debug, cfg.Tracer = true, tracer
+		}
+		result, err := p.ProcessWithOptions(block, statedb, cfg, opts.ProcessOptions)
+		if err != nil {
+			return fmt.Errorf("failed to process block #%d: %w", number, err)
+		}
+		if opts.OnBlock != nil {
+			if err := opts.OnBlock(block, result, tracer); err != nil {
+				return err
+			}
+		}
+		if number == to {
+			return nil
+		}
+	}
+}
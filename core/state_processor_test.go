@@ -17,7 +17,10 @@
 package core
 
 import (
+	"bytes"
+	"context"
 	"crypto/ecdsa"
+	"errors"
 	"math/big"
 	"testing"
 
@@ -27,10 +30,12 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
 	"golang.org/x/crypto/sha3"
 )
@@ -299,6 +304,1105 @@ func TestStateProcessorErrors(t *testing.T) {
 	}
 }
 
+// newProcessTestChain creates a blockchain funding the test key with 1 ether,
+// along with any additional accounts in alloc, and a single block on top of
+// its genesis populated by gen.
+func newProcessTestChain(t *testing.T, config *params.ChainConfig, alloc GenesisAlloc, gen func(*BlockGen)) (*BlockChain, *types.Block) {
+	gspec := &Genesis{
+		Config: config,
+		Alloc: GenesisAlloc{
+			transitionTestSender: {Balance: big.NewInt(params.Ether)},
+		},
+	}
+	for addr, account := range alloc {
+		gspec.Alloc[addr] = account
+	}
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		genesis = gspec.MustCommit(db)
+	)
+	blockchain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 1, func(i int, b *BlockGen) {
+		gen(b)
+	})
+	return blockchain, blocks[0]
+}
+
+// TestProcessCheckpoints tests that state checkpoints can be recorded after each
+// transaction of a block, and that the state can be reverted to them.
+func TestProcessCheckpoints(t *testing.T) {
+	var (
+		config    = params.TestChainConfig
+		signer    = types.LatestSigner(config)
+		key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		recipient = common.HexToAddress("0x2222")
+	)
+	blockchain, block := newProcessTestChain(t, config, nil, func(b *BlockGen) {
+		for i := 0; i < 3; i++ {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(transitionTestSender), recipient, big.NewInt(int64(i+1)), params.TxGas, b.BaseFee(), nil), signer, key)
+			b.AddTx(tx)
+		}
+	})
+	defer blockchain.Stop()
+
+	statedb, err := blockchain.State()
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	result, err := blockchain.Processor().(*StateProcessor).ProcessWithOptions(block, statedb, vm.Config{}, ProcessOptions{Checkpoints: true})
+	if err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	if len(result.Checkpoints) != 3 {
+		t.Fatalf("checkpoint count mismatch: have %d, want %d", len(result.Checkpoints), 3)
+	}
+	seen := make(map[int]bool)
+	for _, id := range result.Checkpoints {
+		if seen[id] {
+			t.Fatalf("duplicate checkpoint id %d", id)
+		}
+		seen[id] = true
+	}
+	if have, want := statedb.GetBalance(recipient), big.NewInt(6); have.Cmp(want) != 0 {
+		t.Fatalf("final balance mismatch: have %v, want %v", have, want)
+	}
+	statedb.RevertToCheckpoint(result.Checkpoints[0])
+	if have, want := statedb.GetBalance(recipient), big.NewInt(1); have.Cmp(want) != 0 {
+		t.Errorf("balance after revert mismatch: have %v, want %v", have, want)
+	}
+	if have, want := statedb.GetNonce(transitionTestSender), uint64(1); have != want {
+		t.Errorf("nonce after revert mismatch: have %d, want %d", have, want)
+	}
+}
+
+// TestProcessTwiceAndCompare tests that processing a deterministic block twice
+// yields identical results. A custom precompile whose output depends on, say,
+// map iteration order would yield differing return data and state between the
+// two runs, which is reported as a receipt or state root mismatch.
+func TestProcessTwiceAndCompare(t *testing.T) {
+	var (
+		config = params.TestChainConfig
+		signer = types.LatestSigner(config)
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	)
+	blockchain, block := newProcessTestChain(t, config, nil, func(b *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(0, common.HexToAddress("0x2222"), big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+	})
+	defer blockchain.Stop()
+
+	statedb, err := blockchain.State()
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	root := statedb.IntermediateRoot(true)
+	if err := blockchain.Processor().(*StateProcessor).ProcessTwiceAndCompare(block, statedb, vm.Config{}); err != nil {
+		t.Fatalf("deterministic block reported as non-deterministic: %v", err)
+	}
+	if have := statedb.IntermediateRoot(true); have != root {
+		t.Errorf("original state modified: have %x, want %x", have, root)
+	}
+}
+
+// TestProcessShard tests that processing a block in shards yields receipts with
+// the same block-wide indices and cumulative gas as processing it at once.
+func TestProcessShard(t *testing.T) {
+	var (
+		config = params.TestChainConfig
+		signer = types.LatestSigner(config)
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	)
+	blockchain, block := newProcessTestChain(t, config, nil, func(b *BlockGen) {
+		for i := 0; i < 3; i++ {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(transitionTestSender), common.HexToAddress("0x2222"), big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key)
+			b.AddTx(tx)
+		}
+	})
+	defer blockchain.Stop()
+
+	processor := blockchain.Processor().(*StateProcessor)
+	statedb, err := blockchain.State()
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	// Process the block in two shards, the second continuing from the first
+	txs := block.Transactions()
+	first, _, gas, err := processor.ProcessShard(block, txs[:2], statedb, vm.Config{}, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to process first shard: %v", err)
+	}
+	second, _, gas, err := processor.ProcessShard(block, txs[2:], statedb, vm.Config{}, len(first), gas)
+	if err != nil {
+		t.Fatalf("failed to process second shard: %v", err)
+	}
+	receipts := append(first, second...)
+
+	// Process the block at once and compare the receipt metadata
+	statedb, _ = blockchain.State()
+	want, _, wantGas, err := processor.Process(block, statedb, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	if gas != wantGas {
+		t.Errorf("gas used mismatch: have %d, want %d", gas, wantGas)
+	}
+	if len(receipts) != len(want) {
+		t.Fatalf("receipt count mismatch: have %d, want %d", len(receipts), len(want))
+	}
+	for i, receipt := range receipts {
+		if receipt.TransactionIndex != uint(i) {
+			t.Errorf("receipt %d: transaction index mismatch: have %d, want %d", i, receipt.TransactionIndex, i)
+		}
+		if receipt.CumulativeGasUsed != want[i].CumulativeGasUsed {
+			t.Errorf("receipt %d: cumulative gas mismatch: have %d, want %d", i, receipt.CumulativeGasUsed, want[i].CumulativeGasUsed)
+		}
+	}
+}
+
+// TestProcessParallel tests that executing the transactions of a block in
+// parallel yields the same state and receipts as executing them serially, both
+// for independent transactions and for ones depending on earlier ones.
+func TestProcessParallel(t *testing.T) {
+	var (
+		config    = params.TestChainConfig
+		signer    = types.LatestSigner(config)
+		key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		other, _  = crypto.GenerateKey()
+		funded, _ = crypto.GenerateKey()
+	)
+	alloc := GenesisAlloc{
+		crypto.PubkeyToAddress(other.PublicKey): {Balance: big.NewInt(params.Ether)},
+	}
+	blockchain, block := newProcessTestChain(t, config, alloc, func(b *BlockGen) {
+		// Fund an empty account, have an unrelated account transfer in
+		// between, then spend from the freshly funded account.
+		tx, _ := types.SignTx(types.NewTransaction(0, crypto.PubkeyToAddress(funded.PublicKey), big.NewInt(params.Ether/10), params.TxGas, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+		tx, _ = types.SignTx(types.NewTransaction(0, common.HexToAddress("0x2222"), big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, other)
+		b.AddTx(tx)
+		tx, _ = types.SignTx(types.NewTransaction(0, common.HexToAddress("0x3333"), big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, funded)
+		b.AddTx(tx)
+	})
+	defer blockchain.Stop()
+
+	processor := blockchain.Processor().(*StateProcessor)
+	statedb, err := blockchain.State()
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	want, _, wantGas, err := processor.Process(block, statedb, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to process block serially: %v", err)
+	}
+	statedb, _ = blockchain.State()
+	result, err := processor.ProcessWithOptions(block, statedb, vm.Config{}, ProcessOptions{Parallelism: 4})
+	if err != nil {
+		t.Fatalf("failed to process block in parallel: %v", err)
+	}
+	if root := statedb.IntermediateRoot(config.IsEIP158(block.Number())); root != block.Root() {
+		t.Errorf("state root mismatch: have %x, want %x", root, block.Root())
+	}
+	if result.GasUsed != wantGas {
+		t.Errorf("gas used mismatch: have %d, want %d", result.GasUsed, wantGas)
+	}
+	if len(result.Receipts) != len(want) {
+		t.Fatalf("receipt count mismatch: have %d, want %d", len(result.Receipts), len(want))
+	}
+	for i, receipt := range result.Receipts {
+		if receipt.Status != want[i].Status {
+			t.Errorf("receipt %d: status mismatch: have %d, want %d", i, receipt.Status, want[i].Status)
+		}
+		if receipt.CumulativeGasUsed != want[i].CumulativeGasUsed {
+			t.Errorf("receipt %d: cumulative gas mismatch: have %d, want %d", i, receipt.CumulativeGasUsed, want[i].CumulativeGasUsed)
+		}
+		if receipt.TransactionIndex != uint(i) {
+			t.Errorf("receipt %d: transaction index mismatch: have %d, want %d", i, receipt.TransactionIndex, i)
+		}
+	}
+	if types.DeriveSha(result.Receipts, trie.NewStackTrie(nil)) != block.ReceiptHash() {
+		t.Errorf("receipt root mismatch")
+	}
+}
+
+// TestProcessPrefetch tests that warming the caches on throwaway copies of the
+// state leaves the outcome of processing a block untouched.
+func TestProcessPrefetch(t *testing.T) {
+	var (
+		config = params.TestChainConfig
+		signer = types.LatestSigner(config)
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	)
+	blockchain, block := newProcessTestChain(t, config, nil, func(b *BlockGen) {
+		for i := 0; i < 4; i++ {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(transitionTestSender), common.HexToAddress("0x2222"), big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key)
+			b.AddTx(tx)
+		}
+	})
+	defer blockchain.Stop()
+
+	statedb, err := blockchain.State()
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	result, err := blockchain.Processor().(*StateProcessor).ProcessWithOptions(block, statedb, vm.Config{}, ProcessOptions{Prefetch: 2})
+	if err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	if root := statedb.IntermediateRoot(config.IsEIP158(block.Number())); root != block.Root() {
+		t.Errorf("state root mismatch: have %x, want %x", root, block.Root())
+	}
+	if types.DeriveSha(result.Receipts, trie.NewStackTrie(nil)) != block.ReceiptHash() {
+		t.Errorf("receipt root mismatch")
+	}
+}
+
+// TestProcessWithWitness tests that a block can be executed statelessly, on top
+// of nothing but the execution witness recorded while processing it.
+func TestProcessWithWitness(t *testing.T) {
+	var (
+		config   = params.TestChainConfig
+		signer   = types.LatestSigner(config)
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		contract = common.HexToAddress("0x3333")
+	)
+	alloc := GenesisAlloc{
+		// Increment the counter in slot 0
+		contract: {
+			Code:    []byte{byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.PUSH1), 1, byte(vm.ADD), byte(vm.PUSH1), 0, byte(vm.SSTORE)},
+			Storage: map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(1))},
+			Balance: new(big.Int),
+		},
+		common.HexToAddress("0x5555"): {Balance: big.NewInt(1)},
+	}
+	blockchain, block := newProcessTestChain(t, config, alloc, func(b *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(transitionTestSender), contract, new(big.Int), 100000, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+		tx, _ = types.SignTx(types.NewTransaction(b.TxNonce(transitionTestSender), common.HexToAddress("0x4444"), big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+	})
+	defer blockchain.Stop()
+
+	processor := blockchain.Processor().(*StateProcessor)
+	statedb, err := blockchain.State()
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	_, _, _, enc, err := processor.ProcessWithWitness(block, statedb, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	var witness state.Witness
+	if err := rlp.DecodeBytes(enc, &witness); err != nil {
+		t.Fatalf("failed to decode witness: %v", err)
+	}
+	if len(witness.Codes) != 1 {
+		t.Fatalf("witness code count mismatch: have %d, want 1", len(witness.Codes))
+	}
+	// Execute the block again with nothing but the witness at hand
+	db := rawdb.NewMemoryDatabase()
+	for _, node := range witness.Nodes {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	for _, code := range witness.Codes {
+		rawdb.WriteCode(db, crypto.Keccak256Hash(code), code)
+	}
+	stateless, err := state.New(witness.Root, state.NewDatabase(db), nil)
+	if err != nil {
+		t.Fatalf("failed to open witness state: %v", err)
+	}
+	if _, _, _, err := processor.Process(block, stateless, vm.Config{}); err != nil {
+		t.Fatalf("failed to process block statelessly: %v", err)
+	}
+	if err := stateless.Error(); err != nil {
+		t.Fatalf("witness incomplete: %v", err)
+	}
+	if root := stateless.IntermediateRoot(config.IsEIP158(block.Number())); root != block.Root() {
+		t.Errorf("stateless state root mismatch: have %x, want %x", root, block.Root())
+	}
+}
+
+// TestProcessOnTransaction tests that the results of a block's transactions are
+// streamed in order, whether executed serially or in parallel.
+func TestProcessOnTransaction(t *testing.T) {
+	var (
+		config = params.TestChainConfig
+		signer = types.LatestSigner(config)
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	)
+	blockchain, block := newProcessTestChain(t, config, nil, func(b *BlockGen) {
+		for i := 0; i < 3; i++ {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(transitionTestSender), common.HexToAddress("0x2222"), big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key)
+			b.AddTx(tx)
+		}
+	})
+	defer blockchain.Stop()
+
+	processor := blockchain.Processor().(*StateProcessor)
+	for _, parallelism := range []int{0, 4} {
+		statedb, err := blockchain.State()
+		if err != nil {
+			t.Fatalf("failed to retrieve state: %v", err)
+		}
+		var streamed types.Receipts
+		onTx := func(index int, receipt *types.Receipt, cumulativeGas uint64) {
+			if index != len(streamed) {
+				t.Errorf("parallelism %d: index mismatch: have %d, want %d", parallelism, index, len(streamed))
+			}
+			if cumulativeGas != uint64(index+1)*params.TxGas {
+				t.Errorf("parallelism %d: tx %d: cumulative gas mismatch: have %d, want %d", parallelism, index, cumulativeGas, uint64(index+1)*params.TxGas)
+			}
+			streamed = append(streamed, receipt)
+		}
+		result, err := processor.ProcessWithOptions(block, statedb, vm.Config{}, ProcessOptions{Parallelism: parallelism, OnTransaction: onTx})
+		if err != nil {
+			t.Fatalf("parallelism %d: failed to process block: %v", parallelism, err)
+		}
+		if len(streamed) != len(result.Receipts) {
+			t.Fatalf("parallelism %d: streamed receipt count mismatch: have %d, want %d", parallelism, len(streamed), len(result.Receipts))
+		}
+		for i, receipt := range streamed {
+			if receipt != result.Receipts[i] {
+				t.Errorf("parallelism %d: streamed receipt %d mismatch", parallelism, i)
+			}
+		}
+	}
+}
+
+// TestSystemCalls tests that the system contracts are updated before the
+// transactions of a block, without consuming block gas or producing receipts.
+func TestSystemCalls(t *testing.T) {
+	var (
+		config = *params.TestChainConfig
+		signer = types.LatestSigner(&config)
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		// Stores the first word of the calldata at the slot of the block number
+		code = common.FromHex("6000354355")
+		root = common.HexToHash("0xbeac")
+	)
+	config.CancunBlock, config.PragueBlock = big.NewInt(0), big.NewInt(0)
+
+	alloc := GenesisAlloc{
+		params.BeaconRootsAddress:    {Code: code, Balance: common.Big0},
+		params.HistoryStorageAddress: {Code: code, Balance: common.Big0},
+	}
+	blockchain, block := newProcessTestChain(t, &config, alloc, func(b *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(transitionTestSender), common.HexToAddress("0x2222"), big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+	})
+	defer blockchain.Stop()
+
+	header := block.Header()
+	header.ParentBeaconRoot = &root
+	block = types.NewBlockWithHeader(header).WithBody(block.Transactions(), nil)
+
+	statedb, err := blockchain.State()
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	receipts, _, gasUsed, err := blockchain.Processor().Process(block, statedb, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	if len(receipts) != 1 {
+		t.Errorf("receipt count mismatch: have %d, want 1", len(receipts))
+	}
+	if gasUsed != params.TxGas {
+		t.Errorf("gas used mismatch: have %d, want %d", gasUsed, params.TxGas)
+	}
+	slot := common.BigToHash(block.Number())
+	if have := statedb.GetState(params.BeaconRootsAddress, slot); have != root {
+		t.Errorf("beacon root mismatch: have %x, want %x", have, root)
+	}
+	if have := statedb.GetState(params.HistoryStorageAddress, slot); have != block.ParentHash() {
+		t.Errorf("parent hash mismatch: have %x, want %x", have, block.ParentHash())
+	}
+	if statedb.Exist(params.SystemAddress) {
+		t.Errorf("system address left in the state")
+	}
+}
+
+// TestApplyTransactionWithEVM tests that a single EVM can be shared by all the
+// transactions of a block, yielding the same receipts as regular processing.
+func TestApplyTransactionWithEVM(t *testing.T) {
+	var (
+		config    = params.TestChainConfig
+		signer    = types.LatestSigner(config)
+		key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		recipient = common.HexToAddress("0x2222")
+	)
+	blockchain, block := newProcessTestChain(t, config, nil, func(b *BlockGen) {
+		for i := 0; i < 2; i++ {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(transitionTestSender), recipient, big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key)
+			b.AddTx(tx)
+		}
+	})
+	defer blockchain.Stop()
+
+	statedb, err := blockchain.State()
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	want, _, _, err := blockchain.Processor().Process(block, statedb.Copy(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	var (
+		header  = block.Header()
+		gp      = new(GasPool).AddGas(block.GasLimit())
+		usedGas = new(uint64)
+		evm     = vm.NewEVM(NewEVMBlockContext(header, blockchain, nil), vm.TxContext{}, statedb, config, vm.Config{})
+	)
+	for i, tx := range block.Transactions() {
+		msg, err := tx.AsMessage(types.MakeSigner(config, header.Number), header.BaseFee)
+		if err != nil {
+			t.Fatalf("tx %d: failed to derive message: %v", i, err)
+		}
+		statedb.Prepare(tx.Hash(), i)
+		receipt, err := ApplyTransactionWithEVM(msg, config, gp, statedb, block.Number(), block.Hash(), tx, usedGas, evm)
+		if err != nil {
+			t.Fatalf("tx %d: failed to apply: %v", i, err)
+		}
+		if evm.TxContext.Origin != transitionTestSender {
+			t.Errorf("tx %d: origin mismatch: have %x, want %x", i, evm.TxContext.Origin, transitionTestSender)
+		}
+		if receipt.CumulativeGasUsed != want[i].CumulativeGasUsed {
+			t.Errorf("tx %d: cumulative gas mismatch: have %d, want %d", i, receipt.CumulativeGasUsed, want[i].CumulativeGasUsed)
+		}
+		if receipt.Status != want[i].Status {
+			t.Errorf("tx %d: status mismatch: have %d, want %d", i, receipt.Status, want[i].Status)
+		}
+	}
+}
+
+// sidecarEngine is a consensus engine deriving a sidecar from the address of
+// every log emitted in a block.
+type sidecarEngine struct {
+	consensus.Engine
+}
+
+func (e *sidecarEngine) ProcessReceipts(chain consensus.ChainHeaderReader, header *types.Header, receipts []*types.Receipt) ([][]byte, error) {
+	var sidecars [][]byte
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			sidecars = append(sidecars, log.Address.Bytes())
+		}
+	}
+	return sidecars, nil
+}
+
+// TestProcessReceiptsHook tests that consensus engines implementing the
+// ReceiptProcessor interface can attach data derived from the logs to the
+// processing result.
+func TestProcessReceiptsHook(t *testing.T) {
+	var (
+		config = params.TestChainConfig
+		signer = types.LatestSigner(config)
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		// Emits an empty log without topics
+		code    = common.FromHex("60006000a0")
+		emitter = common.HexToAddress("0x6110")
+	)
+	alloc := GenesisAlloc{emitter: {Code: code, Balance: common.Big0}}
+	blockchain, block := newProcessTestChain(t, config, alloc, func(b *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(transitionTestSender), emitter, big.NewInt(0), 50000, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+	})
+	defer blockchain.Stop()
+
+	statedb, err := blockchain.State()
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	processor := NewStateProcessor(config, blockchain, &sidecarEngine{ethash.NewFaker()})
+	result, err := processor.ProcessWithOptions(block, statedb, vm.Config{}, ProcessOptions{})
+	if err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	if len(result.Sidecars) != 1 {
+		t.Fatalf("sidecar count mismatch: have %d, want 1", len(result.Sidecars))
+	}
+	if have := common.BytesToAddress(result.Sidecars[0]); have != emitter {
+		t.Errorf("sidecar mismatch: have %x, want %x", have, emitter)
+	}
+}
+
+// TestProcessRange tests that a range of canonical blocks can be replayed on
+// top of their historical states, and that the replay can be cancelled.
+func TestProcessRange(t *testing.T) {
+	var (
+		config    = params.TestChainConfig
+		signer    = types.LatestSigner(config)
+		key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		recipient = common.HexToAddress("0x2222")
+		gspec     = &Genesis{
+			Config: config,
+			Alloc:  GenesisAlloc{transitionTestSender: {Balance: big.NewInt(params.Ether)}},
+		}
+		db      = rawdb.NewMemoryDatabase()
+		genesis = gspec.MustCommit(db)
+	)
+	blockchain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 3, func(i int, b *BlockGen) {
+		for j := 0; j <= i; j++ {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(transitionTestSender), recipient, big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key)
+			b.AddTx(tx)
+		}
+	})
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	processor := blockchain.Processor().(*StateProcessor)
+
+	var replayed []uint64
+	err = processor.ProcessRange(context.Background(), 2, 3, RangeOptions{
+		OnBlock: func(block *types.Block, result *ProcessResult, tracer vm.EVMLogger) error {
+			if len(result.Receipts) != len(block.Transactions()) {
+				t.Errorf("block #%d: receipt count mismatch: have %d, want %d", block.NumberU64(), len(result.Receipts), len(block.Transactions()))
+			}
+			if result.GasUsed != block.GasUsed() {
+				t.Errorf("block #%d: gas used mismatch: have %d, want %d", block.NumberU64(), result.GasUsed, block.GasUsed())
+			}
+			replayed = append(replayed, block.NumberU64())
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to replay range: %v", err)
+	}
+	if len(replayed) != 2 || replayed[0] != 2 || replayed[1] != 3 {
+		t.Errorf("replayed blocks mismatch: have %v, want [2 3]", replayed)
+	}
+	if err := processor.ProcessRange(context.Background(), 3, 2, RangeOptions{}); !errors.Is(err, errInvalidRange) {
+		t.Errorf("reversed range error mismatch: have %v, want %v", err, errInvalidRange)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := processor.ProcessRange(ctx, 1, 3, RangeOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled replay error mismatch: have %v, want %v", err, context.Canceled)
+	}
+}
+
+// TestProcessStrictGas tests that strict gas checking pinpoints the first
+// transaction diverging from the header or from the expected receipts.
+func TestProcessStrictGas(t *testing.T) {
+	var (
+		config    = params.TestChainConfig
+		signer    = types.LatestSigner(config)
+		key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		recipient = common.HexToAddress("0x2222")
+	)
+	blockchain, block := newProcessTestChain(t, config, nil, func(b *BlockGen) {
+		for i := 0; i < 3; i++ {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(transitionTestSender), recipient, big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key)
+			b.AddTx(tx)
+		}
+	})
+	defer blockchain.Stop()
+
+	processor := blockchain.Processor().(*StateProcessor)
+	process := func(block *types.Block, expected types.Receipts) error {
+		statedb, err := blockchain.State()
+		if err != nil {
+			t.Fatalf("failed to retrieve state: %v", err)
+		}
+		_, err = processor.ProcessWithOptions(block, statedb, vm.Config{}, ProcessOptions{StrictGas: true, ExpectedReceipts: expected})
+		return err
+	}
+	expected := make(types.Receipts, 3)
+	for i := range expected {
+		expected[i] = &types.Receipt{CumulativeGasUsed: uint64(i+1) * params.TxGas}
+	}
+	if err := process(block, expected); err != nil {
+		t.Fatalf("failed to process valid block: %v", err)
+	}
+	check := func(err error, index int, field string) {
+		t.Helper()
+		var mismatch *ReceiptMismatchError
+		if !errors.As(err, &mismatch) {
+			t.Fatalf("error mismatch: have %v, want *ReceiptMismatchError", err)
+		}
+		if m := mismatch.Mismatches[0]; m.Index != index || m.Field != field {
+			t.Errorf("mismatch: have tx %d %s, want tx %d %s", m.Index, m.Field, index, field)
+		}
+	}
+	// Diverge from the expected receipts at the second transaction
+	expected[1] = &types.Receipt{CumulativeGasUsed: 3 * params.TxGas}
+	check(process(block, expected), 1, "cumulativeGasUsed")
+
+	// Declare less gas in the header than used by the first two transactions
+	header := block.Header()
+	header.GasUsed = params.TxGas + 1
+	check(process(types.NewBlockWithHeader(header).WithBody(block.Transactions(), nil), nil), 1, "gasUsed")
+}
+
+// TestProcessLenient tests that lenient processing skips the transactions that
+// cannot be applied, reporting them and continuing with the rest of the block.
+func TestProcessLenient(t *testing.T) {
+	var (
+		config    = params.TestChainConfig
+		signer    = types.LatestSigner(config)
+		key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		recipient = common.HexToAddress("0x2222")
+	)
+	blockchain, block := newProcessTestChain(t, config, nil, func(b *BlockGen) {
+		for i := 0; i < 2; i++ {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(transitionTestSender), recipient, big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key)
+			b.AddTx(tx)
+		}
+	})
+	defer blockchain.Stop()
+
+	// Insert a transaction with a nonce gap between the valid ones
+	invalid, _ := types.SignTx(types.NewTransaction(5, recipient, big.NewInt(1), params.TxGas, block.BaseFee(), nil), signer, key)
+	txs := types.Transactions{block.Transactions()[0], invalid, block.Transactions()[1]}
+	block = types.NewBlockWithHeader(block.Header()).WithBody(txs, nil)
+
+	processor := blockchain.Processor().(*StateProcessor)
+	statedb, err := blockchain.State()
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	if _, _, _, err := processor.Process(block, statedb.Copy(), vm.Config{}); !errors.Is(err, ErrNonceTooHigh) {
+		t.Fatalf("strict processing error mismatch: have %v, want %v", err, ErrNonceTooHigh)
+	}
+	result, err := processor.ProcessLenient(block, statedb, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to process block leniently: %v", err)
+	}
+	if len(result.Failures) != 1 {
+		t.Fatalf("failure count mismatch: have %d, want 1", len(result.Failures))
+	}
+	if failure := result.Failures[0]; failure.Index != 1 || failure.Hash != invalid.Hash() || !errors.Is(failure.Err, ErrNonceTooHigh) {
+		t.Errorf("failure mismatch: have %d %x %v", failure.Index, failure.Hash, failure.Err)
+	}
+	if len(result.Receipts) != 2 {
+		t.Fatalf("receipt count mismatch: have %d, want 2", len(result.Receipts))
+	}
+	if have := result.Receipts[1].TransactionIndex; have != 2 {
+		t.Errorf("receipt index mismatch: have %d, want 2", have)
+	}
+	if result.GasUsed != 2*params.TxGas {
+		t.Errorf("gas used mismatch: have %d, want %d", result.GasUsed, 2*params.TxGas)
+	}
+	if result.GasExhausted != -1 {
+		t.Errorf("unexpected gas exhaustion at tx %d", result.GasExhausted)
+	}
+	if have := statedb.GetBalance(recipient); have.Cmp(big.NewInt(2)) != 0 {
+		t.Errorf("recipient balance mismatch: have %v, want 2", have)
+	}
+}
+
+// TestProcessAccessLists tests that the accounts and storage slots accessed by
+// each transaction of a block can be emitted as access lists.
+func TestProcessAccessLists(t *testing.T) {
+	var (
+		config    = params.TestChainConfig
+		signer    = types.LatestSigner(config)
+		key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		contract  = common.HexToAddress("0xc0de")
+		recipient = common.HexToAddress("0x2222")
+		// PUSH1 1 PUSH1 1 SSTORE
+		alloc = GenesisAlloc{contract: {Code: common.FromHex("0x6001600155"), Balance: new(big.Int)}}
+	)
+	blockchain, block := newProcessTestChain(t, config, alloc, func(b *BlockGen) {
+		for _, to := range []common.Address{contract, recipient} {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(transitionTestSender), to, big.NewInt(1), 100000, b.BaseFee(), nil), signer, key)
+			b.AddTx(tx)
+		}
+	})
+	defer blockchain.Stop()
+
+	statedb, err := blockchain.State()
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	// Access lists force serial execution, ensure that's honoured too
+	result, err := blockchain.Processor().(*StateProcessor).ProcessWithOptions(block, statedb, vm.Config{}, ProcessOptions{AccessLists: true, Parallelism: 4})
+	if err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	if len(result.AccessLists) != 2 {
+		t.Fatalf("access list count mismatch: have %d, want 2", len(result.AccessLists))
+	}
+	slots := func(list types.AccessList, addr common.Address) []common.Hash {
+		for _, tuple := range list {
+			if tuple.Address == addr {
+				return tuple.StorageKeys
+			}
+		}
+		return nil
+	}
+	for i, list := range result.AccessLists {
+		for _, addr := range []common.Address{transitionTestSender, block.Coinbase()} {
+			if slots(list, addr) == nil {
+				t.Errorf("tx %d: account %x missing", i, addr)
+			}
+		}
+	}
+	if keys := slots(result.AccessLists[0], contract); len(keys) != 1 || keys[0] != common.BigToHash(common.Big1) {
+		t.Errorf("tx 0: contract slots mismatch: have %v", keys)
+	}
+	if slots(result.AccessLists[0], recipient) != nil {
+		t.Errorf("tx 0: untouched recipient present")
+	}
+	if slots(result.AccessLists[1], recipient) == nil {
+		t.Errorf("tx 1: recipient missing")
+	}
+	if slots(result.AccessLists[1], contract) != nil {
+		t.Errorf("tx 1: untouched contract present")
+	}
+}
+
+// TestIrregularStateChanges tests that the irregular state changes registered in
+// the chain config are applied at the start of their block.
+func TestIrregularStateChanges(t *testing.T) {
+	var (
+		config   = *params.TestChainConfig
+		signer   = types.LatestSigner(&config)
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		contract = common.HexToAddress("0xc0de")
+		// PUSH1 1 PUSH1 1 SSTORE
+		code = common.FromHex("0x6001600155")
+	)
+	config.IrregularStateChanges = map[uint64]params.IrregularStateChange{
+		1: func(statedb params.IrregularStateDB) {
+			statedb.SetCode(contract, code)
+		},
+	}
+	blockchain, block := newProcessTestChain(t, &config, nil, func(b *BlockGen) {
+		// Call the contract deployed by the irregular state change
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(transitionTestSender), contract, new(big.Int), 100000, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+	})
+	defer blockchain.Stop()
+
+	statedb, err := blockchain.State()
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	if _, _, _, err := blockchain.Processor().Process(block, statedb, vm.Config{}); err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	if !bytes.Equal(statedb.GetCode(contract), code) {
+		t.Errorf("contract code mismatch: have %x, want %x", statedb.GetCode(contract), code)
+	}
+	if have := statedb.GetState(contract, common.BigToHash(common.Big1)); have != common.BigToHash(common.Big1) {
+		t.Errorf("contract storage mismatch: have %x, want 1", have)
+	}
+	if root := statedb.IntermediateRoot(true); root != block.Root() {
+		t.Errorf("state root mismatch with generated block: have %x, want %x", root, block.Root())
+	}
+}
+
+// TestProcessResultCache tests that processing a block again on top of the same
+// pre-state restores the cached outcome instead of re-executing it.
+func TestProcessResultCache(t *testing.T) {
+	var (
+		config = params.TestChainConfig
+		signer = types.LatestSigner(config)
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	)
+	blockchain, block := newProcessTestChain(t, config, nil, func(b *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(transitionTestSender), common.HexToAddress("0x2222"), big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+	})
+	defer blockchain.Stop()
+
+	processor := NewStateProcessor(config, blockchain, blockchain.Engine())
+	processor.CacheResults(1)
+
+	process := func() (*state.StateDB, types.Receipts) {
+		statedb, err := blockchain.State()
+		if err != nil {
+			t.Fatalf("failed to retrieve state: %v", err)
+		}
+		receipts, _, _, err := processor.Process(block, statedb, vm.Config{})
+		if err != nil {
+			t.Fatalf("failed to process block: %v", err)
+		}
+		return statedb, receipts
+	}
+	first, want := process()
+	second, have := process()
+	if have[0] != want[0] {
+		t.Errorf("block re-executed instead of served from the cache")
+	}
+	if root := second.IntermediateRoot(true); root != first.IntermediateRoot(true) || root != block.Root() {
+		t.Errorf("cached state root mismatch: have %x, want %x", root, block.Root())
+	}
+	// Processing on top of a different pre-state must not hit the cache
+	statedb, err := state.New(block.Root(), blockchain.StateCache(), nil)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	if _, _, _, err := processor.Process(block, statedb, vm.Config{}); err == nil {
+		t.Errorf("block processed on top of the wrong pre-state")
+	}
+}
+
+// TestValidateGasOnly tests that the declared gas used of a block is checked by
+// a dry-run execution that does not modify the state.
+func TestValidateGasOnly(t *testing.T) {
+	var (
+		config = params.TestChainConfig
+		signer = types.LatestSigner(config)
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	)
+	blockchain, block := newProcessTestChain(t, config, nil, func(b *BlockGen) {
+		for i := 0; i < 2; i++ {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(transitionTestSender), common.HexToAddress("0x2222"), big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key)
+			b.AddTx(tx)
+		}
+	})
+	defer blockchain.Stop()
+
+	processor := blockchain.Processor().(*StateProcessor)
+	statedb, err := blockchain.State()
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	root := statedb.IntermediateRoot(true)
+	if err := processor.ValidateGasOnly(block, statedb, vm.Config{}); err != nil {
+		t.Fatalf("failed to validate correct block: %v", err)
+	}
+	if have := statedb.IntermediateRoot(true); have != root {
+		t.Fatalf("state modified: root %x, want %x", have, root)
+	}
+	header := block.Header()
+	header.GasUsed++
+	if err := processor.ValidateGasOnly(block.WithSeal(header), statedb, vm.Config{}); err == nil {
+		t.Fatalf("block with invalid gas used validated")
+	}
+}
+
+// TestMaxTxPerBlock tests that blocks with more transactions than the limit
+// are rejected in strict mode and truncated at the limit otherwise.
+func TestMaxTxPerBlock(t *testing.T) {
+	var (
+		config = params.TestChainConfig
+		signer = types.LatestSigner(config)
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	)
+	blockchain, block := newProcessTestChain(t, config, nil, func(b *BlockGen) {
+		for i := 0; i < 3; i++ {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(transitionTestSender), common.HexToAddress("0x2222"), big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key)
+			b.AddTx(tx)
+		}
+	})
+	defer blockchain.Stop()
+
+	processor := NewStateProcessor(config, blockchain, blockchain.Engine())
+	processor.MaxTxPerBlock = 2
+
+	statedb, _ := blockchain.State()
+	if _, err := processor.ProcessWithOptions(block, statedb, vm.Config{}, ProcessOptions{}); !errors.Is(err, ErrTooManyTransactions) {
+		t.Fatalf("strict processing error mismatch: have %v, want %v", err, ErrTooManyTransactions)
+	}
+	processor.TruncateAtMaxTx = true
+
+	statedb, _ = blockchain.State()
+	result, err := processor.ProcessWithOptions(block, statedb, vm.Config{}, ProcessOptions{})
+	if err != nil {
+		t.Fatalf("failed to process truncated block: %v", err)
+	}
+	if !result.Truncated {
+		t.Errorf("result not flagged as truncated")
+	}
+	if len(result.Receipts) != 2 {
+		t.Errorf("receipt count mismatch: have %d, want 2", len(result.Receipts))
+	}
+	if nonce := statedb.GetNonce(transitionTestSender); nonce != 2 {
+		t.Errorf("sender nonce mismatch: have %d, want 2", nonce)
+	}
+}
+
+// TestGasExhaustion tests that when building blocks, processing stops at the
+// first transaction exceeding the gas left in the block and reports its index
+// along with the remaining gas.
+func TestGasExhaustion(t *testing.T) {
+	var (
+		config = params.TestChainConfig
+		signer = types.LatestSigner(config)
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	)
+	blockchain, block := newProcessTestChain(t, config, nil, func(b *BlockGen) {
+		for _, gas := range []uint64{21000, 30000, 40000, 50000} {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(transitionTestSender), common.HexToAddress("0x2222"), big.NewInt(1), gas, b.BaseFee(), nil), signer, key)
+			b.AddTx(tx)
+		}
+	})
+	defer blockchain.Stop()
+
+	// Leave 23000 gas after the first three transfers, too little for the fourth
+	header := block.Header()
+	header.GasLimit = 3*params.TxGas + 23000
+	block = block.WithSeal(header)
+
+	processor := NewStateProcessor(config, blockchain, blockchain.Engine())
+	statedb, _ := blockchain.State()
+	if _, err := processor.ProcessWithOptions(block, statedb, vm.Config{}, ProcessOptions{}); !errors.Is(err, ErrGasLimitReached) {
+		t.Fatalf("strict processing error mismatch: have %v, want %v", err, ErrGasLimitReached)
+	}
+	// Lenient processing skips the transaction, but still reports the exhaustion
+	statedb, _ = blockchain.State()
+	result, err := processor.ProcessWithOptions(block, statedb, vm.Config{}, ProcessOptions{Lenient: true})
+	if err != nil {
+		t.Fatalf("failed to process block leniently: %v", err)
+	}
+	if result.GasExhausted != 3 {
+		t.Errorf("lenient exhaustion index mismatch: have %d, want 3", result.GasExhausted)
+	}
+	if len(result.Failures) != 1 || !errors.Is(result.Failures[0].Err, ErrGasLimitReached) {
+		t.Errorf("lenient failures mismatch: have %v", result.Failures)
+	}
+	processor.TruncateAtMaxTx = true
+
+	for _, parallelism := range []int{0, 4} {
+		statedb, _ = blockchain.State()
+		result, err := processor.ProcessWithOptions(block, statedb, vm.Config{}, ProcessOptions{Parallelism: parallelism})
+		if err != nil {
+			t.Fatalf("parallelism %d: failed to process block: %v", parallelism, err)
+		}
+		if result.GasExhausted != 3 {
+			t.Errorf("parallelism %d: exhaustion index mismatch: have %d, want 3", parallelism, result.GasExhausted)
+		}
+		if result.GasRemaining != 23000 {
+			t.Errorf("parallelism %d: remaining gas mismatch: have %d, want 23000", parallelism, result.GasRemaining)
+		}
+		if len(result.Receipts) != 3 {
+			t.Errorf("parallelism %d: receipt count mismatch: have %d, want 3", parallelism, len(result.Receipts))
+		}
+		if nonce := statedb.GetNonce(transitionTestSender); nonce != 3 {
+			t.Errorf("parallelism %d: sender nonce mismatch: have %d, want 3", parallelism, nonce)
+		}
+	}
+}
+
+// TestBlobGasLimit tests that blocks whose transactions carry more blobs than
+// the per-block blob gas allows are rejected.
+func TestBlobGasLimit(t *testing.T) {
+	var (
+		config = *params.TestChainConfig
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		hashes = []common.Hash{{params.BlobTxHashVersion}, {params.BlobTxHashVersion}}
+	)
+	config.CancunBlock = big.NewInt(0)
+	signer := types.LatestSigner(&config)
+
+	// Four transactions with two blobs each exceed the six blobs allowed
+	blockchain, block := newProcessTestChain(t, &config, nil, func(b *BlockGen) {
+		for i := 0; i < 4; i++ {
+			b.AddTx(types.MustSignNewTx(key, signer, &types.BlobTx{
+				ChainID:    config.ChainID,
+				Nonce:      b.TxNonce(transitionTestSender),
+				GasTipCap:  new(big.Int),
+				GasFeeCap:  b.BaseFee(),
+				Gas:        params.TxGas,
+				To:         common.HexToAddress("0x2222"),
+				Value:      new(big.Int),
+				BlobFeeCap: big.NewInt(1),
+				BlobHashes: hashes,
+			}))
+		}
+	})
+	defer blockchain.Stop()
+
+	statedb, _ := blockchain.State()
+	if _, _, _, err := blockchain.Processor().Process(block, statedb, vm.Config{}); !errors.Is(err, ErrBlobGasLimitReached) {
+		t.Fatalf("processing error mismatch: have %v, want %v", err, ErrBlobGasLimitReached)
+	}
+	// Processing leniently only skips the transaction exceeding the blob gas
+	statedb, _ = blockchain.State()
+	result, err := blockchain.Processor().(*StateProcessor).ProcessLenient(block, statedb, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to process block leniently: %v", err)
+	}
+	if len(result.Failures) != 1 || result.Failures[0].Index != 3 || !errors.Is(result.Failures[0].Err, ErrBlobGasLimitReached) {
+		t.Errorf("lenient failures mismatch: have %v, want tx 3 failing with %v", result.Failures, ErrBlobGasLimitReached)
+	}
+}
+
+// TestProcessFinalize tests that the consensus engine's finalization can be
+// replaced or skipped when processing a block.
+func TestProcessFinalize(t *testing.T) {
+	blockchain, block := newProcessTestChain(t, params.TestChainConfig, nil, func(b *BlockGen) {})
+	defer blockchain.Stop()
+
+	var (
+		processor = blockchain.Processor().(*StateProcessor)
+		coinbase  = block.Coinbase()
+		custom    = big.NewInt(42)
+	)
+	for i, tt := range []struct {
+		opts ProcessOptions
+		want *big.Int
+	}{
+		{ProcessOptions{}, ethash.ConstantinopleBlockReward},
+		{ProcessOptions{SkipFinalize: true}, new(big.Int)},
+		{ProcessOptions{Finalize: func(header *types.Header, statedb *state.StateDB, txs []*types.Transaction, uncles []*types.Header) {
+			statedb.AddBalance(header.Coinbase, custom)
+		}}, custom},
+	} {
+		statedb, _ := blockchain.State()
+		if _, err := processor.ProcessWithOptions(block, statedb, vm.Config{}, tt.opts); err != nil {
+			t.Fatalf("test %d: failed to process block: %v", i, err)
+		}
+		if balance := statedb.GetBalance(coinbase); balance.Cmp(tt.want) != 0 {
+			t.Errorf("test %d: coinbase balance mismatch: have %v, want %v", i, balance, tt.want)
+		}
+	}
+}
+
+// TestVerifyLogs tests that replaying a transaction accepts the logs it emits
+// and reports tampered ones.
+func TestVerifyLogs(t *testing.T) {
+	var (
+		config   = params.TestChainConfig
+		signer   = types.LatestSigner(config)
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		contract = common.HexToAddress("0xc0de")
+		// PUSH1 0xaa PUSH1 32 PUSH1 0 LOG1 STOP
+		alloc = GenesisAlloc{contract: {Code: common.FromHex("0x60aa60206000a100"), Balance: new(big.Int)}}
+		tx    *types.Transaction
+	)
+	blockchain, block := newProcessTestChain(t, config, alloc, func(b *BlockGen) {
+		tx, _ = types.SignTx(types.NewTransaction(b.TxNonce(transitionTestSender), contract, new(big.Int), 100000, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+	})
+	defer blockchain.Stop()
+
+	statedb, err := blockchain.State()
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	logs := []*types.Log{{
+		Address: contract,
+		Topics:  []common.Hash{common.BytesToHash([]byte{0xaa})},
+		Data:    make([]byte, 32),
+	}}
+	if err := VerifyLogs(config, blockchain, statedb, block.Header(), tx, logs); err != nil {
+		t.Fatalf("failed to verify matching logs: %v", err)
+	}
+	logs[0].Topics = []common.Hash{common.BytesToHash([]byte{0xbb})}
+
+	err = VerifyLogs(config, blockchain, statedb, block.Header(), tx, logs)
+	var mismatch *LogMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("tampered logs error mismatch: have %v, want log mismatch", err)
+	}
+	if mismatch.Index != 0 || mismatch.Field != "topics" {
+		t.Errorf("mismatch location: have log %d %s, want log 0 topics", mismatch.Index, mismatch.Field)
+	}
+}
+
 // GenerateBadBlock constructs a "block" which contains the transactions. The transactions are not expected to be
 // valid, and no proper post-state can be made. But from the perspective of the blockchain, the block is sufficiently
 // valid to be considered for import:
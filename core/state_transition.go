@@ -17,6 +17,7 @@
 package core
 
 import (
+	"bytes"
 	"fmt"
 	"math"
 	"math/big"
@@ -49,7 +50,7 @@ The state transitioning model does all the necessary work to work out a valid ne
 6) Derive new state root
 */
 type StateTransition struct {
-	gp         *GasPool
+	gp         GasPooler
 	msg        Message
 	gas        uint64
 	gasPrice   *big.Int
@@ -60,6 +61,24 @@ type StateTransition struct {
 	data       []byte
 	state      vm.StateDB
 	evm        *vm.EVM
+
+	// noFeeCheck, if set for a fake message, runs it without requiring the
+	// sender to afford the gas and without charging or paying any fees.
+	noFeeCheck bool
+
+	// refundQuotient, if non-zero, overrides the fork's refund cap quotient.
+	refundQuotient uint64
+
+	// gasCap, if non-zero, is the maximum gas a single message may allocate.
+	gasCap uint64
+
+	// floorDataGas is the minimum gas the message pays for its data (EIP-7623),
+	// tracked apart from the running gas counter. It is zero before Prague.
+	floorDataGas uint64
+
+	// operatorFee is the fee charged by the configured vm.OperatorFee along with
+	// the gas, credited to its vault once the message was executed.
+	operatorFee *big.Int
 }
 
 // Message represents a message sent to a contract.
@@ -77,6 +96,19 @@ type Message interface {
 	IsFake() bool
 	Data() []byte
 	AccessList() types.AccessList
+
+	BlobGasFeeCap() *big.Int
+	BlobHashes() []common.Hash
+
+	SetCodeAuthorizations() []types.SetCodeAuthorization
+
+	// GasPayer returns the account buying the gas of the message and receiving
+	// the refund of the unused part, or nil if the sender pays for its own gas.
+	GasPayer() *common.Address
+
+	// Size returns the encoded size of the transaction the message originates
+	// from, or zero if it was not derived from a transaction.
+	Size() uint64
 }
 
 // ExecutionResult includes all output after executing given evm
@@ -85,6 +117,31 @@ type ExecutionResult struct {
 	UsedGas    uint64 // Total used gas but include the refunded gas
 	Err        error  // Any error encountered during the execution(listed in core/vm/errors.go)
 	ReturnData []byte // Returned data from evm(function result or data supplied with revert opcode)
+
+	// The following statistics are only filled in if vm.Config.DetailedResults
+	// is set.
+	StateModified bool   // Whether the execution changed any balance, nonce, code or storage (gas accounting excluded)
+	DeployedCode  []byte // Runtime code deployed by a successful contract creation
+
+	ClearedAccounts []common.Address // Empty accounts touched by the message, deleted by EIP-161 state clearing
+
+	BlockHashOutOfRange bool // Whether BLOCKHASH was queried for a block outside the recent window, yielding zero
+
+	CreateCount  int // Number of CREATE operations executed, including nested ones
+	Create2Count int // Number of CREATE2 operations executed, including nested ones
+	StipendCalls int // Number of value transferring calls granted the call stipend
+
+	CalldataGas uint64 // Portion of the intrinsic gas charged for the transaction data
+	EncodedSize int    // Encoded size of the transaction the message originates from, zero if none
+	BlobGasUsed uint64 // Data availability gas of the blobs carried by a blob transaction
+
+	// The following are only filled in if the corresponding diagnostics are
+	// enabled in vm.Config.
+	CaughtReverts  [][]byte        // Revert data of internal calls reverted within a successful execution, if recorded
+	BalanceChanges []BalanceChange // Every balance mutation in order, if recorded
+	Deterministic  bool            // Whether a second run of the execution matched the first, if checked
+
+	OpcodeProfile *vm.OpcodeProfile // Executions of every opcode and the time spent on them, if profiled
 }
 
 // Unwrap returns the internal evm error which allows us for further
@@ -115,15 +172,77 @@ func (result *ExecutionResult) Revert() []byte {
 }
 
 // IntrinsicGas computes the 'intrinsic gas' for a message with the given data.
-func IntrinsicGas(data []byte, accessList types.AccessList, isContractCreation bool, isHomestead, isEIP2028 bool) (uint64, error) {
+func IntrinsicGas(data []byte, accessList types.AccessList, authList []types.SetCodeAuthorization, isContractCreation bool, isHomestead, isEIP2028, isEIP3860 bool) (uint64, error) {
+	return IntrinsicGasWithTable(protocolGasTable(isHomestead, isEIP2028), data, accessList, authList, isContractCreation, isEIP3860)
+}
+
+// IntrinsicGasWithTable computes the 'intrinsic gas' for a message with the
+// given data, using the prices of the given table, as resolved for a block by
+// ChainConfig.IntrinsicGasTable. All the prices of the table must be set.
+func IntrinsicGasWithTable(table params.GasTable, data []byte, accessList types.AccessList, authList []types.SetCodeAuthorization, isContractCreation bool, isEIP3860 bool) (uint64, error) {
 	// Set the starting gas for the raw transaction
 	var gas uint64
-	if isContractCreation && isHomestead {
-		gas = params.TxGasContractCreation
+	if isContractCreation {
+		gas = table.TxGasContractCreation
 	} else {
-		gas = params.TxGas
+		gas = table.TxGas
 	}
 	// Bump the required gas by the amount of transactional data
+	dataGas, err := calldataGas(data, table)
+	if err != nil {
+		return 0, err
+	}
+	if math.MaxUint64-gas < dataGas {
+		return 0, ErrGasUintOverflow
+	}
+	gas += dataGas
+
+	// Contract creations also pay for every word of init code since EIP-3860
+	if isContractCreation && isEIP3860 {
+		words := (uint64(len(data)) + 31) / 32
+		if (math.MaxUint64-gas)/params.InitCodeWordGas < words {
+			return 0, ErrGasUintOverflow
+		}
+		gas += words * params.InitCodeWordGas
+	}
+	if accessList != nil {
+		gas += uint64(len(accessList)) * params.TxAccessListAddressGas
+		gas += uint64(accessList.StorageKeys()) * params.TxAccessListStorageKeyGas
+	}
+	if authList != nil {
+		gas += uint64(len(authList)) * params.CallNewAccountGas
+	}
+	return gas, nil
+}
+
+// protocolGasTable returns the protocol's intrinsic gas prices for the given
+// fork flags.
+func protocolGasTable(isHomestead, isEIP2028 bool) params.GasTable {
+	table := params.GasTable{
+		TxGas:                 params.TxGas,
+		TxGasContractCreation: params.TxGas,
+		TxDataZeroGas:         params.TxDataZeroGas,
+		TxDataNonZeroGas:      params.TxDataNonZeroGasFrontier,
+	}
+	if isHomestead {
+		table.TxGasContractCreation = params.TxGasContractCreation
+	}
+	if isEIP2028 {
+		table.TxDataNonZeroGas = params.TxDataNonZeroGasEIP2028
+	}
+	return table
+}
+
+// CalldataGas computes the part of the intrinsic gas charged for the data of a
+// transaction, pricing zero and non-zero bytes differently.
+func CalldataGas(data []byte, isEIP2028 bool) (uint64, error) {
+	return calldataGas(data, protocolGasTable(false, isEIP2028))
+}
+
+// calldataGas computes the data part of the intrinsic gas using the prices of
+// the given table.
+func calldataGas(data []byte, table params.GasTable) (uint64, error) {
+	var gas uint64
 	if len(data) > 0 {
 		// Zero and non-zero bytes are priced differently
 		var nz uint64
@@ -133,30 +252,38 @@ func IntrinsicGas(data []byte, accessList types.AccessList, isContractCreation b
 			}
 		}
 		// Make sure we don't exceed uint64 for all data combinations
-		nonZeroGas := params.TxDataNonZeroGasFrontier
-		if isEIP2028 {
-			nonZeroGas = params.TxDataNonZeroGasEIP2028
-		}
-		if (math.MaxUint64-gas)/nonZeroGas < nz {
+		if (math.MaxUint64-gas)/table.TxDataNonZeroGas < nz {
 			return 0, ErrGasUintOverflow
 		}
-		gas += nz * nonZeroGas
+		gas += nz * table.TxDataNonZeroGas
 
 		z := uint64(len(data)) - nz
-		if (math.MaxUint64-gas)/params.TxDataZeroGas < z {
+		if (math.MaxUint64-gas)/table.TxDataZeroGas < z {
 			return 0, ErrGasUintOverflow
 		}
-		gas += z * params.TxDataZeroGas
-	}
-	if accessList != nil {
-		gas += uint64(len(accessList)) * params.TxAccessListAddressGas
-		gas += uint64(accessList.StorageKeys()) * params.TxAccessListStorageKeyGas
+		gas += z * table.TxDataZeroGas
 	}
 	return gas, nil
 }
 
+// FloorDataGas computes the minimum gas a transaction with the given data pays
+// since EIP-7623, regardless of the gas its execution consumes.
+func FloorDataGas(data []byte) (uint64, error) {
+	var (
+		z      = uint64(bytes.Count(data, []byte{0}))
+		nz     = uint64(len(data)) - z
+		tokens = nz*params.TxTokenPerNonZeroByte + z
+	)
+	// Check for overflow
+	if (math.MaxUint64-params.TxGas)/params.TxCostFloorPerToken < tokens {
+		return 0, ErrGasUintOverflow
+	}
+	// Minimum gas required for a transaction based on its data tokens (EIP-7623)
+	return params.TxGas + tokens*params.TxCostFloorPerToken, nil
+}
+
 // NewStateTransition initialises and returns a new state transition object.
-func NewStateTransition(evm *vm.EVM, msg Message, gp *GasPool) *StateTransition {
+func NewStateTransition(evm *vm.EVM, msg Message, gp GasPooler) *StateTransition {
 	return &StateTransition{
 		gp:        gp,
 		evm:       evm,
@@ -177,10 +304,53 @@ func NewStateTransition(evm *vm.EVM, msg Message, gp *GasPool) *StateTransition
 // the gas used (which includes gas refunds) and an error if it failed. An error always
 // indicates a core error meaning that the message would always fail for that particular
 // state and would never be accepted within a block.
-func ApplyMessage(evm *vm.EVM, msg Message, gp *GasPool) (*ExecutionResult, error) {
+func ApplyMessage(evm *vm.EVM, msg Message, gp GasPooler) (*ExecutionResult, error) {
 	return NewStateTransition(evm, msg, gp).TransitionDb()
 }
 
+// ApplyMessageNoFeeCheck applies a fake message like ApplyMessage, but without
+// checking the nonce or requiring the sender to hold the balance to buy the gas.
+// No fees are deducted from the sender nor paid to the coinbase, while intrinsic
+// and execution gas are still metered. It is meant for eth_call and gas
+// estimation; messages derived from real transactions are rejected.
+func ApplyMessageNoFeeCheck(evm *vm.EVM, msg Message, gp GasPooler) (*ExecutionResult, error) {
+	if !msg.IsFake() {
+		return nil, ErrNotFakeMessage
+	}
+	st := NewStateTransition(evm, msg, gp)
+	st.noFeeCheck = true
+	return st.TransitionDb()
+}
+
+// ApplyMessageWithRefundQuotient applies a message like ApplyMessage, but caps
+// the gas refund to gasUsed / quotient instead of the quotient of the active
+// fork. It allows experimenting with refund policies and is not meant for
+// consensus-critical execution. A zero quotient keeps the fork's default.
+func ApplyMessageWithRefundQuotient(evm *vm.EVM, msg Message, gp GasPooler, quotient uint64) (*ExecutionResult, error) {
+	st := NewStateTransition(evm, msg, gp)
+	st.refundQuotient = quotient
+	return st.TransitionDb()
+}
+
+// payer returns the account paying for the gas of the message.
+func (st *StateTransition) payer() common.Address {
+	if payer := st.msg.GasPayer(); payer != nil {
+		return *payer
+	}
+	return st.msg.From()
+}
+
+// ApplyMessageWithGasCap applies a message like ApplyMessage, but rejects it
+// with ErrGasCapExceeded if its gas limit exceeds gasCap, independently of the
+// gas left in the pool. It lets non-consensus executions such as eth_call bound
+// the work of a single message below the block gas limit. A zero cap disables
+// the check.
+func ApplyMessageWithGasCap(evm *vm.EVM, msg Message, gp GasPooler, gasCap uint64) (*ExecutionResult, error) {
+	st := NewStateTransition(evm, msg, gp)
+	st.gasCap = gasCap
+	return st.TransitionDb()
+}
+
 // to returns the recipient of the message.
 func (st *StateTransition) to() common.Address {
 	if st.msg == nil || st.msg.To() == nil /* contract creation */ {
@@ -192,14 +362,42 @@ func (st *StateTransition) to() common.Address {
 func (st *StateTransition) buyGas() error {
 	mgval := new(big.Int).SetUint64(st.msg.Gas())
 	mgval = mgval.Mul(mgval, st.gasPrice)
-	balanceCheck := mgval
+	balanceCheck := new(big.Int).Set(mgval)
 	if st.gasFeeCap != nil {
 		balanceCheck = new(big.Int).SetUint64(st.msg.Gas())
 		balanceCheck = balanceCheck.Mul(balanceCheck, st.gasFeeCap)
-		balanceCheck.Add(balanceCheck, st.value)
+		// A sponsored sender still needs the value, checked before the transfer
+		if st.payer() == st.msg.From() {
+			balanceCheck.Add(balanceCheck, st.value)
+		}
+	}
+	// Blob transactions additionally pay for their blob gas, which is burned
+	if blobFeeCap := st.msg.BlobGasFeeCap(); blobFeeCap != nil {
+		blobGas := new(big.Int).SetUint64(st.blobGasUsed())
+		if st.evm.Context.BlobBaseFee != nil {
+			mgval.Add(mgval, new(big.Int).Mul(blobGas, st.evm.Context.BlobBaseFee))
+		}
+		balanceCheck.Add(balanceCheck, blobGas.Mul(blobGas, blobFeeCap))
+	}
+	if st.noFeeCheck {
+		// The gas is treated as available without touching the sender
+		if err := st.gp.SubGas(st.msg.Gas()); err != nil {
+			return err
+		}
+		st.gas += st.msg.Gas()
+		st.initialGas = st.msg.Gas()
+		return nil
+	}
+	// Rollups may charge an additional fee, e.g. for posting the data to L1
+	if operator := st.evm.Config.OperatorFee; operator != nil {
+		if fee := operator.Fee(st.msg.Size()); fee != nil && fee.Sign() > 0 {
+			st.operatorFee = new(big.Int).Set(fee)
+			mgval = new(big.Int).Add(mgval, fee)
+			balanceCheck = new(big.Int).Add(balanceCheck, fee)
+		}
 	}
-	if have, want := st.state.GetBalance(st.msg.From()), balanceCheck; have.Cmp(want) < 0 {
-		return fmt.Errorf("%w: address %v have %v want %v", ErrInsufficientFunds, st.msg.From().Hex(), have, want)
+	if have, want := st.state.GetBalance(st.payer()), balanceCheck; have.Cmp(want) < 0 {
+		return &InsufficientFundsError{Err: ErrInsufficientFunds, Address: st.payer(), Have: have, Want: want}
 	}
 	if err := st.gp.SubGas(st.msg.Gas()); err != nil {
 		return err
@@ -207,7 +405,7 @@ func (st *StateTransition) buyGas() error {
 	st.gas += st.msg.Gas()
 
 	st.initialGas = st.msg.Gas()
-	st.state.SubBalance(st.msg.From(), mgval)
+	st.state.SubBalance(st.payer(), mgval)
 	return nil
 }
 
@@ -217,21 +415,35 @@ func (st *StateTransition) preCheck() error {
 		// Make sure this transaction's nonce is correct.
 		stNonce := st.state.GetNonce(st.msg.From())
 		if msgNonce := st.msg.Nonce(); stNonce < msgNonce {
-			return fmt.Errorf("%w: address %v, tx: %d state: %d", ErrNonceTooHigh,
-				st.msg.From().Hex(), msgNonce, stNonce)
+			return &NonceError{Err: ErrNonceTooHigh, Address: st.msg.From(), Have: msgNonce, Want: stNonce}
 		} else if stNonce > msgNonce {
-			return fmt.Errorf("%w: address %v, tx: %d state: %d", ErrNonceTooLow,
-				st.msg.From().Hex(), msgNonce, stNonce)
+			return &NonceError{Err: ErrNonceTooLow, Address: st.msg.From(), Have: msgNonce, Want: stNonce}
 		} else if stNonce+1 < stNonce {
 			return fmt.Errorf("%w: address %v, nonce: %d", ErrNonceMax,
 				st.msg.From().Hex(), stNonce)
 		}
-		// Make sure the sender is an EOA
+		// Make sure the sender is an EOA, possibly delegating its code (EIP-7702)
 		if codeHash := st.state.GetCodeHash(st.msg.From()); codeHash != emptyCodeHash && codeHash != (common.Hash{}) {
-			return fmt.Errorf("%w: address %v, codehash: %s", ErrSenderNoEOA,
-				st.msg.From().Hex(), codeHash)
+			if _, delegated := types.ParseDelegation(st.state.GetCode(st.msg.From())); !delegated {
+				return fmt.Errorf("%w: address %v, codehash: %s", ErrSenderNoEOA,
+					st.msg.From().Hex(), codeHash)
+			}
 		}
 	}
+	// Set code transactions must carry at least one authorization
+	if authList := st.msg.SetCodeAuthorizations(); authList != nil && len(authList) == 0 {
+		return fmt.Errorf("%w: address %v", ErrEmptyAuthList, st.msg.From().Hex())
+	}
+	// Make sure the transaction gas limit is within the cap (post osaka)
+	if st.evm.ChainConfig().IsOsaka(st.evm.Context.BlockNumber) && st.msg.Gas() > params.MaxTxGas {
+		return fmt.Errorf("%w: address %v, gas limit: %d cap: %d", ErrGasLimitTooHigh,
+			st.msg.From().Hex(), st.msg.Gas(), params.MaxTxGas)
+	}
+	// Make sure the message gas limit is within the call-site cap, if any
+	if st.gasCap != 0 && st.msg.Gas() > st.gasCap {
+		return fmt.Errorf("%w: address %v, gas limit: %d cap: %d", ErrGasCapExceeded,
+			st.msg.From().Hex(), st.msg.Gas(), st.gasCap)
+	}
 	// Make sure that transaction gasFeeCap is greater than the baseFee (post london)
 	if st.evm.ChainConfig().IsLondon(st.evm.Context.BlockNumber) {
 		// Skip the checks if gas fields are zero and baseFee was explicitly disabled (eth_call)
@@ -256,6 +468,30 @@ func (st *StateTransition) preCheck() error {
 			}
 		}
 	}
+	// Make sure blob transactions carry valid blob hashes and that their blob
+	// fee cap covers the blob gas price of the block (post cancun)
+	if blobFeeCap := st.msg.BlobGasFeeCap(); blobFeeCap != nil {
+		hashes := st.msg.BlobHashes()
+		if len(hashes) == 0 {
+			return fmt.Errorf("%w: address %v", ErrMissingBlobHashes, st.msg.From().Hex())
+		}
+		for i, hash := range hashes {
+			if hash[0] != params.BlobTxHashVersion {
+				return fmt.Errorf("%w: address %v, blob %d version: %d", ErrInvalidBlobHashVersion,
+					st.msg.From().Hex(), i, hash[0])
+			}
+		}
+		// Only simulations without fee checks may run blobs on a block without
+		// a blob gas price, any other execution would make them free
+		blobBaseFee := st.evm.Context.BlobBaseFee
+		if blobBaseFee == nil && !st.evm.Config.NoBaseFee {
+			return fmt.Errorf("%w: address %v", ErrMissingBlobBaseFee, st.msg.From().Hex())
+		}
+		if blobBaseFee != nil && blobFeeCap.Cmp(blobBaseFee) < 0 {
+			return fmt.Errorf("%w: address %v, maxFeePerBlobGas: %s blobBaseFee: %s", ErrBlobFeeCapTooLow,
+				st.msg.From().Hex(), blobFeeCap, blobBaseFee)
+		}
+	}
 	return st.buyGas()
 }
 
@@ -273,6 +509,23 @@ func (st *StateTransition) preCheck() error {
 // However if any consensus issue encountered, return the error directly with
 // nil evm execution result.
 func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
+	hooks := st.evm.Config.Hooks
+	if hooks.OnTxStart != nil {
+		hooks.OnTxStart(st.msg.From(), st.msg.To(), st.msg.Gas())
+	}
+	result, err := st.transitionDb()
+	if hooks.OnTxEnd != nil {
+		if err != nil {
+			hooks.OnTxEnd(0, err)
+		} else {
+			hooks.OnTxEnd(result.UsedGas, result.Err)
+		}
+	}
+	return result, err
+}
+
+// transitionDb implements TransitionDb, without firing the start and end hooks.
+func (st *StateTransition) transitionDb() (*ExecutionResult, error) {
 	// First check this message satisfies all consensus rules before
 	// applying the message. The rules include these clauses
 	//
@@ -283,6 +536,27 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 	// 5. there is no overflow when calculating intrinsic gas
 	// 6. caller has enough balance to cover asset transfer for **topmost** call
 
+	// Route all state access through a recorder if balance changes are requested
+	var recorder *balanceRecorder
+	if st.evm.Config.RecordBalanceChanges {
+		recorder = &balanceRecorder{StateDB: st.state, reason: BalanceChangeGasPurchase}
+
+		statedb, transfer := st.evm.StateDB, st.evm.Context.Transfer
+		st.state, st.evm.StateDB = recorder, recorder
+		st.evm.Context.Transfer = recorder.wrapTransfer(transfer)
+		defer func() {
+			st.state, st.evm.StateDB = statedb, statedb
+			st.evm.Context.Transfer = transfer
+		}()
+	}
+	if hook := st.evm.Config.Hooks.OnBalanceTransfer; hook != nil {
+		transfer := st.evm.Context.Transfer
+		st.evm.Context.Transfer = func(db vm.StateDB, from, to common.Address, amount *big.Int) {
+			transfer(db, from, to, amount)
+			hook(from, to, amount)
+		}
+		defer func() { st.evm.Context.Transfer = transfer }()
+	}
 	// Check clauses 1-3, buy gas if everything is correct
 	if err := st.preCheck(); err != nil {
 		return nil, err
@@ -303,7 +577,8 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 	)
 
 	// Check clauses 4-5, subtract intrinsic gas if everything is correct
-	gas, err := IntrinsicGas(st.data, st.msg.AccessList(), contractCreation, rules.IsHomestead, rules.IsIstanbul)
+	gasTable := st.evm.ChainConfig().IntrinsicGasTable(st.evm.Context.BlockNumber)
+	gas, err := IntrinsicGasWithTable(gasTable, st.data, st.msg.AccessList(), st.msg.SetCodeAuthorizations(), contractCreation, rules.IsShanghai)
 	if err != nil {
 		return nil, err
 	}
@@ -311,46 +586,298 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 		return nil, fmt.Errorf("%w: have %d, want %d", ErrIntrinsicGas, st.gas, gas)
 	}
 	st.gas -= gas
+	st.gasConsumed(gas)
+
+	// Since Prague, data-heavy messages pay at least the calldata floor (EIP-7623)
+	if rules.IsPrague {
+		if st.floorDataGas, err = FloorDataGas(st.data); err != nil {
+			return nil, err
+		}
+		if msg.Gas() < st.floorDataGas {
+			return nil, fmt.Errorf("%w: have %d, want %d", ErrFloorDataGas, msg.Gas(), st.floorDataGas)
+		}
+	}
 
 	// Check clause 6
 	if msg.Value().Sign() > 0 && !st.evm.Context.CanTransfer(st.state, msg.From(), msg.Value()) {
-		return nil, fmt.Errorf("%w: address %v", ErrInsufficientFundsForTransfer, msg.From().Hex())
+		return nil, &InsufficientFundsError{
+			Err:     ErrInsufficientFundsForTransfer,
+			Address: msg.From(),
+			Have:    st.state.GetBalance(msg.From()),
+			Want:    msg.Value(),
+		}
+	}
+
+	// Check whether the init code size has been exceeded
+	if limit := st.evm.ChainConfig().InitCodeSizeLimit(); rules.IsShanghai && contractCreation && len(st.data) > limit {
+		return nil, fmt.Errorf("%w: code size %v limit %v", ErrMaxInitCodeSizeExceeded, len(st.data), limit)
 	}
 
 	// Set up the initial access list.
 	if rules.IsBerlin {
-		st.state.PrepareAccessList(msg.From(), msg.To(), vm.ActivePrecompiles(rules), msg.AccessList())
+		st.state.PrepareAccessList(msg.From(), msg.To(), st.evm.ActivePrecompiles(), msg.AccessList())
 	}
 	var (
-		ret   []byte
-		vmerr error // vm errors do not effect consensus and are therefore not assigned to err
+		ret      []byte
+		modified bool  // whether the execution itself (not the gas purchase) touched the state
+		vmerr    error // vm errors do not effect consensus and are therefore not assigned to err
+		detailed = st.evm.Config.DetailedResults
+	)
+	st.setBalanceChangeReason(BalanceChangeInternalTransfer)
+	var (
+		check    = st.evm.Config.CheckDeterminism
+		probeRet []byte
+		probeGas uint64
+		execGas  = st.gas
 	)
 	if contractCreation {
+		if check {
+			probeRet, probeGas = st.probeExecution(func() ([]byte, uint64) {
+				ret, _, gas, _ := st.evm.Create(sender, st.data, st.gas, st.value)
+				return ret, gas
+			})
+		}
+		snapshot := st.state.Snapshot()
 		ret, _, st.gas, vmerr = st.evm.Create(sender, st.data, st.gas, st.value)
+		if detailed {
+			modified = st.state.ModifiedSince(snapshot)
+		}
 	} else {
 		// Increment the nonce for the next transaction
 		st.state.SetNonce(msg.From(), st.state.GetNonce(sender.Address())+1)
-		ret, st.gas, vmerr = st.evm.Call(sender, st.to(), st.data, st.gas, st.value)
+
+		snapshot := st.state.Snapshot()
+
+		// Apply the EIP-7702 authorizations, skipping the invalid ones
+		if authList := msg.SetCodeAuthorizations(); authList != nil {
+			for i := range authList {
+				st.applyAuthorization(&authList[i])
+			}
+		}
+		// A delegated destination also warms up the delegation target
+		if rules.IsPrague {
+			if target, ok := types.ParseDelegation(st.state.GetCode(st.to())); ok {
+				st.state.AddAddressToAccessList(target)
+			}
+		}
+		if check {
+			probeRet, probeGas = st.probeExecution(func() ([]byte, uint64) {
+				ret, gas, _ := st.evm.Call(sender, st.to(), st.data, st.gas, st.value)
+				return ret, gas
+			})
+		}
+		if st.plainTransfer() {
+			// Nothing to execute, skip setting up the interpreter
+			st.transferValue(rules)
+		} else {
+			ret, st.gas, vmerr = st.evm.Call(sender, st.to(), st.data, st.gas, st.value)
+		}
+		if detailed {
+			modified = st.state.ModifiedSince(snapshot)
+		}
 	}
+	deterministic := check && bytes.Equal(ret, probeRet) && st.gas == probeGas
+	st.gasConsumed(execGas - st.gas)
 
-	if !rules.IsLondon {
+	st.setBalanceChangeReason(BalanceChangeGasRefund)
+	if st.refundQuotient != 0 {
+		st.refundGas(st.refundQuotient)
+	} else if !rules.IsLondon || st.evm.Config.DisableEIP3529 {
 		// Before EIP-3529: refunds were capped to gasUsed / 2
 		st.refundGas(params.RefundQuotient)
 	} else {
 		// After EIP-3529: refunds are capped to gasUsed / 5
 		st.refundGas(params.RefundQuotientEIP3529)
 	}
-	effectiveTip := st.gasPrice
-	if rules.IsLondon {
-		effectiveTip = cmath.BigMin(st.gasTipCap, new(big.Int).Sub(st.gasFeeCap, st.evm.Context.BaseFee))
+	if !st.noFeeCheck && !st.evm.Config.SkipCoinbasePayment {
+		effectiveTip := st.gasPrice
+		if rules.IsLondon {
+			effectiveTip = cmath.BigMin(st.gasTipCap, new(big.Int).Sub(st.gasFeeCap, st.evm.Context.BaseFee))
+		}
+		fee := new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), effectiveTip)
+		if hook := st.evm.Config.FeeHook; hook != nil {
+			// Clamp the hooked amount to what was paid, so it can't mint ether
+			amount := hook(new(big.Int).Set(effectiveTip), new(big.Int).SetUint64(st.gasUsed()))
+			switch {
+			case amount == nil || amount.Sign() < 0:
+				fee = new(big.Int)
+			case amount.Cmp(fee) < 0:
+				fee = amount
+			}
+		}
+		st.setBalanceChangeReason(BalanceChangeCoinbase)
+		st.state.AddBalance(st.evm.Context.Coinbase, fee)
 	}
-	st.state.AddBalance(st.evm.Context.Coinbase, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), effectiveTip))
 
-	return &ExecutionResult{
-		UsedGas:    st.gasUsed(),
-		Err:        vmerr,
-		ReturnData: ret,
-	}, nil
+	result := &ExecutionResult{
+		UsedGas:       st.gasUsed(),
+		Err:           vmerr,
+		ReturnData:    ret,
+		Deterministic: deterministic,
+		OpcodeProfile: st.evm.OpcodeProfile(),
+	}
+	// Report the reverts caught internally only if the execution succeeded
+	if vmerr == nil {
+		result.CaughtReverts = st.evm.CaughtReverts()
+	}
+	if recorder != nil {
+		result.BalanceChanges = recorder.changes
+	}
+	if detailed {
+		st.fillDetails(result, contractCreation, modified, gasTable, rules)
+	}
+	return result, nil
+}
+
+// fillDetails fills in the statistics of a successfully applied message that
+// are only reported if vm.Config.DetailedResults is set.
+func (st *StateTransition) fillDetails(result *ExecutionResult, contractCreation, modified bool, gasTable params.GasTable, rules params.Rules) {
+	result.StateModified = modified
+	if contractCreation && result.Err == nil {
+		result.DeployedCode = common.CopyBytes(result.ReturnData)
+	}
+	// Collect the empty accounts EIP-161 will clear once the state is finalised
+	if rules.IsEIP158 {
+		result.ClearedAccounts = st.state.EmptyTouchedAccounts()
+	}
+	result.BlockHashOutOfRange = st.evm.BlockHashOutOfRange()
+	result.CreateCount, result.Create2Count = st.evm.CreateCounts()
+	result.StipendCalls = st.evm.StipendCalls()
+
+	// The calldata cost is part of the intrinsic gas, so it cannot overflow
+	result.CalldataGas, _ = calldataGas(st.data, gasTable)
+	result.EncodedSize = int(st.msg.Size())
+	result.BlobGasUsed = st.blobGasUsed()
+}
+
+// blobGasUsed returns the data availability gas of the message's blobs.
+func (st *StateTransition) blobGasUsed() uint64 {
+	return params.BlobTxBlobGasPerBlob * uint64(len(st.msg.BlobHashes()))
+}
+
+// gasConsumed reports the consumption of gas to the hooks, if any.
+func (st *StateTransition) gasConsumed(gas uint64) {
+	if hook := st.evm.Config.Hooks.OnGasConsumed; hook != nil {
+		hook(gas)
+	}
+}
+
+// plainTransfer reports whether the message only moves value to an account
+// without code, so that calling into the EVM would execute nothing.
+func (st *StateTransition) plainTransfer() bool {
+	msg := st.msg
+	if len(st.data) != 0 || len(msg.AccessList()) != 0 || msg.SetCodeAuthorizations() != nil {
+		return false
+	}
+	// Leave anything observable beyond the state change to the EVM
+	cfg := st.evm.Config
+	if cfg.Debug || cfg.CheckDeterminism || cfg.DenyInternalCalls {
+		return false
+	}
+	to := st.to()
+	for _, addr := range st.evm.ActivePrecompiles() {
+		if addr == to {
+			return false
+		}
+	}
+	return st.state.GetCodeSize(to) == 0
+}
+
+// transferValue moves the message value to its recipient the same way a call
+// into an account without code does.
+func (st *StateTransition) transferValue(rules params.Rules) {
+	to := st.to()
+	if !st.state.Exist(to) {
+		if rules.IsEIP158 && st.value.Sign() == 0 {
+			return
+		}
+		st.state.CreateAccount(to)
+	}
+	st.evm.Context.Transfer(st.state, st.msg.From(), to, st.value)
+}
+
+// probeExecution runs exec on a snapshot of the state that is reverted right
+// after, resetting any per-transaction bookkeeping of the EVM and the balance
+// recorder, and returns the return data and leftover gas of the run.
+func (st *StateTransition) probeExecution(exec func() ([]byte, uint64)) ([]byte, uint64) {
+	var (
+		snapshot    = st.state.Snapshot()
+		recorder, _ = st.state.(*balanceRecorder)
+		recorded    int
+		transferred bool
+	)
+	if recorder != nil {
+		recorded, transferred = len(recorder.changes), recorder.transfer
+	}
+	ret, gas := exec()
+	ret = common.CopyBytes(ret)
+
+	st.state.RevertToSnapshot(snapshot)
+	st.evm.Reset(st.evm.TxContext, st.evm.StateDB)
+	if recorder != nil {
+		recorder.changes, recorder.transfer = recorder.changes[:recorded], transferred
+	}
+	return ret, gas
+}
+
+// validateAuthorization checks an EIP-7702 authorization against the state and
+// returns the authority granting it.
+func (st *StateTransition) validateAuthorization(auth *types.SetCodeAuthorization) (common.Address, error) {
+	// Verify the chain ID is zero or equal to the current chain ID
+	if auth.ChainID != nil && auth.ChainID.Sign() != 0 && auth.ChainID.Cmp(st.evm.ChainConfig().ChainID) != 0 {
+		return common.Address{}, ErrAuthorizationWrongChainID
+	}
+	// Limit the nonce to 2^64-1 per EIP-2681
+	if auth.Nonce+1 < auth.Nonce {
+		return common.Address{}, ErrAuthorizationNonceOverflow
+	}
+	authority, err := auth.Authority()
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%w: %v", ErrAuthorizationInvalidSignature, err)
+	}
+	// The authority is warmed up even if the authorization turns out invalid
+	st.state.AddAddressToAccessList(authority)
+
+	// The authority must not have code other than a delegation and its nonce
+	// must match the authorization
+	code := st.state.GetCode(authority)
+	if _, ok := types.ParseDelegation(code); len(code) != 0 && !ok {
+		return authority, ErrAuthorizationDestinationHasCode
+	}
+	if have := st.state.GetNonce(authority); have != auth.Nonce {
+		return authority, ErrAuthorizationNonceMismatch
+	}
+	return authority, nil
+}
+
+// applyAuthorization installs the code delegation of a valid EIP-7702
+// authorization, or clears it if the authorization delegates to the zero
+// address.
+func (st *StateTransition) applyAuthorization(auth *types.SetCodeAuthorization) error {
+	authority, err := st.validateAuthorization(auth)
+	if err != nil {
+		return err
+	}
+	// Refund the new account cost charged as intrinsic gas if the authority
+	// already exists
+	if st.state.Exist(authority) {
+		st.state.AddRefund(params.CallNewAccountGas - params.TxAuthTupleGas)
+	}
+	st.state.SetNonce(authority, auth.Nonce+1)
+	if auth.Address == (common.Address{}) {
+		st.state.SetCode(authority, nil)
+		return nil
+	}
+	st.state.SetCode(authority, types.AddressToDelegation(auth.Address))
+	return nil
+}
+
+// setBalanceChangeReason attributes the following balance changes to reason if
+// they are being recorded.
+func (st *StateTransition) setBalanceChangeReason(reason BalanceChangeReason) {
+	if recorder, ok := st.state.(*balanceRecorder); ok {
+		recorder.reason = reason
+	}
 }
 
 func (st *StateTransition) refundGas(refundQuotient uint64) {
@@ -361,13 +888,26 @@ func (st *StateTransition) refundGas(refundQuotient uint64) {
 	}
 	st.gas += refund
 
+	// Data-heavy messages pay at least the calldata floor (EIP-7623)
+	if st.gasUsed() < st.floorDataGas {
+		st.gas = st.initialGas - st.floorDataGas
+	}
+
 	// Return ETH for remaining gas, exchanged at the original rate.
-	remaining := new(big.Int).Mul(new(big.Int).SetUint64(st.gas), st.gasPrice)
-	st.state.AddBalance(st.msg.From(), remaining)
+	if !st.noFeeCheck {
+		remaining := new(big.Int).Mul(new(big.Int).SetUint64(st.gas), st.gasPrice)
+		st.state.AddBalance(st.payer(), remaining)
+	}
+	// The operator fee is not refundable, hand it to the operator's vault
+	if st.operatorFee != nil {
+		st.setBalanceChangeReason(BalanceChangeOperatorFee)
+		st.state.AddBalance(st.evm.Config.OperatorFee.Vault(), st.operatorFee)
+		st.setBalanceChangeReason(BalanceChangeGasRefund)
+	}
 
 	// Also return remaining gas to the block gas counter so it is
 	// available for the next transaction.
-	st.gp.AddGas(st.gas)
+	st.gp.ReturnGas(st.gas)
 }
 
 // gasUsed returns the amount of gas used up by the state transition.
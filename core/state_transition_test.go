@@ -0,0 +1,1659 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	cmath "github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	transitionTestSender   = common.HexToAddress("0x71562b71999873DB5b286dF957af199Ec94617F7")
+	transitionTestCoinbase = common.HexToAddress("0xc0ffee")
+)
+
+// newTransitionTestState creates an in-memory state populated with the given
+// accounts, funding the default test sender with 1 ether.
+func newTransitionTestState(alloc GenesisAlloc) *state.StateDB {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.AddBalance(transitionTestSender, big.NewInt(params.Ether))
+	for addr, account := range alloc {
+		statedb.AddBalance(addr, account.Balance)
+		statedb.SetNonce(addr, account.Nonce)
+		statedb.SetCode(addr, account.Code)
+		for key, value := range account.Storage {
+			statedb.SetState(addr, key, value)
+		}
+	}
+	statedb.Finalise(true)
+	return statedb
+}
+
+// newTransitionTestEVM creates an EVM for block 1 on the given chain config.
+func newTransitionTestEVM(config *params.ChainConfig, statedb *state.StateDB, cfg vm.Config) *vm.EVM {
+	blockCtx := vm.BlockContext{
+		CanTransfer: CanTransfer,
+		Transfer:    Transfer,
+		GetHash:     func(uint64) common.Hash { return common.Hash{} },
+		Coinbase:    transitionTestCoinbase,
+		BlockNumber: big.NewInt(1),
+		Time:        big.NewInt(1),
+		Difficulty:  big.NewInt(1),
+		GasLimit:    30_000_000,
+		BaseFee:     big.NewInt(params.InitialBaseFee),
+	}
+	return vm.NewEVM(blockCtx, vm.TxContext{}, statedb, config, cfg)
+}
+
+// newTransitionTestMessage creates a message from the default test sender
+// paying exactly the test block's base fee.
+func newTransitionTestMessage(nonce uint64, to *common.Address, value *big.Int, gas uint64, data []byte) types.Message {
+	price := big.NewInt(params.InitialBaseFee)
+	return types.NewMessage(transitionTestSender, to, nonce, value, gas, price, price, new(big.Int), data, nil, false)
+}
+
+// applyTransitionTestMessage runs the message against the given state using
+// a fresh EVM and a block-sized gas pool.
+func applyTransitionTestMessage(config *params.ChainConfig, statedb *state.StateDB, msg types.Message, cfg vm.Config) (*ExecutionResult, error) {
+	evm := newTransitionTestEVM(config, statedb, cfg)
+	evm.Reset(NewEVMTxContext(msg), statedb)
+	return ApplyMessage(evm, msg, new(GasPool).AddGas(evm.Context.GasLimit))
+}
+
+func TestStateModified(t *testing.T) {
+	var (
+		viewer    = common.HexToAddress("0x1111")
+		recipient = common.HexToAddress("0x2222")
+		statedb   = newTransitionTestState(GenesisAlloc{
+			// PUSH1 0 SLOAD PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+			viewer: {Balance: new(big.Int), Code: common.FromHex("0x60005460005260206000f3")},
+		})
+	)
+	for i, tt := range []struct {
+		to    common.Address
+		value *big.Int
+		want  bool
+	}{
+		{viewer, new(big.Int), false},    // read-only contract call
+		{recipient, big.NewInt(1), true}, // plain value transfer
+	} {
+		to := tt.to
+		msg := newTransitionTestMessage(uint64(i), &to, tt.value, 100000, nil)
+		result, err := applyTransitionTestMessage(params.TestChainConfig, statedb, msg, vm.Config{DetailedResults: true})
+		if err != nil {
+			t.Fatalf("test %d: failed to apply message: %v", i, err)
+		}
+		if result.StateModified != tt.want {
+			t.Errorf("test %d: state modified mismatch: have %v, want %v", i, result.StateModified, tt.want)
+		}
+	}
+}
+
+// dualGasPool is a two dimensional gas pool metering calldata separately from
+// execution gas. The calldata gas of the next transaction is set by the block
+// builder before applying it, and unlike execution gas it is never refunded.
+type dualGasPool struct {
+	gas      uint64 // execution gas left in the block
+	calldata uint64 // calldata gas left in the block
+	next     uint64 // calldata gas of the next transaction
+}
+
+func (gp *dualGasPool) ReturnGas(amount uint64) {
+	gp.gas += amount
+}
+
+func (gp *dualGasPool) SubGas(amount uint64) error {
+	if gp.gas < amount || gp.calldata < gp.next {
+		return ErrGasLimitReached
+	}
+	gp.gas -= amount
+	gp.calldata -= gp.next
+	return nil
+}
+
+func (gp *dualGasPool) Gas() uint64 { return gp.gas }
+
+func TestCustomGasPool(t *testing.T) {
+	var (
+		to      = common.HexToAddress("0x2222")
+		statedb = newTransitionTestState(nil)
+		evm     = newTransitionTestEVM(params.TestChainConfig, statedb, vm.Config{})
+		gp      = &dualGasPool{gas: 1000000, calldata: 1000}
+	)
+	// The first transaction is charged in both dimensions, the second one
+	// exceeds the calldata left in the block
+	msg := newTransitionTestMessage(0, &to, big.NewInt(1), 50000, make([]byte, 150))
+	gp.next = 4 * 150
+	evm.Reset(NewEVMTxContext(msg), statedb)
+	result, err := ApplyMessage(evm, msg, gp)
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if have, want := gp.Gas(), 1000000-result.UsedGas; have != want {
+		t.Errorf("remaining gas mismatch: have %d, want %d", have, want)
+	}
+	if have, want := gp.calldata, uint64(1000-4*150); have != want {
+		t.Errorf("remaining calldata gas mismatch: have %d, want %d", have, want)
+	}
+	msg = newTransitionTestMessage(1, &to, big.NewInt(1), 50000, make([]byte, 150))
+	evm.Reset(NewEVMTxContext(msg), statedb)
+	if _, err := ApplyMessage(evm, msg, gp); !errors.Is(err, ErrGasLimitReached) {
+		t.Fatalf("calldata limit error mismatch: have %v, want %v", err, ErrGasLimitReached)
+	}
+	if have, want := gp.calldata, uint64(1000-4*150); have != want {
+		t.Errorf("calldata gas debited by rejected message: have %d, want %d", have, want)
+	}
+}
+
+// TestBn256AddRepricing tests that calls to the bn256 addition precompile are
+// charged according to the fork active for the message (EIP-1108).
+func TestBn256AddRepricing(t *testing.T) {
+	var (
+		byzantium = &params.ChainConfig{
+			ChainID:             big.NewInt(1),
+			HomesteadBlock:      big.NewInt(0),
+			EIP150Block:         big.NewInt(0),
+			EIP155Block:         big.NewInt(0),
+			EIP158Block:         big.NewInt(0),
+			ByzantiumBlock:      big.NewInt(0),
+			ConstantinopleBlock: big.NewInt(0),
+			PetersburgBlock:     big.NewInt(0),
+		}
+		istanbul = *byzantium
+		ecAdd    = common.BytesToAddress([]byte{6})
+	)
+	istanbul.IstanbulBlock = big.NewInt(0)
+
+	for i, tt := range []struct {
+		config *params.ChainConfig
+		gas    uint64
+	}{
+		{byzantium, params.TxGas + params.Bn256AddGasByzantium},
+		{&istanbul, params.TxGas + params.Bn256AddGasIstanbul},
+	} {
+		msg := newTransitionTestMessage(0, &ecAdd, new(big.Int), 100000, nil)
+		result, err := applyTransitionTestMessage(tt.config, newTransitionTestState(nil), msg, vm.Config{})
+		if err != nil {
+			t.Fatalf("test %d: failed to apply message: %v", i, err)
+		}
+		if result.Failed() {
+			t.Fatalf("test %d: execution failed: %v", i, result.Err)
+		}
+		if result.UsedGas != tt.gas {
+			t.Errorf("test %d: gas used mismatch: have %d, want %d", i, result.UsedGas, tt.gas)
+		}
+	}
+}
+
+// TestForkGasSchedules tests that the intrinsic data gas and the refund cap are
+// selected according to the active fork: 68 gas per non-zero byte and refunds
+// capped at half the gas used before Istanbul, 16 gas per non-zero byte from
+// Istanbul (EIP-2028) and refunds capped at a fifth from London (EIP-3529).
+func TestForkGasSchedules(t *testing.T) {
+	var (
+		petersburg = &params.ChainConfig{
+			ChainID:             big.NewInt(1),
+			HomesteadBlock:      big.NewInt(0),
+			EIP150Block:         big.NewInt(0),
+			EIP155Block:         big.NewInt(0),
+			EIP158Block:         big.NewInt(0),
+			ByzantiumBlock:      big.NewInt(0),
+			ConstantinopleBlock: big.NewInt(0),
+			PetersburgBlock:     big.NewInt(0),
+		}
+		istanbul = *petersburg
+		london   = *petersburg
+		contract = common.HexToAddress("0xc0de")
+		// PUSH1 0 PUSH1 0 SSTORE STOP, clearing a set slot for a refund
+		alloc = GenesisAlloc{contract: {
+			Code:    common.FromHex("0x6000600055"),
+			Storage: map[common.Hash]common.Hash{{}: common.BytesToHash([]byte{1})},
+			Balance: new(big.Int),
+		}}
+		data = []byte{1, 2, 3, 4}
+	)
+	istanbul.IstanbulBlock = big.NewInt(0)
+	london.IstanbulBlock = big.NewInt(0)
+	london.BerlinBlock = big.NewInt(0)
+	london.LondonBlock = big.NewInt(0)
+
+	for i, tt := range []struct {
+		config    *params.ChainConfig
+		intrinsic uint64
+		used      uint64
+	}{
+		// 26278 gas spent before refund, 15000 refund capped at 26278/2
+		{petersburg, params.TxGas + 4*params.TxDataNonZeroGasFrontier, 13139},
+		// 26070 gas spent before refund, 15000 refund capped at 26070/2
+		{&istanbul, params.TxGas + 4*params.TxDataNonZeroGasEIP2028, 13035},
+		// 26070 gas spent before refund, 4800 refund below 26070/5
+		{&london, params.TxGas + 4*params.TxDataNonZeroGasEIP2028, 26070 - params.SstoreClearsScheduleRefundEIP3529},
+	} {
+		rules := tt.config.Rules(big.NewInt(1), false)
+		intrinsic, err := IntrinsicGas(data, nil, nil, false, rules.IsHomestead, rules.IsIstanbul, rules.IsShanghai)
+		if err != nil {
+			t.Fatalf("test %d: failed to compute intrinsic gas: %v", i, err)
+		}
+		if intrinsic != tt.intrinsic {
+			t.Errorf("test %d: intrinsic gas mismatch: have %d, want %d", i, intrinsic, tt.intrinsic)
+		}
+		msg := newTransitionTestMessage(0, &contract, new(big.Int), 100000, data)
+		result, err := applyTransitionTestMessage(tt.config, newTransitionTestState(alloc), msg, vm.Config{})
+		if err != nil {
+			t.Fatalf("test %d: failed to apply message: %v", i, err)
+		}
+		if result.Failed() {
+			t.Fatalf("test %d: execution failed: %v", i, result.Err)
+		}
+		if result.UsedGas != tt.used {
+			t.Errorf("test %d: gas used mismatch: have %d, want %d", i, result.UsedGas, tt.used)
+		}
+	}
+}
+
+// TestGasPoolRefund tests that the gas left over by a transaction, including
+// refunds, is returned to the block gas pool both before and after London, as
+// blocks have always been charged only the gas their transactions used.
+func TestGasPoolRefund(t *testing.T) {
+	var (
+		berlin   = *params.TestChainConfig
+		contract = common.HexToAddress("0xc0de")
+		// PUSH1 0 PUSH1 0 SSTORE STOP, clearing a set slot for a refund
+		alloc = GenesisAlloc{contract: {
+			Code:    common.FromHex("0x6000600055"),
+			Storage: map[common.Hash]common.Hash{{}: common.BytesToHash([]byte{1})},
+			Balance: new(big.Int),
+		}}
+	)
+	berlin.LondonBlock = nil
+
+	for i, config := range []*params.ChainConfig{&berlin, params.TestChainConfig} {
+		var (
+			statedb = newTransitionTestState(alloc)
+			evm     = newTransitionTestEVM(config, statedb, vm.Config{})
+			gp      = new(GasPool).AddGas(200000)
+			used    uint64
+		)
+		for nonce := uint64(0); nonce < 2; nonce++ {
+			msg := newTransitionTestMessage(nonce, &contract, new(big.Int), 100000, nil)
+			evm.Reset(NewEVMTxContext(msg), statedb)
+			result, err := ApplyMessage(evm, msg, gp)
+			if err != nil {
+				t.Fatalf("test %d, tx %d: failed to apply message: %v", i, nonce, err)
+			}
+			used += result.UsedGas
+			if gp.Gas() != 200000-used {
+				t.Errorf("test %d, tx %d: pool gas mismatch: have %d, want %d", i, nonce, gp.Gas(), 200000-used)
+			}
+		}
+	}
+}
+
+// TestRefundQuotientOverride tests that the refund cap quotient can be chosen
+// per message, overriding the one of the active fork.
+func TestRefundQuotientOverride(t *testing.T) {
+	var (
+		contract = common.HexToAddress("0xc0de")
+		// PUSH1 0 PUSH1 0 SSTORE STOP, clearing a set slot for a refund
+		alloc = GenesisAlloc{contract: {
+			Code:    common.FromHex("0x6000600055"),
+			Storage: map[common.Hash]common.Hash{{}: common.BytesToHash([]byte{1})},
+			Balance: new(big.Int),
+		}}
+		// Berlin rules apply, granting a 15000 refund on 26006 gas spent
+		config = *params.TestChainConfig
+	)
+	config.LondonBlock = nil
+
+	for i, tt := range []struct {
+		quotient uint64
+		used     uint64
+	}{
+		{2, 26006 - 26006/2},
+		{5, 26006 - 26006/5},
+	} {
+		var (
+			statedb = newTransitionTestState(alloc)
+			evm     = newTransitionTestEVM(&config, statedb, vm.Config{})
+			msg     = newTransitionTestMessage(0, &contract, new(big.Int), 100000, nil)
+		)
+		evm.Reset(NewEVMTxContext(msg), statedb)
+		result, err := ApplyMessageWithRefundQuotient(evm, msg, new(GasPool).AddGas(evm.Context.GasLimit), tt.quotient)
+		if err != nil {
+			t.Fatalf("test %d: failed to apply message: %v", i, err)
+		}
+		if result.UsedGas != tt.used {
+			t.Errorf("test %d: gas used mismatch: have %d, want %d", i, result.UsedGas, tt.used)
+		}
+		cost := new(big.Int).Mul(new(big.Int).SetUint64(tt.used), msg.GasPrice())
+		if want := new(big.Int).Sub(big.NewInt(params.Ether), cost); statedb.GetBalance(transitionTestSender).Cmp(want) != 0 {
+			t.Errorf("test %d: sender balance mismatch: have %v, want %v", i, statedb.GetBalance(transitionTestSender), want)
+		}
+	}
+}
+
+// TestDisableEIP3529 tests that the pre EIP-3529 refund rules can be applied on
+// London for comparison, refunding self-destructs and capping at half the gas.
+func TestDisableEIP3529(t *testing.T) {
+	var (
+		contract = common.HexToAddress("0xc0de")
+		// PUSH1 0 PUSH1 0 SSTORE PUSH1 0xbe SELFDESTRUCT
+		alloc = GenesisAlloc{contract: {
+			Code:    common.FromHex("0x600060005560beff"),
+			Storage: map[common.Hash]common.Hash{{}: common.BytesToHash([]byte{1})},
+			Balance: new(big.Int),
+		}}
+	)
+	for i, tt := range []struct {
+		disable bool
+		used    uint64
+	}{
+		// 33609 gas spent before refund, 4800 refund below 33609/5
+		{false, 33609 - params.SstoreClearsScheduleRefundEIP3529},
+		// 33609 gas spent before refund, 15000+24000 refund capped at 33609/2
+		{true, 33609 - 33609/2},
+	} {
+		msg := newTransitionTestMessage(0, &contract, new(big.Int), 100000, nil)
+		result, err := applyTransitionTestMessage(params.TestChainConfig, newTransitionTestState(alloc), msg, vm.Config{DisableEIP3529: tt.disable})
+		if err != nil {
+			t.Fatalf("test %d: failed to apply message: %v", i, err)
+		}
+		if result.Failed() {
+			t.Fatalf("test %d: execution failed: %v", i, result.Err)
+		}
+		if result.UsedGas != tt.used {
+			t.Errorf("test %d: gas used mismatch: have %d, want %d", i, result.UsedGas, tt.used)
+		}
+	}
+}
+
+// TestSstoreClearRefund tests that clearing a storage slot adds the refund of
+// the active fork to the refund counter: 15000 gas before EIP-3529 and 4800 gas
+// from London on.
+func TestSstoreClearRefund(t *testing.T) {
+	var (
+		berlin = &params.ChainConfig{
+			ChainID:             big.NewInt(1),
+			HomesteadBlock:      big.NewInt(0),
+			EIP150Block:         big.NewInt(0),
+			EIP155Block:         big.NewInt(0),
+			EIP158Block:         big.NewInt(0),
+			ByzantiumBlock:      big.NewInt(0),
+			ConstantinopleBlock: big.NewInt(0),
+			PetersburgBlock:     big.NewInt(0),
+			IstanbulBlock:       big.NewInt(0),
+			BerlinBlock:         big.NewInt(0),
+		}
+		london   = *berlin
+		contract = common.HexToAddress("0xc0de")
+		// PUSH1 0 PUSH1 0 SSTORE STOP, clearing a set slot for a refund
+		alloc = GenesisAlloc{contract: {
+			Code:    common.FromHex("0x6000600055"),
+			Storage: map[common.Hash]common.Hash{{}: common.BytesToHash([]byte{1})},
+			Balance: new(big.Int),
+		}}
+	)
+	london.LondonBlock = big.NewInt(0)
+
+	for i, tt := range []struct {
+		config *params.ChainConfig
+		refund uint64
+	}{
+		{berlin, params.SstoreClearsScheduleRefundEIP2200},
+		{&london, params.SstoreClearsScheduleRefundEIP3529},
+	} {
+		statedb := newTransitionTestState(alloc)
+		msg := newTransitionTestMessage(0, &contract, new(big.Int), 100000, nil)
+		result, err := applyTransitionTestMessage(tt.config, statedb, msg, vm.Config{})
+		if err != nil {
+			t.Fatalf("test %d: failed to apply message: %v", i, err)
+		}
+		if result.Failed() {
+			t.Fatalf("test %d: execution failed: %v", i, result.Err)
+		}
+		if refund := statedb.GetRefund(); refund != tt.refund {
+			t.Errorf("test %d: refund counter mismatch: have %d, want %d", i, refund, tt.refund)
+		}
+	}
+}
+
+// TestDynamicFeeAccounting tests that with EIP-1559 active the sender pays the
+// effective gas price, the coinbase only receives the effective tip and the
+// base fee portion is burned.
+func TestDynamicFeeAccounting(t *testing.T) {
+	var (
+		to      = common.HexToAddress("0x2222")
+		statedb = newTransitionTestState(nil)
+		baseFee = big.NewInt(params.InitialBaseFee)
+		feeCap  = new(big.Int).Mul(baseFee, big.NewInt(3))
+		tipCap  = big.NewInt(params.GWei)
+		price   = cmath.BigMin(new(big.Int).Add(tipCap, baseFee), feeCap)
+		msg     = types.NewMessage(transitionTestSender, &to, 0, new(big.Int), params.TxGas, price, feeCap, tipCap, nil, nil, false)
+	)
+	result, err := applyTransitionTestMessage(params.TestChainConfig, statedb, msg, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	used := new(big.Int).SetUint64(result.UsedGas)
+
+	paid := new(big.Int).Sub(big.NewInt(params.Ether), statedb.GetBalance(transitionTestSender))
+	if want := new(big.Int).Mul(used, price); paid.Cmp(want) != 0 {
+		t.Errorf("sender payment mismatch: have %v, want %v", paid, want)
+	}
+	tip := statedb.GetBalance(transitionTestCoinbase)
+	if want := new(big.Int).Mul(used, tipCap); tip.Cmp(want) != 0 {
+		t.Errorf("coinbase tip mismatch: have %v, want %v", tip, want)
+	}
+	if burnt, want := new(big.Int).Sub(paid, tip), new(big.Int).Mul(used, baseFee); burnt.Cmp(want) != 0 {
+		t.Errorf("burnt fee mismatch: have %v, want %v", burnt, want)
+	}
+}
+
+func TestDeployedCode(t *testing.T) {
+	var (
+		statedb = newTransitionTestState(nil)
+		runtime = common.FromHex("0x60005460005260206000f3")
+		// PUSH11 <runtime> PUSH1 0 MSTORE PUSH1 11 PUSH1 21 RETURN
+		initcode = append(append([]byte{0x6a}, runtime...), common.FromHex("0x600052600b6015f3")...)
+		msg      = newTransitionTestMessage(0, nil, new(big.Int), 200000, initcode)
+	)
+	result, err := applyTransitionTestMessage(params.TestChainConfig, statedb, msg, vm.Config{DetailedResults: true})
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if result.Failed() {
+		t.Fatalf("contract creation failed: %v", result.Err)
+	}
+	code := statedb.GetCode(crypto.CreateAddress(transitionTestSender, 0))
+	if !bytes.Equal(code, runtime) {
+		t.Fatalf("stored code mismatch: have %x, want %x", code, runtime)
+	}
+	if !bytes.Equal(result.DeployedCode, code) {
+		t.Errorf("deployed code mismatch: have %x, want %x", result.DeployedCode, code)
+	}
+}
+
+// TestDetailedResults tests that the statistics of the execution result are
+// only computed if requested.
+func TestDetailedResults(t *testing.T) {
+	msg := newTransitionTestMessage(0, nil, new(big.Int), 200000, common.FromHex("0x60016000526001601ff3"))
+	result, err := applyTransitionTestMessage(params.TestChainConfig, newTransitionTestState(nil), msg, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if result.Failed() {
+		t.Fatalf("contract creation failed: %v", result.Err)
+	}
+	if result.StateModified || result.DeployedCode != nil || result.CalldataGas != 0 {
+		t.Errorf("details reported without being requested: modified %v, code %x, calldata gas %d", result.StateModified, result.DeployedCode, result.CalldataGas)
+	}
+}
+
+// TestEmptyInitcodeCreation tests that a contract creation without initcode is
+// charged exactly the creation base gas and deploys an empty contract.
+func TestEmptyInitcodeCreation(t *testing.T) {
+	var (
+		statedb = newTransitionTestState(nil)
+		msg     = newTransitionTestMessage(0, nil, new(big.Int), 100000, nil)
+	)
+	result, err := applyTransitionTestMessage(params.TestChainConfig, statedb, msg, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if result.Failed() {
+		t.Fatalf("contract creation failed: %v", result.Err)
+	}
+	if result.UsedGas != params.TxGasContractCreation {
+		t.Errorf("gas used mismatch: have %d, want %d", result.UsedGas, params.TxGasContractCreation)
+	}
+	addr := crypto.CreateAddress(transitionTestSender, 0)
+	if !statedb.Exist(addr) {
+		t.Fatalf("created account %x does not exist", addr)
+	}
+	if code := statedb.GetCode(addr); len(code) != 0 {
+		t.Errorf("created account has code: %x", code)
+	}
+	if nonce := statedb.GetNonce(addr); nonce != 1 {
+		t.Errorf("created account nonce mismatch: have %d, want 1", nonce)
+	}
+	cost := new(big.Int).Mul(new(big.Int).SetUint64(params.TxGasContractCreation), msg.GasPrice())
+	if want := new(big.Int).Sub(big.NewInt(params.Ether), cost); statedb.GetBalance(transitionTestSender).Cmp(want) != 0 {
+		t.Errorf("sender balance mismatch: have %v, want %v", statedb.GetBalance(transitionTestSender), want)
+	}
+}
+
+// TestApplyMessageNoFeeCheck tests that fake messages can be applied without a
+// matching nonce or the balance to buy gas, metering the same gas as a regular
+// execution while leaving the sender and coinbase balances untouched.
+func TestApplyMessageNoFeeCheck(t *testing.T) {
+	var (
+		contract = common.HexToAddress("0xc0de")
+		// PUSH1 1 PUSH1 0 SSTORE STOP
+		code     = common.FromHex("0x6001600055")
+		alloc    = GenesisAlloc{contract: {Code: code, Balance: new(big.Int)}}
+		unfunded = common.HexToAddress("0xdead")
+		price    = big.NewInt(params.InitialBaseFee)
+	)
+	// Execute the call regularly to get the expected gas usage
+	want, err := applyTransitionTestMessage(params.TestChainConfig, newTransitionTestState(alloc), newTransitionTestMessage(0, &contract, new(big.Int), 100000, nil), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to apply regular message: %v", err)
+	}
+	// Execute the same call from an unfunded account with a bogus nonce
+	statedb := newTransitionTestState(alloc)
+	evm := newTransitionTestEVM(params.TestChainConfig, statedb, vm.Config{})
+
+	msg := types.NewMessage(unfunded, &contract, 5, new(big.Int), 100000, price, price, new(big.Int), nil, nil, true)
+	evm.Reset(NewEVMTxContext(msg), statedb)
+	result, err := ApplyMessageNoFeeCheck(evm, msg, new(GasPool).AddGas(evm.Context.GasLimit))
+	if err != nil {
+		t.Fatalf("failed to apply message without fee checks: %v", err)
+	}
+	if result.Failed() {
+		t.Fatalf("message execution failed: %v", result.Err)
+	}
+	if result.UsedGas != want.UsedGas {
+		t.Errorf("gas used mismatch: have %d, want %d", result.UsedGas, want.UsedGas)
+	}
+	if balance := statedb.GetBalance(unfunded); balance.Sign() != 0 {
+		t.Errorf("sender charged: balance %v", balance)
+	}
+	if balance := statedb.GetBalance(transitionTestCoinbase); balance.Sign() != 0 {
+		t.Errorf("coinbase paid: balance %v", balance)
+	}
+	// Messages of real transactions must not skip the checks
+	msg = types.NewMessage(unfunded, &contract, 0, new(big.Int), 100000, price, price, new(big.Int), nil, nil, false)
+	if _, err := ApplyMessageNoFeeCheck(evm, msg, new(GasPool).AddGas(evm.Context.GasLimit)); !errors.Is(err, ErrNotFakeMessage) {
+		t.Errorf("non-fake message error mismatch: have %v, want %v", err, ErrNotFakeMessage)
+	}
+}
+
+func TestFeeHook(t *testing.T) {
+	var (
+		to      = common.HexToAddress("0x2222")
+		baseFee = big.NewInt(params.InitialBaseFee)
+		tipCap  = big.NewInt(params.GWei)
+		price   = new(big.Int).Add(baseFee, tipCap)
+		msg     = types.NewMessage(transitionTestSender, &to, 0, new(big.Int), params.TxGas, price, price, tipCap, nil, nil, false)
+		tip     = new(big.Int).Mul(tipCap, new(big.Int).SetUint64(params.TxGas))
+	)
+	tests := []struct {
+		hook func(effectivePrice, gasUsed *big.Int) *big.Int
+		want *big.Int
+	}{
+		// Half of the tip credited, the rest burned
+		{
+			hook: func(effectivePrice, gasUsed *big.Int) *big.Int {
+				fee := new(big.Int).Mul(effectivePrice, gasUsed)
+				return fee.Div(fee, big.NewInt(2))
+			},
+			want: new(big.Int).Div(tip, big.NewInt(2)),
+		},
+		// Amounts above the tip paid are capped to it
+		{
+			hook: func(effectivePrice, gasUsed *big.Int) *big.Int {
+				return new(big.Int).Mul(tip, big.NewInt(2))
+			},
+			want: tip,
+		},
+		// Negative and missing amounts credit nothing
+		{
+			hook: func(effectivePrice, gasUsed *big.Int) *big.Int { return big.NewInt(-1) },
+			want: new(big.Int),
+		},
+		{
+			hook: func(effectivePrice, gasUsed *big.Int) *big.Int { return nil },
+			want: new(big.Int),
+		},
+	}
+	for i, tt := range tests {
+		statedb := newTransitionTestState(nil)
+		if _, err := applyTransitionTestMessage(params.TestChainConfig, statedb, msg, vm.Config{FeeHook: tt.hook}); err != nil {
+			t.Fatalf("test %d: failed to apply message: %v", i, err)
+		}
+		if have := statedb.GetBalance(transitionTestCoinbase); have.Cmp(tt.want) != 0 {
+			t.Errorf("test %d: coinbase balance mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
+
+// TestFloorDataGas tests that since Prague data-heavy messages are charged the
+// calldata floor instead of their lower execution gas (EIP-7623).
+func TestFloorDataGas(t *testing.T) {
+	var (
+		prague = *params.TestChainConfig
+		to     = common.HexToAddress("0x2222")
+		data   = bytes.Repeat([]byte{0xff}, 1000)
+		floor  = params.TxGas + 1000*params.TxTokenPerNonZeroByte*params.TxCostFloorPerToken
+	)
+	prague.PragueBlock = big.NewInt(0)
+
+	if have, err := FloorDataGas(data); err != nil || have != floor {
+		t.Fatalf("floor data gas mismatch: have %d, %v, want %d", have, err, floor)
+	}
+	for i, tt := range []struct {
+		config *params.ChainConfig
+		gas    uint64
+		used   uint64
+		err    error
+	}{
+		{params.TestChainConfig, 100000, params.TxGas + 1000*params.TxDataNonZeroGasEIP2028, nil},
+		{&prague, 100000, floor, nil},
+		{&prague, floor - 1, 0, ErrFloorDataGas},
+	} {
+		msg := newTransitionTestMessage(0, &to, new(big.Int), tt.gas, data)
+		result, err := applyTransitionTestMessage(tt.config, newTransitionTestState(nil), msg, vm.Config{})
+		if !errors.Is(err, tt.err) {
+			t.Fatalf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if err == nil && result.UsedGas != tt.used {
+			t.Errorf("test %d: gas used mismatch: have %d, want %d", i, result.UsedGas, tt.used)
+		}
+	}
+}
+
+// TestIntrinsicGasTable tests that a chain config can reprice the intrinsic gas
+// of transactions, falling back to the protocol prices for unset entries.
+func TestIntrinsicGasTable(t *testing.T) {
+	var (
+		config = *params.TestChainConfig
+		to     = common.HexToAddress("0x2222")
+		data   = []byte{0x00, 0xff}
+	)
+	config.GasTable = &params.GasTable{TxGas: 5000, TxDataZeroGas: 1}
+
+	want := params.GasTable{
+		TxGas:                 5000,
+		TxGasContractCreation: params.TxGasContractCreation,
+		TxDataZeroGas:         1,
+		TxDataNonZeroGas:      params.TxDataNonZeroGasEIP2028,
+	}
+	if have := config.IntrinsicGasTable(common.Big0); have != want {
+		t.Fatalf("gas table mismatch: have %+v, want %+v", have, want)
+	}
+	msg := newTransitionTestMessage(0, &to, new(big.Int), 100000, data)
+	result, err := applyTransitionTestMessage(&config, newTransitionTestState(nil), msg, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if used := uint64(5000 + 1 + params.TxDataNonZeroGasEIP2028); result.UsedGas != used {
+		t.Errorf("gas used mismatch: have %d, want %d", result.UsedGas, used)
+	}
+	// A table scheduled for a future block leaves the protocol prices in force
+	config.GasTable.Block = big.NewInt(1)
+	if have := config.IntrinsicGasTable(common.Big0); have.TxGas != params.TxGas {
+		t.Errorf("premature gas table: have %d, want %d", have.TxGas, params.TxGas)
+	}
+}
+
+// TestPlainTransfer tests that value transfers skipping the interpreter end up
+// in the same state as the ones executed by it.
+func TestPlainTransfer(t *testing.T) {
+	var (
+		existing = common.HexToAddress("0x2222")
+		contract = common.HexToAddress("0x3333")
+		fresh    = common.HexToAddress("0x4444")
+		alloc    = GenesisAlloc{
+			existing: {Balance: big.NewInt(1)},
+			contract: {Code: []byte{byte(vm.STOP)}, Balance: new(big.Int)},
+		}
+	)
+	for i, tt := range []struct {
+		to    common.Address
+		value int64
+		gas   uint64
+	}{
+		{existing, 1000, params.TxGas},
+		{existing, 0, params.TxGas},
+		{contract, 1000, params.TxGas},
+		{fresh, 1000, params.TxGas},
+		{fresh, 0, params.TxGas},
+		{common.BytesToAddress([]byte{0x04}), 1000, params.TxGas + params.IdentityBaseGas}, // identity precompile
+	} {
+		var roots []common.Hash
+		for _, cfg := range []vm.Config{{}, {Debug: true, Tracer: logger.NewStructLogger(nil)}} {
+			statedb := newTransitionTestState(alloc)
+			msg := newTransitionTestMessage(0, &tt.to, big.NewInt(tt.value), 100000, nil)
+			result, err := applyTransitionTestMessage(params.TestChainConfig, statedb, msg, cfg)
+			if err != nil {
+				t.Fatalf("test %d: failed to apply message: %v", i, err)
+			}
+			if result.Failed() || result.UsedGas != tt.gas {
+				t.Fatalf("test %d: unexpected result: err %v, gas used %d", i, result.Err, result.UsedGas)
+			}
+			roots = append(roots, statedb.IntermediateRoot(true))
+		}
+		if roots[0] != roots[1] {
+			t.Errorf("test %d: state root mismatch: have %x, want %x", i, roots[0], roots[1])
+		}
+	}
+}
+
+// TestSkipCoinbasePayment tests that the coinbase can be left out of the state
+// diff of a message while the sender still pays for its gas.
+func TestSkipCoinbasePayment(t *testing.T) {
+	var (
+		to      = common.HexToAddress("0x2222")
+		statedb = newTransitionTestState(nil)
+		baseFee = big.NewInt(params.InitialBaseFee)
+		tipCap  = big.NewInt(params.GWei)
+		price   = new(big.Int).Add(baseFee, tipCap)
+		msg     = types.NewMessage(transitionTestSender, &to, 0, new(big.Int), params.TxGas, price, price, tipCap, nil, nil, false)
+	)
+	if _, err := applyTransitionTestMessage(params.TestChainConfig, statedb, msg, vm.Config{SkipCoinbasePayment: true}); err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if statedb.Exist(transitionTestCoinbase) {
+		t.Errorf("coinbase credited: balance %v", statedb.GetBalance(transitionTestCoinbase))
+	}
+	want := new(big.Int).Sub(big.NewInt(params.Ether), new(big.Int).Mul(price, new(big.Int).SetUint64(params.TxGas)))
+	if have := statedb.GetBalance(transitionTestSender); have.Cmp(want) != 0 {
+		t.Errorf("sender balance mismatch: have %v, want %v", have, want)
+	}
+}
+
+// TestCalldataGas tests that the portion of the intrinsic gas charged for the
+// transaction data is reported, priced per zero and non-zero byte.
+func TestCalldataGas(t *testing.T) {
+	var (
+		to   = common.HexToAddress("0x2222")
+		data = []byte{0, 0, 0, 1, 2}
+		msg  = newTransitionTestMessage(0, &to, new(big.Int), 100000, data)
+		want = 3*params.TxDataZeroGas + 2*params.TxDataNonZeroGasEIP2028
+	)
+	result, err := applyTransitionTestMessage(params.TestChainConfig, newTransitionTestState(nil), msg, vm.Config{DetailedResults: true})
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if result.CalldataGas != want {
+		t.Errorf("calldata gas mismatch: have %d, want %d", result.CalldataGas, want)
+	}
+	if result.UsedGas != params.TxGas+want {
+		t.Errorf("gas used mismatch: have %d, want %d", result.UsedGas, params.TxGas+want)
+	}
+}
+
+// TestEncodedSize tests that the result of a message derived from a transaction
+// reports the size of the transaction's encoding.
+func TestEncodedSize(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		signer = types.LatestSigner(params.TestChainConfig)
+		to     = common.HexToAddress("0x2222")
+		tx     = types.MustSignNewTx(key, signer, &types.LegacyTx{
+			To:       &to,
+			Value:    big.NewInt(1),
+			Gas:      params.TxGas + 100,
+			GasPrice: big.NewInt(params.InitialBaseFee),
+			Data:     []byte{0x01, 0x02, 0x03},
+		})
+	)
+	msg, err := tx.AsMessage(signer, big.NewInt(params.InitialBaseFee))
+	if err != nil {
+		t.Fatalf("failed to create message: %v", err)
+	}
+	result, err := applyTransitionTestMessage(params.TestChainConfig, newTransitionTestState(nil), msg, vm.Config{DetailedResults: true})
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	enc, _ := tx.MarshalBinary()
+	if result.EncodedSize != len(enc) {
+		t.Errorf("encoded size mismatch: have %d, want %d", result.EncodedSize, len(enc))
+	}
+	// Messages not originating from a transaction have no encoding
+	result, err = applyTransitionTestMessage(params.TestChainConfig, newTransitionTestState(nil), newTransitionTestMessage(0, &to, big.NewInt(1), params.TxGas, nil), vm.Config{DetailedResults: true})
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if result.EncodedSize != 0 {
+		t.Errorf("encoded size of non-transaction message: have %d, want 0", result.EncodedSize)
+	}
+}
+
+// TestIntrinsicGasAccessList tests that access lists are charged per address and
+// per storage key on top of the regular intrinsic gas.
+func TestIntrinsicGasAccessList(t *testing.T) {
+	var (
+		data = []byte{0x00, 0x01, 0x02}
+		list = types.AccessList{
+			{Address: common.HexToAddress("0x1111"), StorageKeys: []common.Hash{{0x01}, {0x02}}},
+			{Address: common.HexToAddress("0x2222"), StorageKeys: []common.Hash{{0x03}}},
+		}
+	)
+	base, err := IntrinsicGas(data, nil, nil, false, true, true, false)
+	if err != nil {
+		t.Fatalf("failed to compute intrinsic gas: %v", err)
+	}
+	gas, err := IntrinsicGas(data, list, nil, false, true, true, false)
+	if err != nil {
+		t.Fatalf("failed to compute intrinsic gas with access list: %v", err)
+	}
+	if have, want := gas-base, 2*params.TxAccessListAddressGas+3*params.TxAccessListStorageKeyGas; have != want {
+		t.Errorf("access list gas mismatch: have %d, want %d", have, want)
+	}
+}
+
+// TestClearedAccounts tests that empty accounts touched by a message are reported
+// as cleared under EIP-161, while self-destructs are not.
+func TestClearedAccounts(t *testing.T) {
+	var (
+		empty   = common.HexToAddress("0x3333")
+		statedb = newTransitionTestState(GenesisAlloc{
+			transitionTestCoinbase: {Balance: big.NewInt(1)}, // avoid clearing the coinbase
+		})
+	)
+	// Create an empty account as it could have existed before Spurious Dragon
+	statedb.CreateAccount(empty)
+	statedb.Finalise(false)
+
+	msg := newTransitionTestMessage(0, &empty, new(big.Int), 100000, nil)
+	result, err := applyTransitionTestMessage(params.TestChainConfig, statedb, msg, vm.Config{DetailedResults: true})
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if len(result.ClearedAccounts) != 1 || result.ClearedAccounts[0] != empty {
+		t.Fatalf("cleared accounts mismatch: have %v, want %v", result.ClearedAccounts, []common.Address{empty})
+	}
+	statedb.Finalise(true)
+	if statedb.Exist(empty) {
+		t.Errorf("empty account not cleared")
+	}
+}
+
+// TestExecutionResultRevert tests that the revert reason of a reverting call is
+// retained in the execution result and can be decoded.
+func TestExecutionResultRevert(t *testing.T) {
+	var (
+		reverter = common.HexToAddress("0x4444")
+		reason   = "insufficient allowance"
+		payload  = append(common.FromHex("0x08c379a0"), common.LeftPadBytes([]byte{0x20}, 32)...)
+	)
+	payload = append(payload, common.LeftPadBytes([]byte{byte(len(reason))}, 32)...)
+	payload = append(payload, common.RightPadBytes([]byte(reason), 32)...)
+
+	// PUSH1 100 PUSH1 12 PUSH1 0 CODECOPY PUSH1 100 PUSH1 0 REVERT <payload>
+	code := append(common.FromHex("0x6064600c60003960646000fd"), payload...)
+	statedb := newTransitionTestState(GenesisAlloc{
+		reverter: {Balance: new(big.Int), Code: code},
+	})
+	msg := newTransitionTestMessage(0, &reverter, new(big.Int), 100000, nil)
+	result, err := applyTransitionTestMessage(params.TestChainConfig, statedb, msg, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if result.Err != vm.ErrExecutionReverted {
+		t.Fatalf("execution error mismatch: have %v, want %v", result.Err, vm.ErrExecutionReverted)
+	}
+	if result.Return() != nil {
+		t.Errorf("reverted execution returned data: %x", result.Return())
+	}
+	have, err := abi.UnpackRevert(result.Revert())
+	if err != nil {
+		t.Fatalf("failed to unpack revert reason: %v", err)
+	}
+	if have != reason {
+		t.Errorf("revert reason mismatch: have %q, want %q", have, reason)
+	}
+}
+
+// TestCaughtReverts tests that the revert reasons of internal calls reverting
+// are reported for a successful execution if requested.
+func TestCaughtReverts(t *testing.T) {
+	var (
+		reverter = common.HexToAddress("0x4444")
+		catcher  = common.HexToAddress("0x5555")
+		reason   = "insufficient allowance"
+		payload  = append(common.FromHex("0x08c379a0"), common.LeftPadBytes([]byte{0x20}, 32)...)
+	)
+	payload = append(payload, common.LeftPadBytes([]byte{byte(len(reason))}, 32)...)
+	payload = append(payload, common.RightPadBytes([]byte(reason), 32)...)
+
+	statedb := newTransitionTestState(GenesisAlloc{
+		// PUSH1 100 PUSH1 12 PUSH1 0 CODECOPY PUSH1 100 PUSH1 0 REVERT <payload>
+		reverter: {Balance: new(big.Int), Code: append(common.FromHex("0x6064600c60003960646000fd"), payload...)},
+		// CALL(GAS, 0x4444, 0, 0, 0, 0, 0) POP STOP
+		catcher: {Balance: new(big.Int), Code: common.FromHex("0x600060006000600060006144445af15000")},
+	})
+	msg := newTransitionTestMessage(0, &catcher, new(big.Int), 100000, nil)
+	result, err := applyTransitionTestMessage(params.TestChainConfig, statedb, msg, vm.Config{RecordCaughtReverts: true})
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if result.Failed() {
+		t.Fatalf("execution failed: %v", result.Err)
+	}
+	if len(result.CaughtReverts) != 1 {
+		t.Fatalf("caught revert count mismatch: have %d, want 1", len(result.CaughtReverts))
+	}
+	have, err := abi.UnpackRevert(result.CaughtReverts[0])
+	if err != nil {
+		t.Fatalf("failed to unpack caught revert reason: %v", err)
+	}
+	if have != reason {
+		t.Errorf("caught revert reason mismatch: have %q, want %q", have, reason)
+	}
+}
+
+// TestCreateCounts tests that the CREATE and CREATE2 operations executed by a
+// factory contract are counted separately.
+func TestCreateCounts(t *testing.T) {
+	var (
+		factory = common.HexToAddress("0xfac7")
+		// CREATE(0, 0, 0) and CREATE2(0, 0, 0, salt) with salts 1 and 2
+		code = common.FromHex("0x600060006000f050" + "6001600060006000f550" + "6002600060006000f550")
+		msg  = newTransitionTestMessage(0, &factory, new(big.Int), 200000, nil)
+	)
+	statedb := newTransitionTestState(GenesisAlloc{factory: {Code: code, Balance: new(big.Int)}})
+	result, err := applyTransitionTestMessage(params.TestChainConfig, statedb, msg, vm.Config{DetailedResults: true})
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if result.Failed() {
+		t.Fatalf("factory execution failed: %v", result.Err)
+	}
+	if result.CreateCount != 1 {
+		t.Errorf("CREATE count mismatch: have %d, want 1", result.CreateCount)
+	}
+	if result.Create2Count != 2 {
+		t.Errorf("CREATE2 count mismatch: have %d, want 2", result.Create2Count)
+	}
+}
+
+// TestDenylistedRecipients tests that the state transition doesn't reject
+// transactions sent to a denylisted address, which is left to the transaction
+// pool and the miner, and that internal calls to it fail if configured so.
+func TestDenylistedRecipients(t *testing.T) {
+	var (
+		denied = common.HexToAddress("0xdead")
+		caller = common.HexToAddress("0xca11")
+		// CALL(GAS, 0xdead, 0, 0, 0, 0, 0) and store the success flag in slot 0
+		code  = common.FromHex("0x6000600060006000600061dead5af1600055")
+		alloc = GenesisAlloc{caller: {Code: code, Balance: new(big.Int)}}
+		cfg   = vm.Config{DenylistedRecipients: map[common.Address]bool{denied: true}}
+	)
+	// Top level transfers to the denylisted address are applied
+	msg := newTransitionTestMessage(0, &denied, big.NewInt(1), params.TxGas, nil)
+	if _, err := applyTransitionTestMessage(params.TestChainConfig, newTransitionTestState(nil), msg, cfg); err != nil {
+		t.Fatalf("failed to apply denylisted transfer: %v", err)
+	}
+	// Internal calls only fail if explicitly requested
+	for i, deny := range []bool{false, true} {
+		cfg.DenyInternalCalls = deny
+
+		statedb := newTransitionTestState(alloc)
+		msg := newTransitionTestMessage(0, &caller, new(big.Int), 100000, nil)
+		result, err := applyTransitionTestMessage(params.TestChainConfig, statedb, msg, cfg)
+		if err != nil {
+			t.Fatalf("test %d: failed to apply message: %v", i, err)
+		}
+		if result.Failed() {
+			t.Fatalf("test %d: execution failed: %v", i, result.Err)
+		}
+		if success := statedb.GetState(caller, common.Hash{}) != (common.Hash{}); success == deny {
+			t.Errorf("test %d: internal call success mismatch: have %v, want %v", i, success, !deny)
+		}
+	}
+}
+
+// blockHashTracer records the out of range block numbers passed to BLOCKHASH.
+type blockHashTracer struct {
+	*logger.StructLogger
+	numbers []uint64
+}
+
+func (t *blockHashTracer) CaptureBlockHashOutOfRange(number uint64, current uint64) {
+	t.numbers = append(t.numbers, number)
+}
+
+// TestBlockHashOutOfRange tests that querying BLOCKHASH of a block older than
+// the 256 most recent ones is flagged in the result and reported to tracers,
+// while querying a recent block is not.
+func TestBlockHashOutOfRange(t *testing.T) {
+	contract := common.HexToAddress("0xc0de")
+	for i, tt := range []struct {
+		number uint64
+		flag   bool
+	}{
+		{1, true},
+		{299, false},
+	} {
+		var (
+			// PUSH2 <number> BLOCKHASH STOP
+			code    = []byte{byte(vm.PUSH2), byte(tt.number >> 8), byte(tt.number), byte(vm.BLOCKHASH), byte(vm.STOP)}
+			statedb = newTransitionTestState(GenesisAlloc{contract: {Code: code, Balance: new(big.Int)}})
+			tracer  = &blockHashTracer{StructLogger: logger.NewStructLogger(nil)}
+			evm     = newTransitionTestEVM(params.TestChainConfig, statedb, vm.Config{Debug: true, Tracer: tracer, DetailedResults: true})
+			msg     = newTransitionTestMessage(0, &contract, new(big.Int), 100000, nil)
+		)
+		evm.Context.BlockNumber = big.NewInt(300)
+		evm.Reset(NewEVMTxContext(msg), statedb)
+
+		result, err := ApplyMessage(evm, msg, new(GasPool).AddGas(evm.Context.GasLimit))
+		if err != nil {
+			t.Fatalf("test %d: failed to apply message: %v", i, err)
+		}
+		if result.BlockHashOutOfRange != tt.flag {
+			t.Errorf("test %d: out of range flag mismatch: have %v, want %v", i, result.BlockHashOutOfRange, tt.flag)
+		}
+		if tt.flag && (len(tracer.numbers) != 1 || tracer.numbers[0] != tt.number) {
+			t.Errorf("test %d: traced numbers mismatch: have %v, want [%d]", i, tracer.numbers, tt.number)
+		}
+		if !tt.flag && len(tracer.numbers) != 0 {
+			t.Errorf("test %d: unexpected traced numbers: %v", i, tracer.numbers)
+		}
+	}
+}
+
+// stipendTracer records the recipients of calls granted the call stipend.
+type stipendTracer struct {
+	*logger.StructLogger
+	recipients []common.Address
+}
+
+func (t *stipendTracer) CaptureCallStipend(to common.Address, stipend uint64) {
+	t.recipients = append(t.recipients, to)
+}
+
+// TestCallStipend tests that value transferring calls granted the call stipend
+// are counted in the result and reported to tracers.
+func TestCallStipend(t *testing.T) {
+	var (
+		forwarder = common.HexToAddress("0xf0")
+		fallback  = common.HexToAddress("0xbeef")
+		// CALL(0, 0xbeef, 1, 0, 0, 0, 0) STOP, relying on the stipend alone
+		alloc = GenesisAlloc{
+			forwarder: {Code: common.FromHex("0x6000600060006000600161beef6000f100"), Balance: new(big.Int)},
+			fallback:  {Code: []byte{byte(vm.STOP)}, Balance: new(big.Int)},
+		}
+		statedb = newTransitionTestState(alloc)
+		tracer  = &stipendTracer{StructLogger: logger.NewStructLogger(nil)}
+		msg     = newTransitionTestMessage(0, &forwarder, big.NewInt(1), 100000, nil)
+	)
+	result, err := applyTransitionTestMessage(params.TestChainConfig, statedb, msg, vm.Config{Debug: true, Tracer: tracer, DetailedResults: true})
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if result.Failed() {
+		t.Fatalf("execution failed: %v", result.Err)
+	}
+	if result.StipendCalls != 1 {
+		t.Errorf("stipend calls mismatch: have %d, want 1", result.StipendCalls)
+	}
+	if len(tracer.recipients) != 1 || tracer.recipients[0] != fallback {
+		t.Errorf("traced recipients mismatch: have %v, want [%v]", tracer.recipients, fallback)
+	}
+	if balance := statedb.GetBalance(fallback); balance.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("forwarded value mismatch: have %v, want 1", balance)
+	}
+}
+
+// TestBalanceChanges tests that the balance mutations of a transfer are recorded
+// in order: gas purchase, value transfer, gas refund and coinbase payment.
+func TestBalanceChanges(t *testing.T) {
+	var (
+		to  = common.HexToAddress("0x2222")
+		msg = newTransitionTestMessage(0, &to, big.NewInt(1), 50000, nil)
+	)
+	result, err := applyTransitionTestMessage(params.TestChainConfig, newTransitionTestState(nil), msg, vm.Config{RecordBalanceChanges: true})
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	price := msg.GasPrice()
+	want := []BalanceChange{
+		{transitionTestSender, new(big.Int).Neg(new(big.Int).Mul(big.NewInt(50000), price)), BalanceChangeGasPurchase},
+		{transitionTestSender, big.NewInt(-1), BalanceChangeTransfer},
+		{to, big.NewInt(1), BalanceChangeTransfer},
+		{transitionTestSender, new(big.Int).Mul(big.NewInt(int64(50000-params.TxGas)), price), BalanceChangeGasRefund},
+		{transitionTestCoinbase, new(big.Int), BalanceChangeCoinbase},
+	}
+	if len(result.BalanceChanges) != len(want) {
+		t.Fatalf("balance change count mismatch: have %d, want %d", len(result.BalanceChanges), len(want))
+	}
+	for i, have := range result.BalanceChanges {
+		if have.Address != want[i].Address || have.Delta.Cmp(want[i].Delta) != 0 || have.Reason != want[i].Reason {
+			t.Errorf("change %d mismatch: have %x %v %v, want %x %v %v", i, have.Address, have.Delta, have.Reason, want[i].Address, want[i].Delta, want[i].Reason)
+		}
+	}
+}
+
+// TestMaxTxGas tests that transaction gas limits above the EIP-7825 cap are only
+// rejected once Osaka is active.
+func TestMaxTxGas(t *testing.T) {
+	var (
+		osaka = *params.TestChainConfig
+		to    = common.HexToAddress("0x2222")
+	)
+	osaka.OsakaBlock = big.NewInt(0)
+
+	for i, tt := range []struct {
+		config *params.ChainConfig
+		gas    uint64
+		err    error
+	}{
+		{&osaka, params.MaxTxGas, nil},
+		{&osaka, params.MaxTxGas + 1, ErrGasLimitTooHigh},
+		{params.TestChainConfig, params.MaxTxGas + 1, nil},
+	} {
+		msg := newTransitionTestMessage(0, &to, new(big.Int), tt.gas, nil)
+		_, err := applyTransitionTestMessage(tt.config, newTransitionTestState(nil), msg, vm.Config{})
+		if !errors.Is(err, tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// TestApplyMessageWithGasCap tests that messages allocating more gas than the
+// call-site cap are rejected, regardless of the gas left in the block.
+func TestApplyMessageWithGasCap(t *testing.T) {
+	to := common.HexToAddress("0x2222")
+	for i, tt := range []struct {
+		gas, cap uint64
+		err      error
+	}{
+		{50000, 50000, nil},
+		{50001, 50000, ErrGasCapExceeded},
+		{1_000_000, 0, nil},
+	} {
+		statedb := newTransitionTestState(nil)
+		msg := newTransitionTestMessage(0, &to, new(big.Int), tt.gas, nil)
+		evm := newTransitionTestEVM(params.TestChainConfig, statedb, vm.Config{})
+		evm.Reset(NewEVMTxContext(msg), statedb)
+		if _, err := ApplyMessageWithGasCap(evm, msg, new(GasPool).AddGas(evm.Context.GasLimit), tt.cap); !errors.Is(err, tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// TestCheckDeterminism tests that a contract is reported deterministic when its
+// execution is checked twice, and that the probe run leaves no state behind.
+func TestCheckDeterminism(t *testing.T) {
+	var (
+		counter = common.HexToAddress("0xc0c0")
+		// SSTORE(0, SLOAD(0) + 1) MSTORE(0, SLOAD(0)) RETURN(0, 32)
+		code = common.FromHex("0x60016000540160005560005460005260206000f3")
+		msg  = newTransitionTestMessage(0, &counter, new(big.Int), 100000, nil)
+	)
+	statedb := newTransitionTestState(GenesisAlloc{counter: {Code: code, Balance: new(big.Int)}})
+	result, err := applyTransitionTestMessage(params.TestChainConfig, statedb, msg, vm.Config{CheckDeterminism: true})
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if result.Failed() {
+		t.Fatalf("counter execution failed: %v", result.Err)
+	}
+	if !result.Deterministic {
+		t.Errorf("deterministic execution reported as non-deterministic")
+	}
+	if have, want := new(big.Int).SetBytes(result.ReturnData), big.NewInt(1); have.Cmp(want) != 0 {
+		t.Errorf("return value mismatch: have %v, want %v", have, want)
+	}
+	if have := statedb.GetState(counter, common.Hash{}); have != common.BigToHash(big.NewInt(1)) {
+		t.Errorf("counter slot mismatch: have %x, want 1", have)
+	}
+}
+
+// TestBlobTransactions tests that blob transactions are rejected with invalid
+// blob hashes, a blob fee cap below the block's blob gas price or on a block
+// without a blob gas price, and that valid ones pay for their blob gas on top
+// of the execution gas.
+func TestBlobTransactions(t *testing.T) {
+	var (
+		config  = *params.TestChainConfig
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		blobFee = big.NewInt(10)
+		valid   = common.Hash{params.BlobTxHashVersion}
+	)
+	config.CancunBlock = big.NewInt(0)
+	signer := types.LatestSigner(&config)
+
+	for i, tt := range []struct {
+		hashes  []common.Hash
+		feeCap  *big.Int
+		blobFee *big.Int
+		err     error
+	}{
+		{[]common.Hash{valid, valid}, big.NewInt(10), blobFee, nil},
+		{nil, big.NewInt(10), blobFee, ErrMissingBlobHashes},
+		{[]common.Hash{valid, {0x02}}, big.NewInt(10), blobFee, ErrInvalidBlobHashVersion},
+		{[]common.Hash{valid}, big.NewInt(9), blobFee, ErrBlobFeeCapTooLow},
+		{[]common.Hash{valid}, big.NewInt(10), nil, ErrMissingBlobBaseFee},
+	} {
+		tx := types.MustSignNewTx(key, signer, &types.BlobTx{
+			ChainID:    config.ChainID,
+			GasTipCap:  new(big.Int),
+			GasFeeCap:  big.NewInt(params.InitialBaseFee),
+			Gas:        params.TxGas,
+			To:         common.HexToAddress("0x2222"),
+			Value:      new(big.Int),
+			BlobFeeCap: tt.feeCap,
+			BlobHashes: tt.hashes,
+		})
+		msg, err := tx.AsMessage(signer, big.NewInt(params.InitialBaseFee))
+		if err != nil {
+			t.Fatalf("test %d: failed to derive message: %v", i, err)
+		}
+		statedb := newTransitionTestState(nil)
+		evm := newTransitionTestEVM(&config, statedb, vm.Config{DetailedResults: true})
+		evm.Context.BlobBaseFee = tt.blobFee
+		evm.Reset(NewEVMTxContext(msg), statedb)
+
+		result, err := ApplyMessage(evm, msg, new(GasPool).AddGas(evm.Context.GasLimit))
+		if !errors.Is(err, tt.err) {
+			t.Fatalf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if tt.err != nil {
+			continue
+		}
+		if have, want := result.BlobGasUsed, uint64(len(tt.hashes))*params.BlobTxBlobGasPerBlob; have != want {
+			t.Errorf("test %d: blob gas used mismatch: have %d, want %d", i, have, want)
+		}
+		want := new(big.Int).SetUint64(params.Ether - params.TxGas*params.InitialBaseFee - tx.BlobGas()*blobFee.Uint64())
+		if have := statedb.GetBalance(transitionTestSender); have.Cmp(want) != 0 {
+			t.Errorf("test %d: sender balance mismatch: have %v, want %v", i, have, want)
+		}
+	}
+}
+
+// TestInsufficientFundsError tests that failing to cover the gas or the value
+// of a message reports the sender, its balance and the required amount.
+func TestInsufficientFundsError(t *testing.T) {
+	var (
+		to    = common.HexToAddress("0x2222")
+		price = big.NewInt(params.InitialBaseFee)
+		ether = big.NewInt(params.Ether)
+		value = new(big.Int).Mul(ether, big.NewInt(2))
+	)
+	// Buying the gas and the value upfront exceeds the balance
+	statedb := newTransitionTestState(nil)
+	_, err := applyTransitionTestMessage(params.TestChainConfig, statedb, newTransitionTestMessage(0, &to, value, params.TxGas, nil), vm.Config{})
+
+	var fundsErr *InsufficientFundsError
+	if !errors.As(err, &fundsErr) || !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("gas purchase error mismatch: have %v, want %v", err, ErrInsufficientFunds)
+	}
+	want := new(big.Int).Add(value, new(big.Int).Mul(price, new(big.Int).SetUint64(params.TxGas)))
+	if fundsErr.Address != transitionTestSender || fundsErr.Have.Cmp(ether) != 0 || fundsErr.Want.Cmp(want) != 0 {
+		t.Errorf("gas purchase error fields mismatch: have %x %v %v, want %x %v %v", fundsErr.Address, fundsErr.Have, fundsErr.Want, transitionTestSender, ether, want)
+	}
+	// Without fee checks only the value transfer can fail
+	evm := newTransitionTestEVM(params.TestChainConfig, statedb, vm.Config{})
+	msg := types.NewMessage(transitionTestSender, &to, 0, value, params.TxGas, price, price, new(big.Int), nil, nil, true)
+	evm.Reset(NewEVMTxContext(msg), statedb)
+	_, err = ApplyMessageNoFeeCheck(evm, msg, new(GasPool).AddGas(evm.Context.GasLimit))
+
+	if !errors.As(err, &fundsErr) || !errors.Is(err, ErrInsufficientFundsForTransfer) {
+		t.Fatalf("transfer error mismatch: have %v, want %v", err, ErrInsufficientFundsForTransfer)
+	}
+	if fundsErr.Address != transitionTestSender || fundsErr.Have.Cmp(ether) != 0 || fundsErr.Want.Cmp(value) != 0 {
+		t.Errorf("transfer error fields mismatch: have %x %v %v, want %x %v %v", fundsErr.Address, fundsErr.Have, fundsErr.Want, transitionTestSender, ether, value)
+	}
+}
+
+// TestNonceError tests that nonce mismatches report the sender together with
+// the supplied and the expected nonces.
+func TestNonceError(t *testing.T) {
+	to := common.HexToAddress("0x2222")
+	statedb := newTransitionTestState(nil)
+	statedb.SetNonce(transitionTestSender, 2)
+
+	for i, tt := range []struct {
+		nonce uint64
+		err   error
+	}{
+		{5, ErrNonceTooHigh},
+		{1, ErrNonceTooLow},
+	} {
+		_, err := applyTransitionTestMessage(params.TestChainConfig, statedb, newTransitionTestMessage(tt.nonce, &to, new(big.Int), params.TxGas, nil), vm.Config{})
+
+		var nonceErr *NonceError
+		if !errors.As(err, &nonceErr) || !errors.Is(err, tt.err) {
+			t.Fatalf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if nonceErr.Address != transitionTestSender || nonceErr.Have != tt.nonce || nonceErr.Want != 2 {
+			t.Errorf("test %d: error fields mismatch: have %x %d %d, want %x %d %d", i, nonceErr.Address, nonceErr.Have, nonceErr.Want, transitionTestSender, tt.nonce, 2)
+		}
+	}
+}
+
+// TestTxHooks tests that the transition hooks observe the start and the end
+// of a message, its gas consumption and all of its value transfers.
+func TestTxHooks(t *testing.T) {
+	type transfer struct {
+		from, to common.Address
+		amount   uint64
+	}
+	var (
+		contract = common.HexToAddress("0xc0de")
+		// CALL(GAS, 0xdead, 1, 0, 0, 0, 0) POP STOP
+		code  = common.FromHex("0x6000600060006000600161dead5af15000")
+		alloc = GenesisAlloc{contract: {Code: code, Balance: new(big.Int)}}
+
+		starts    int
+		ends      []error
+		gasUsed   uint64
+		consumed  []uint64
+		transfers []transfer
+	)
+	hooks := vm.TxHooks{
+		OnTxStart: func(from common.Address, to *common.Address, gas uint64) {
+			if from != transitionTestSender || to == nil || *to != contract || gas != 100000 {
+				t.Errorf("start hook arguments mismatch: have %x %v %d", from, to, gas)
+			}
+			starts++
+		},
+		OnTxEnd: func(used uint64, err error) {
+			gasUsed = used
+			ends = append(ends, err)
+		},
+		OnGasConsumed: func(gas uint64) {
+			consumed = append(consumed, gas)
+		},
+		OnBalanceTransfer: func(from, to common.Address, amount *big.Int) {
+			transfers = append(transfers, transfer{from, to, amount.Uint64()})
+		},
+	}
+	statedb := newTransitionTestState(alloc)
+	msg := newTransitionTestMessage(0, &contract, big.NewInt(5), 100000, nil)
+	result, err := applyTransitionTestMessage(params.TestChainConfig, statedb, msg, vm.Config{Hooks: hooks})
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if result.Failed() {
+		t.Fatalf("execution failed: %v", result.Err)
+	}
+	if starts != 1 || len(ends) != 1 || ends[0] != nil {
+		t.Fatalf("start and end hooks mismatch: have %d starts, ends %v", starts, ends)
+	}
+	if gasUsed != result.UsedGas {
+		t.Errorf("end hook gas used mismatch: have %d, want %d", gasUsed, result.UsedGas)
+	}
+	if len(consumed) != 2 || consumed[0] != params.TxGas || consumed[0]+consumed[1] != result.UsedGas {
+		t.Errorf("consumed gas mismatch: have %v, want intrinsic %d and execution totalling %d", consumed, params.TxGas, result.UsedGas)
+	}
+	want := []transfer{{transitionTestSender, contract, 5}, {contract, common.HexToAddress("0xdead"), 1}}
+	if !reflect.DeepEqual(transfers, want) {
+		t.Errorf("transfers mismatch: have %v, want %v", transfers, want)
+	}
+	// Rejected messages end with the consensus error
+	msg = newTransitionTestMessage(5, &contract, big.NewInt(5), 100000, nil)
+	if _, err := applyTransitionTestMessage(params.TestChainConfig, statedb, msg, vm.Config{Hooks: hooks}); !errors.Is(err, ErrNonceTooHigh) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrNonceTooHigh)
+	}
+	if len(ends) != 2 || !errors.Is(ends[1], ErrNonceTooHigh) {
+		t.Errorf("end hook error mismatch: have %v, want %v", ends, ErrNonceTooHigh)
+	}
+}
+
+// TestGasPayer tests that a sponsored message has its gas bought and refunded
+// by the payer, while the sender only pays for the transferred value.
+func TestGasPayer(t *testing.T) {
+	var (
+		to    = common.HexToAddress("0x2222")
+		payer = common.HexToAddress("0x3333")
+		price = big.NewInt(params.InitialBaseFee)
+		ether = big.NewInt(params.Ether)
+		value = big.NewInt(1000)
+	)
+	statedb := newTransitionTestState(GenesisAlloc{payer: {Balance: ether}})
+	msg := newTransitionTestMessage(0, &to, value, 50000, nil).WithGasPayer(payer)
+	result, err := applyTransitionTestMessage(params.TestChainConfig, statedb, msg, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if result.UsedGas != params.TxGas {
+		t.Fatalf("gas used mismatch: have %d, want %d", result.UsedGas, params.TxGas)
+	}
+	if have, want := statedb.GetBalance(transitionTestSender), new(big.Int).Sub(ether, value); have.Cmp(want) != 0 {
+		t.Errorf("sender balance mismatch: have %v, want %v", have, want)
+	}
+	fee := new(big.Int).Mul(price, new(big.Int).SetUint64(params.TxGas))
+	if have, want := statedb.GetBalance(payer), new(big.Int).Sub(ether, fee); have.Cmp(want) != 0 {
+		t.Errorf("payer balance mismatch: have %v, want %v", have, want)
+	}
+	// A payer unable to buy the gas rejects the message
+	broke := common.HexToAddress("0x4444")
+	msg = newTransitionTestMessage(1, &to, value, 50000, nil).WithGasPayer(broke)
+	_, err = applyTransitionTestMessage(params.TestChainConfig, statedb, msg, vm.Config{})
+
+	var fundsErr *InsufficientFundsError
+	if !errors.As(err, &fundsErr) || fundsErr.Address != broke {
+		t.Fatalf("error mismatch: have %v, want %v for %x", err, ErrInsufficientFunds, broke)
+	}
+}
+
+// TestInitCodeLimit tests that since Shanghai contract creations pay for every
+// word of init code and are rejected above the EIP-3860 init code size limit.
+func TestInitCodeLimit(t *testing.T) {
+	shanghai := *params.TestChainConfig
+	shanghai.ShanghaiBlock = big.NewInt(0)
+
+	// 33 zero bytes span two words of init code
+	initcode := make([]byte, 33)
+	want := params.TxGasContractCreation + 33*params.TxDataZeroGas + 2*params.InitCodeWordGas
+	if gas, err := IntrinsicGas(initcode, nil, nil, true, true, true, true); err != nil || gas != want {
+		t.Errorf("intrinsic gas mismatch: have %d, %v, want %d", gas, err, want)
+	}
+	want = params.TxGas + 33*params.TxDataZeroGas
+	if gas, err := IntrinsicGas(initcode, nil, nil, false, true, true, true); err != nil || gas != want {
+		t.Errorf("call intrinsic gas mismatch: have %d, %v, want %d", gas, err, want)
+	}
+	// Oversized init code is only rejected once Shanghai is active
+	initcode = make([]byte, params.MaxInitCodeSize+1)
+	for i, config := range []*params.ChainConfig{params.TestChainConfig, &shanghai} {
+		statedb := newTransitionTestState(nil)
+		_, err := applyTransitionTestMessage(config, statedb, newTransitionTestMessage(0, nil, new(big.Int), 1_000_000, initcode), vm.Config{})
+		if i == 0 && err != nil {
+			t.Errorf("pre-Shanghai creation failed: %v", err)
+		}
+		if i == 1 && !errors.Is(err, ErrMaxInitCodeSizeExceeded) {
+			t.Errorf("error mismatch: have %v, want %v", err, ErrMaxInitCodeSizeExceeded)
+		}
+	}
+}
+
+// TestSetCodeTransactions tests that the valid authorizations of a set code
+// transaction delegate the code of their authorities, whose delegated code is
+// then executed in the context of the authority.
+func TestSetCodeTransactions(t *testing.T) {
+	var (
+		config     = *params.TestChainConfig
+		key, _     = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		authKey, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		badKey, _  = crypto.HexToECDSA("49a7b37aa6f6645917e7b807e9d1c00d4fa71f18343b0d4122a4d2df64dd6fee")
+		authority  = crypto.PubkeyToAddress(authKey.PublicKey)
+		stale      = crypto.PubkeyToAddress(badKey.PublicKey)
+		contract   = common.HexToAddress("0xc0de")
+	)
+	config.PragueBlock = big.NewInt(0)
+	signer := types.LatestSigner(&config)
+
+	// PUSH1 1 PUSH1 0 SSTORE STOP
+	statedb := newTransitionTestState(GenesisAlloc{
+		contract:  {Code: common.FromHex("0x600160005500"), Balance: new(big.Int)},
+		authority: {Balance: big.NewInt(1)},
+	})
+	valid, _ := types.SignSetCode(authKey, types.SetCodeAuthorization{ChainID: config.ChainID, Address: contract})
+	mismatch, _ := types.SignSetCode(badKey, types.SetCodeAuthorization{ChainID: config.ChainID, Address: contract, Nonce: 5})
+
+	for i, tt := range []struct {
+		auths []types.SetCodeAuthorization
+		err   error
+	}{
+		{[]types.SetCodeAuthorization{}, ErrEmptyAuthList},
+		{[]types.SetCodeAuthorization{valid, mismatch}, nil},
+	} {
+		tx := types.MustSignNewTx(key, signer, &types.SetCodeTx{
+			ChainID:   config.ChainID,
+			GasTipCap: new(big.Int),
+			GasFeeCap: big.NewInt(params.InitialBaseFee),
+			Gas:       200000,
+			To:        authority,
+			Value:     new(big.Int),
+			AuthList:  tt.auths,
+		})
+		msg, err := tx.AsMessage(signer, big.NewInt(params.InitialBaseFee))
+		if err != nil {
+			t.Fatalf("test %d: failed to derive message: %v", i, err)
+		}
+		result, err := applyTransitionTestMessage(&config, statedb, msg, vm.Config{})
+		if !errors.Is(err, tt.err) {
+			t.Fatalf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if tt.err != nil {
+			continue
+		}
+		if result.Failed() {
+			t.Fatalf("test %d: execution failed: %v", i, result.Err)
+		}
+	}
+	if have, want := statedb.GetCode(authority), types.AddressToDelegation(contract); !bytes.Equal(have, want) {
+		t.Errorf("authority code mismatch: have %x, want %x", have, want)
+	}
+	if nonce := statedb.GetNonce(authority); nonce != 1 {
+		t.Errorf("authority nonce mismatch: have %d, want 1", nonce)
+	}
+	if have := statedb.GetState(authority, common.Hash{}); have != common.BigToHash(common.Big1) {
+		t.Errorf("delegated code did not run in the authority context: slot 0 is %x", have)
+	}
+	if code, nonce := statedb.GetCode(stale), statedb.GetNonce(stale); len(code) != 0 || nonce != 0 {
+		t.Errorf("invalid authorization applied: code %x, nonce %d", code, nonce)
+	}
+}
+
+// TestSelfdestructDelegatedAuthority tests that an account created by its own
+// EIP-7702 authorization is not destroyed when its delegated code executes
+// SELFDESTRUCT in the same transaction, as it is not a new contract (EIP-6780).
+func TestSelfdestructDelegatedAuthority(t *testing.T) {
+	var (
+		config     = *params.TestChainConfig
+		key, _     = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		authKey, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		authority  = crypto.PubkeyToAddress(authKey.PublicKey)
+		contract   = common.HexToAddress("0xc0de")
+	)
+	config.ShanghaiBlock = big.NewInt(0)
+	config.CancunBlock = big.NewInt(0)
+	config.PragueBlock = big.NewInt(0)
+	signer := types.LatestSigner(&config)
+
+	// PUSH1 0 SELFDESTRUCT, the authority does not exist before the transaction
+	statedb := newTransitionTestState(GenesisAlloc{
+		contract: {Code: common.FromHex("0x6000ff"), Balance: new(big.Int)},
+	})
+	auth, _ := types.SignSetCode(authKey, types.SetCodeAuthorization{ChainID: config.ChainID, Address: contract})
+	tx := types.MustSignNewTx(key, signer, &types.SetCodeTx{
+		ChainID:   config.ChainID,
+		GasTipCap: new(big.Int),
+		GasFeeCap: big.NewInt(params.InitialBaseFee),
+		Gas:       200000,
+		To:        authority,
+		Value:     big.NewInt(1),
+		AuthList:  []types.SetCodeAuthorization{auth},
+	})
+	msg, err := tx.AsMessage(signer, big.NewInt(params.InitialBaseFee))
+	if err != nil {
+		t.Fatalf("failed to derive message: %v", err)
+	}
+	result, err := applyTransitionTestMessage(&config, statedb, msg, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to apply message: %v", err)
+	}
+	if result.Failed() {
+		t.Fatalf("execution failed: %v", result.Err)
+	}
+	statedb.Finalise(true)
+	if !statedb.Exist(authority) {
+		t.Fatalf("authority destroyed")
+	}
+	if have, want := statedb.GetCode(authority), types.AddressToDelegation(contract); !bytes.Equal(have, want) {
+		t.Errorf("authority code mismatch: have %x, want %x", have, want)
+	}
+	if nonce := statedb.GetNonce(authority); nonce != 1 {
+		t.Errorf("authority nonce mismatch: have %d, want 1", nonce)
+	}
+}
+
+// testOperatorFee is a vm.OperatorFee charging a fixed price per byte.
+type testOperatorFee struct {
+	vault   common.Address
+	perByte int64
+}
+
+func (f testOperatorFee) Fee(size uint64) *big.Int {
+	return new(big.Int).Mul(new(big.Int).SetUint64(size), big.NewInt(f.perByte))
+}
+
+func (f testOperatorFee) Vault() common.Address { return f.vault }
+
+// TestOperatorFee tests that the operator fee is charged to the sender along
+// with the gas, is not refunded and ends up in the operator's vault.
+func TestOperatorFee(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		signer   = types.LatestSigner(params.TestChainConfig)
+		operator = testOperatorFee{vault: common.HexToAddress("0xfee")}
+	)
+	for i, perByte := range []int64{params.GWei, params.Ether} {
+		operator.perByte = perByte
+
+		tx := types.MustSignNewTx(key, signer, &types.LegacyTx{
+			GasPrice: big.NewInt(params.InitialBaseFee),
+			Gas:      100000,
+			To:       &common.Address{0x22},
+			Value:    new(big.Int),
+		})
+		msg, err := tx.AsMessage(signer, big.NewInt(params.InitialBaseFee))
+		if err != nil {
+			t.Fatalf("test %d: failed to derive message: %v", i, err)
+		}
+		statedb := newTransitionTestState(nil)
+		_, err = applyTransitionTestMessage(params.TestChainConfig, statedb, msg, vm.Config{OperatorFee: operator})
+
+		fee := operator.Fee(uint64(tx.Size()))
+		if perByte == params.Ether {
+			// The fee exceeds the sender's balance, rejecting the transaction
+			if !errors.Is(err, ErrInsufficientFunds) {
+				t.Fatalf("test %d: error mismatch: have %v, want %v", i, err, ErrInsufficientFunds)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test %d: failed to apply message: %v", i, err)
+		}
+		want := new(big.Int).SetUint64(params.Ether - params.TxGas*params.InitialBaseFee)
+		if have := statedb.GetBalance(transitionTestSender); have.Cmp(want.Sub(want, fee)) != 0 {
+			t.Errorf("test %d: sender balance mismatch: have %v, want %v", i, have, want)
+		}
+		if have := statedb.GetBalance(operator.vault); have.Cmp(fee) != 0 {
+			t.Errorf("test %d: vault balance mismatch: have %v, want %v", i, have, fee)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// SystemCall is a call performed by the protocol itself at the start of a block,
+// before any of its transactions, to update a predeployed system contract. It
+// is sent from params.SystemAddress, does not consume any block gas and does
+// not produce a receipt.
+type SystemCall struct {
+	To   common.Address // System contract to call
+	Data []byte         // Input of the call
+}
+
+// SystemCalls returns the system calls to execute, in order, at the start of
+// the block with the given header.
+func SystemCalls(config *params.ChainConfig, header *types.Header) []SystemCall {
+	var calls []SystemCall
+
+	// EIP-4788: store the parent beacon block root
+	if config.IsCancun(header.Number) && header.ParentBeaconRoot != nil {
+		calls = append(calls, SystemCall{To: params.BeaconRootsAddress, Data: header.ParentBeaconRoot.Bytes()})
+	}
+	// EIP-2935: store the parent block hash
+	if config.IsPrague(header.Number) && header.Number.Sign() > 0 {
+		calls = append(calls, SystemCall{To: params.HistoryStorageAddress, Data: header.ParentHash.Bytes()})
+	}
+	return calls
+}
+
+// ApplySystemCalls executes the system calls of the block with the given header
+// on top of statedb. The calls are not traced, as they are not part of any
+// transaction. As mandated by the EIPs introducing them, a failing system call
+// (e.g. if the system contract is not deployed) is ignored.
+func ApplySystemCalls(config *params.ChainConfig, header *types.Header, blockContext vm.BlockContext, statedb *state.StateDB, cfg vm.Config) {
+	calls := SystemCalls(config, header)
+	if len(calls) == 0 {
+		return
+	}
+	cfg.Debug, cfg.Tracer, cfg.Hooks = false, nil, vm.TxHooks{}
+
+	txContext := vm.TxContext{Origin: params.SystemAddress, GasPrice: new(big.Int)}
+	evm := vm.NewEVM(blockContext, txContext, statedb, config, cfg)
+	for _, call := range calls {
+		statedb.AddAddressToAccessList(call.To)
+		evm.Call(vm.AccountRef(params.SystemAddress), call.To, call.Data, params.SystemCallGas, common.Big0)
+	}
+	// Finalise once after all calls, so the empty system address touched by
+	// each of them is cleaned up
+	statedb.Finalise(true)
+}
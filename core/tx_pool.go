@@ -18,6 +18,7 @@ package core
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"math/big"
 	"sort"
@@ -165,6 +166,8 @@ type TxPoolConfig struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	DenylistedRecipients map[common.Address]bool `toml:",omitempty"` // Recipients whose transactions are rejected by the pool
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -240,9 +243,12 @@ type TxPool struct {
 	signer      types.Signer
 	mu          sync.RWMutex
 
-	istanbul bool // Fork indicator whether we are in the istanbul stage.
+	shanghai bool // Fork indicator whether we are in the shanghai stage.
 	eip2718  bool // Fork indicator whether we are using EIP-2718 type transactions.
 	eip1559  bool // Fork indicator whether we are using EIP-1559 type transactions.
+	prague   bool // Fork indicator whether we are using EIP-7702 type transactions.
+
+	gasTable params.GasTable // Intrinsic gas prices of the next block
 
 	currentState  *state.StateDB // Current state in the blockchain head
 	pendingNonces *txNoncer      // Pending state tracking virtual nonces
@@ -592,10 +598,26 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if !pool.eip1559 && tx.Type() == types.DynamicFeeTxType {
 		return ErrTxTypeNotSupported
 	}
+	// Reject set code transactions until EIP-7702 activates.
+	if !pool.prague && tx.Type() == types.SetCodeTxType {
+		return ErrTxTypeNotSupported
+	}
+	// Reject blob transactions, the pool does not track their blob sidecars.
+	if tx.Type() == types.BlobTxType {
+		return ErrTxTypeNotSupported
+	}
 	// Reject transactions over defined size to prevent DOS attacks
 	if uint64(tx.Size()) > txMaxSize {
 		return ErrOversizedData
 	}
+	// Check whether the init code size has been exceeded.
+	if limit := pool.chainconfig.InitCodeSizeLimit(); pool.shanghai && tx.To() == nil && len(tx.Data()) > limit {
+		return fmt.Errorf("%w: code size %v limit %v", ErrMaxInitCodeSizeExceeded, len(tx.Data()), limit)
+	}
+	// Reject transactions sent to recipients denylisted by the node
+	if to := tx.To(); to != nil && pool.config.DenylistedRecipients[*to] {
+		return fmt.Errorf("%w: address %v", ErrRecipientDenied, to.Hex())
+	}
 	// Transactions can't be negative. This may never happen using RLP decoded
 	// transactions but may occur if you create a transaction using the RPC.
 	if tx.Value().Sign() < 0 {
@@ -635,7 +657,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		return ErrInsufficientFunds
 	}
 	// Ensure the transaction has more gas than the basic tx fee.
-	intrGas, err := IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, true, pool.istanbul)
+	intrGas, err := IntrinsicGasWithTable(pool.gasTable, tx.Data(), tx.AccessList(), tx.SetCodeAuthorizations(), tx.To() == nil, pool.shanghai)
 	if err != nil {
 		return err
 	}
@@ -1301,9 +1323,11 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 
 	// Update all fork indicator by next pending block number.
 	next := new(big.Int).Add(newHead.Number, big.NewInt(1))
-	pool.istanbul = pool.chainconfig.IsIstanbul(next)
+	pool.shanghai = pool.chainconfig.IsShanghai(next)
+	pool.gasTable = pool.chainconfig.IntrinsicGasTable(next)
 	pool.eip2718 = pool.chainconfig.IsBerlin(next)
 	pool.eip1559 = pool.chainconfig.IsLondon(next)
+	pool.prague = pool.chainconfig.IsPrague(next)
 }
 
 // promoteExecutables moves transactions that have become processable from the
@@ -380,6 +380,23 @@ func TestTransactionNegativeValue(t *testing.T) {
 	}
 }
 
+func TestTransactionDenylistedRecipient(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	denied := common.HexToAddress("0xdead")
+	pool.config.DenylistedRecipients = map[common.Address]bool{denied: true}
+
+	tx, _ := types.SignTx(types.NewTransaction(0, denied, big.NewInt(1), params.TxGas, big.NewInt(1), nil), types.HomesteadSigner{}, key)
+	from, _ := deriveSender(tx)
+	testAddBalance(pool, from, big.NewInt(params.Ether))
+	if err := pool.AddRemote(tx); !errors.Is(err, ErrRecipientDenied) {
+		t.Error("expected", ErrRecipientDenied, "got", err)
+	}
+}
+
 func TestTransactionTipAboveFeeCap(t *testing.T) {
 	t.Parallel()
 
@@ -413,6 +430,58 @@ func TestTransactionVeryHighValues(t *testing.T) {
 	}
 }
 
+func TestTransactionBlobRejected(t *testing.T) {
+	t.Parallel()
+
+	config := *eip1559Config
+	config.CancunBlock = common.Big0
+	pool, key := setupTxPoolWithConfig(&config)
+	defer pool.Stop()
+
+	tx := types.MustSignNewTx(key, types.LatestSigner(&config), &types.BlobTx{
+		ChainID:    config.ChainID,
+		GasTipCap:  big.NewInt(1),
+		GasFeeCap:  big.NewInt(1),
+		Gas:        100000,
+		BlobFeeCap: big.NewInt(1),
+		BlobHashes: []common.Hash{{params.BlobTxHashVersion}},
+	})
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(params.Ether))
+	if err := pool.AddRemote(tx); err != ErrTxTypeNotSupported {
+		t.Error("expected", ErrTxTypeNotSupported, "got", err)
+	}
+}
+
+func TestTransactionSetCodeBeforePrague(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		prague *big.Int
+		err    error
+	}{
+		{big.NewInt(100), ErrTxTypeNotSupported},
+		{common.Big0, nil},
+	} {
+		config := *eip1559Config
+		config.PragueBlock = tt.prague
+		pool, key := setupTxPoolWithConfig(&config)
+
+		auth, _ := types.SignSetCode(key, types.SetCodeAuthorization{ChainID: config.ChainID, Address: common.Address{0x01}})
+		tx := types.MustSignNewTx(key, types.LatestSigner(&config), &types.SetCodeTx{
+			ChainID:   config.ChainID,
+			GasTipCap: big.NewInt(1),
+			GasFeeCap: big.NewInt(1),
+			Gas:       100000,
+			AuthList:  []types.SetCodeAuthorization{auth},
+		})
+		testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(params.Ether))
+		if err := pool.AddRemote(tx); err != tt.err {
+			t.Error("prague", tt.prague, "expected", tt.err, "got", err)
+		}
+		pool.Stop()
+	}
+}
+
 func TestTransactionChainFork(t *testing.T) {
 	t.Parallel()
 
@@ -194,6 +194,45 @@ func TestEIP2718BlockEncoding(t *testing.T) {
 	}
 }
 
+func TestWithdrawalsBlockEncoding(t *testing.T) {
+	header := &Header{
+		Difficulty: common.Big0,
+		Number:     big.NewInt(1),
+		GasLimit:   30_000_000,
+		BaseFee:    big.NewInt(params.InitialBaseFee),
+	}
+	withdrawals := []*Withdrawal{
+		{Index: 0, Validator: 7, Address: common.HexToAddress("0x1111"), Amount: 32},
+		{Index: 1, Validator: 8, Address: common.HexToAddress("0x2222"), Amount: 1},
+	}
+	block := NewBlockWithWithdrawals(header, nil, nil, nil, withdrawals, newHasher())
+
+	enc, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		t.Fatal("encode error: ", err)
+	}
+	var decoded Block
+	if err := rlp.DecodeBytes(enc, &decoded); err != nil {
+		t.Fatal("decode error: ", err)
+	}
+	if decoded.Hash() != block.Hash() {
+		t.Errorf("hash mismatch: got %x, want %x", decoded.Hash(), block.Hash())
+	}
+	if h := decoded.Header().WithdrawalsHash; h == nil || *h != DeriveSha(Withdrawals(withdrawals), newHasher()) {
+		t.Errorf("withdrawals hash mismatch: got %v", h)
+	}
+	if !reflect.DeepEqual(decoded.Withdrawals(), Withdrawals(withdrawals)) {
+		t.Errorf("withdrawals mismatch: got %v, want %v", decoded.Withdrawals(), withdrawals)
+	}
+	// Blocks without withdrawals must not gain a withdrawals hash
+	if h := NewBlockWithWithdrawals(header, nil, nil, nil, nil, newHasher()).Header().WithdrawalsHash; h != nil {
+		t.Errorf("unexpected withdrawals hash for nil withdrawals: %x", *h)
+	}
+	if h := NewBlockWithWithdrawals(header, nil, nil, nil, []*Withdrawal{}, newHasher()).Header().WithdrawalsHash; h == nil || *h != EmptyRootHash {
+		t.Errorf("empty withdrawals hash mismatch: got %v", h)
+	}
+}
+
 func TestUncleHash(t *testing.T) {
 	uncles := make([]*Header, 0)
 	h := CalcUncleHash(uncles)
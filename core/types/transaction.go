@@ -45,6 +45,8 @@ const (
 	LegacyTxType = iota
 	AccessListTxType
 	DynamicFeeTxType
+	BlobTxType
+	SetCodeTxType
 )
 
 // Transaction is an Ethereum transaction.
@@ -184,6 +186,14 @@ func (tx *Transaction) decodeTyped(b []byte) (TxData, error) {
 		var inner DynamicFeeTx
 		err := rlp.DecodeBytes(b[1:], &inner)
 		return &inner, err
+	case BlobTxType:
+		var inner BlobTx
+		err := rlp.DecodeBytes(b[1:], &inner)
+		return &inner, err
+	case SetCodeTxType:
+		var inner SetCodeTx
+		err := rlp.DecodeBytes(b[1:], &inner)
+		return &inner, err
 	default:
 		return nil, ErrTxTypeNotSupported
 	}
@@ -285,9 +295,49 @@ func (tx *Transaction) To() *common.Address {
 	return copyAddressPtr(tx.inner.to())
 }
 
-// Cost returns gas * gasPrice + value.
+// BlobGas returns the blob gas limit of the transaction for blob transactions,
+// 0 otherwise.
+func (tx *Transaction) BlobGas() uint64 {
+	if blobtx, ok := tx.inner.(*BlobTx); ok {
+		return blobtx.blobGas()
+	}
+	return 0
+}
+
+// BlobGasFeeCap returns the blob gas fee cap per blob gas of the transaction for
+// blob transactions, nil otherwise.
+func (tx *Transaction) BlobGasFeeCap() *big.Int {
+	if blobtx, ok := tx.inner.(*BlobTx); ok {
+		return new(big.Int).Set(blobtx.BlobFeeCap)
+	}
+	return nil
+}
+
+// BlobHashes returns the versioned hashes of the blobs of the transaction for
+// blob transactions, nil otherwise.
+func (tx *Transaction) BlobHashes() []common.Hash {
+	if blobtx, ok := tx.inner.(*BlobTx); ok {
+		return blobtx.BlobHashes
+	}
+	return nil
+}
+
+// SetCodeAuthorizations returns the authorizations list of the transaction for
+// set code transactions, nil otherwise.
+func (tx *Transaction) SetCodeAuthorizations() []SetCodeAuthorization {
+	if setcodetx, ok := tx.inner.(*SetCodeTx); ok {
+		return setcodetx.AuthList
+	}
+	return nil
+}
+
+// Cost returns gas * gasPrice + value, plus blobGas * blobGasFeeCap for blob
+// transactions.
 func (tx *Transaction) Cost() *big.Int {
 	total := new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(tx.Gas()))
+	if tx.Type() == BlobTxType {
+		total.Add(total, new(big.Int).Mul(tx.BlobGasFeeCap(), new(big.Int).SetUint64(tx.BlobGas())))
+	}
 	total.Add(total, tx.Value())
 	return total
 }
@@ -590,6 +640,14 @@ type Message struct {
 	data       []byte
 	accessList AccessList
 	isFake     bool
+
+	blobGasFeeCap *big.Int
+	blobHashes    []common.Hash
+
+	authList []SetCodeAuthorization
+
+	gasPayer *common.Address
+	size     uint64
 }
 
 func NewMessage(from common.Address, to *common.Address, nonce uint64, amount *big.Int, gasLimit uint64, gasPrice, gasFeeCap, gasTipCap *big.Int, data []byte, accessList AccessList, isFake bool) Message {
@@ -621,6 +679,12 @@ func (tx *Transaction) AsMessage(s Signer, baseFee *big.Int) (Message, error) {
 		data:       tx.Data(),
 		accessList: tx.AccessList(),
 		isFake:     false,
+
+		blobGasFeeCap: tx.BlobGasFeeCap(),
+		blobHashes:    tx.BlobHashes(),
+
+		authList: tx.SetCodeAuthorizations(),
+		size:     uint64(tx.Size()),
 	}
 	// If baseFee provided, set gasPrice to effectiveGasPrice.
 	if baseFee != nil {
@@ -643,6 +707,24 @@ func (m Message) Data() []byte           { return m.data }
 func (m Message) AccessList() AccessList { return m.accessList }
 func (m Message) IsFake() bool           { return m.isFake }
 
+func (m Message) BlobGasFeeCap() *big.Int   { return m.blobGasFeeCap }
+func (m Message) BlobHashes() []common.Hash { return m.blobHashes }
+func (m Message) GasPayer() *common.Address { return m.gasPayer }
+
+func (m Message) SetCodeAuthorizations() []SetCodeAuthorization { return m.authList }
+
+// Size returns the encoded size of the transaction the message was derived
+// from, or zero for messages not originating from a transaction.
+func (m Message) Size() uint64 { return m.size }
+
+// WithGasPayer returns a copy of the message whose gas is bought by payer
+// instead of the sender, which also receives the refund of the unused gas.
+// It is meant for sponsored transaction experiments on private chains.
+func (m Message) WithGasPayer(payer common.Address) Message {
+	m.gasPayer = &payer
+	return m
+}
+
 // copyAddressPtr copies an address.
 func copyAddressPtr(a *common.Address) *common.Address {
 	if a == nil {
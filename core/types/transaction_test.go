@@ -29,6 +29,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -492,6 +493,136 @@ func TestTransactionCoding(t *testing.T) {
 	}
 }
 
+// TestBlobTransactionCoding tests that blob transactions survive the binary and
+// JSON encodings and that their sender is recovered by the Cancun signer.
+func TestBlobTransactionCoding(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	var (
+		signer = NewCancunSigner(common.Big1)
+		hashes = []common.Hash{{0x01, 0xaa}, {0x01, 0xbb}}
+	)
+	tx, err := SignNewTx(key, signer, &BlobTx{
+		ChainID:    big.NewInt(1),
+		Nonce:      1,
+		GasTipCap:  big.NewInt(1),
+		GasFeeCap:  big.NewInt(10),
+		Gas:        123457,
+		To:         common.HexToAddress("095e7baea6a6c7c4c2dfeb977efac326af552d87"),
+		Value:      big.NewInt(5),
+		Data:       []byte("abcdef"),
+		BlobFeeCap: big.NewInt(7),
+		BlobHashes: hashes,
+	})
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	for _, codec := range []func(*Transaction) (*Transaction, error){encodeDecodeBinary, encodeDecodeJSON} {
+		parsedTx, err := codec(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := assertEqual(parsedTx, tx); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(parsedTx.BlobHashes(), hashes) {
+			t.Errorf("blob hashes mismatch: have %x, want %x", parsedTx.BlobHashes(), hashes)
+		}
+		if parsedTx.BlobGasFeeCap().Cmp(big.NewInt(7)) != 0 {
+			t.Errorf("blob fee cap mismatch: have %v, want 7", parsedTx.BlobGasFeeCap())
+		}
+		from, err := Sender(signer, parsedTx)
+		if err != nil {
+			t.Fatalf("could not recover sender: %v", err)
+		}
+		if want := crypto.PubkeyToAddress(key.PublicKey); from != want {
+			t.Errorf("sender mismatch: have %x, want %x", from, want)
+		}
+	}
+	if have, want := tx.BlobGas(), 2*uint64(params.BlobTxBlobGasPerBlob); have != want {
+		t.Errorf("blob gas mismatch: have %d, want %d", have, want)
+	}
+	if _, err := Sender(NewLondonSigner(common.Big1), tx); err != ErrTxTypeNotSupported {
+		t.Errorf("london signer error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+}
+
+// TestSetCodeTransactionCoding tests that set code transactions and their
+// authorizations survive the binary and JSON codecs, and that the authority
+// of an authorization is recovered from its signature.
+func TestSetCodeTransactionCoding(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	authKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	auth, err := SignSetCode(authKey, SetCodeAuthorization{
+		ChainID: big.NewInt(1),
+		Address: common.HexToAddress("0xc0de"),
+		Nonce:   3,
+	})
+	if err != nil {
+		t.Fatalf("could not sign authorization: %v", err)
+	}
+	signer := NewPragueSigner(common.Big1)
+	tx, err := SignNewTx(key, signer, &SetCodeTx{
+		ChainID:   big.NewInt(1),
+		Nonce:     1,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(10),
+		Gas:       123457,
+		To:        common.HexToAddress("095e7baea6a6c7c4c2dfeb977efac326af552d87"),
+		Value:     big.NewInt(5),
+		Data:      []byte("abcdef"),
+		AuthList:  []SetCodeAuthorization{auth},
+	})
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	for _, codec := range []func(*Transaction) (*Transaction, error){encodeDecodeBinary, encodeDecodeJSON} {
+		parsedTx, err := codec(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := assertEqual(parsedTx, tx); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(parsedTx.SetCodeAuthorizations(), []SetCodeAuthorization{auth}) {
+			t.Errorf("authorizations mismatch: have %v, want %v", parsedTx.SetCodeAuthorizations(), auth)
+		}
+		from, err := Sender(signer, parsedTx)
+		if err != nil {
+			t.Fatalf("could not recover sender: %v", err)
+		}
+		if want := crypto.PubkeyToAddress(key.PublicKey); from != want {
+			t.Errorf("sender mismatch: have %x, want %x", from, want)
+		}
+		authority, err := parsedTx.SetCodeAuthorizations()[0].Authority()
+		if err != nil {
+			t.Fatalf("could not recover authority: %v", err)
+		}
+		if want := crypto.PubkeyToAddress(authKey.PublicKey); authority != want {
+			t.Errorf("authority mismatch: have %x, want %x", authority, want)
+		}
+	}
+	if _, err := Sender(NewCancunSigner(common.Big1), tx); err != ErrTxTypeNotSupported {
+		t.Errorf("cancun signer error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+	// Delegation designators round trip through the code prefix
+	code := AddressToDelegation(auth.Address)
+	if target, ok := ParseDelegation(code); !ok || target != auth.Address {
+		t.Errorf("delegation mismatch: have %x %v, want %x", target, ok, auth.Address)
+	}
+	if _, ok := ParseDelegation(append(code, 0x00)); ok {
+		t.Errorf("oversized delegation parsed")
+	}
+}
+
 func encodeDecodeJSON(tx *Transaction) (*Transaction, error) {
 	data, err := json.Marshal(tx)
 	if err != nil {
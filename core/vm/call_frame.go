@@ -0,0 +1,86 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// CallFrame describes a frame of the call stack executing code.
+type CallFrame struct {
+	Depth       int            // Depth of the frame, 1 for the outermost one
+	Caller      common.Address // Caller of the frame (the caller's caller for DELEGATECALL)
+	Address     common.Address // Account the frame operates on
+	CodeAddress common.Address // Account whose code is executed
+	Value       *big.Int       // Value passed to the frame
+	Gas         uint64         // Gas remaining in the frame
+	ReadOnly    bool           // Whether state modifications are forbidden
+}
+
+// frame is a call frame on the stack of the interpreter.
+type frame struct {
+	contract *Contract
+	readOnly bool
+}
+
+// Depth returns the current call depth, 0 if no code is executing.
+func (evm *EVM) Depth() int {
+	return evm.depth
+}
+
+// CallFrames returns the frames of the call stack executing code, from the
+// outermost to the innermost one. Calls to precompiles and accounts without
+// code do not execute any and thus have no frame.
+func (evm *EVM) CallFrames() []CallFrame {
+	frames := make([]CallFrame, len(evm.frames))
+	for i, f := range evm.frames {
+		frames[i] = f.callFrame(i + 1)
+	}
+	return frames
+}
+
+// CurrentFrame returns the innermost frame of the call stack, or false if no
+// code is executing.
+func (evm *EVM) CurrentFrame() (CallFrame, bool) {
+	if len(evm.frames) == 0 {
+		return CallFrame{}, false
+	}
+	return evm.frames[len(evm.frames)-1].callFrame(len(evm.frames)), true
+}
+
+func (f *frame) callFrame(depth int) CallFrame {
+	c := f.contract
+	codeAddr := c.Address()
+	if c.CodeAddr != nil {
+		codeAddr = *c.CodeAddr
+	}
+	value := new(big.Int)
+	if c.Value() != nil {
+		value.Set(c.Value())
+	}
+	return CallFrame{
+		Depth:       depth,
+		Caller:      c.Caller(),
+		Address:     c.Address(),
+		CodeAddress: codeAddr,
+		Value:       value,
+		Gas:         c.Gas,
+		ReadOnly:    f.readOnly,
+	}
+}
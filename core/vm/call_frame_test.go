@@ -0,0 +1,100 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// frameTracer records the call frames of the EVM at the first opcode executed
+// at the given depth.
+type frameTracer struct {
+	env    *EVM
+	depth  int
+	frames []CallFrame
+}
+
+func (t *frameTracer) CaptureTxStart(gasLimit uint64) {}
+func (t *frameTracer) CaptureTxEnd(restGas uint64)    {}
+func (t *frameTracer) CaptureStart(env *EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+}
+func (t *frameTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) {}
+func (t *frameTracer) CaptureEnter(typ OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+func (t *frameTracer) CaptureExit(output []byte, gasUsed uint64, err error) {}
+func (t *frameTracer) CaptureState(pc uint64, op OpCode, gas, cost uint64, scope *ScopeContext, rData []byte, depth int, err error) {
+	if depth == t.depth && t.frames == nil {
+		t.frames = t.env.CallFrames()
+	}
+}
+func (t *frameTracer) CaptureFault(pc uint64, op OpCode, gas, cost uint64, scope *ScopeContext, depth int, err error) {
+}
+
+func TestCallFrames(t *testing.T) {
+	var (
+		outer = common.BytesToAddress([]byte{0xaa})
+		inner = common.BytesToAddress([]byte{0xbb})
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	// STATICCALL(inner) with all the gas
+	statedb.SetCode(outer, []byte{
+		byte(PUSH1), 0, byte(DUP1), byte(DUP1), byte(DUP1),
+		byte(PUSH1), 0xbb, byte(GAS), byte(STATICCALL), byte(STOP),
+	})
+	statedb.SetCode(inner, []byte{byte(STOP)})
+
+	vmctx := BlockContext{
+		BlockNumber: big.NewInt(0),
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+	}
+	tracer := &frameTracer{depth: 2}
+	evm := NewEVM(vmctx, TxContext{}, statedb, params.AllEthashProtocolChanges, Config{Debug: true, Tracer: tracer})
+	tracer.env = evm
+
+	if _, ok := evm.CurrentFrame(); ok {
+		t.Fatal("frame reported before execution")
+	}
+	if _, _, err := evm.Call(AccountRef(common.Address{0x01}), outer, nil, 100000, new(big.Int)); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if len(tracer.frames) != 2 {
+		t.Fatalf("frame count mismatch: have %d, want 2", len(tracer.frames))
+	}
+	top, sub := tracer.frames[0], tracer.frames[1]
+	if top.Depth != 1 || top.Caller != (common.Address{0x01}) || top.Address != outer || top.ReadOnly {
+		t.Errorf("outer frame mismatch: %+v", top)
+	}
+	if sub.Depth != 2 || sub.Caller != outer || sub.Address != inner || sub.CodeAddress != inner || !sub.ReadOnly {
+		t.Errorf("inner frame mismatch: %+v", sub)
+	}
+	// The outer frame forwarded all but 1/64th of its gas to the inner one
+	if sub.Gas == 0 || top.Gas >= sub.Gas {
+		t.Errorf("unexpected frame gas: outer %d, inner %d", top.Gas, sub.Gas)
+	}
+	if _, ok := evm.CurrentFrame(); ok || evm.Depth() != 0 {
+		t.Fatal("frames left after execution")
+	}
+}
@@ -53,10 +53,11 @@ type Contract struct {
 	jumpdests map[common.Hash]bitvec // Aggregated result of JUMPDEST analysis.
 	analysis  bitvec                 // Locally cached result of JUMPDEST analysis
 
-	Code     []byte
-	CodeHash common.Hash
-	CodeAddr *common.Address
-	Input    []byte
+	Code      []byte
+	container []byte // EOF container holding Code, if any
+	CodeHash  common.Hash
+	CodeAddr  *common.Address
+	Input     []byte
 
 	Gas   uint64
 	value *big.Int
@@ -192,3 +193,12 @@ func (c *Contract) SetCodeOptionalHash(addr *common.Address, codeAndHash *codeAn
 	c.CodeHash = codeAndHash.hash
 	c.CodeAddr = addr
 }
+
+// fullCode returns the code of the contract as deployed, i.e. the whole EOF
+// container rather than its code section for EOF contracts.
+func (c *Contract) fullCode() []byte {
+	if c.container != nil {
+		return c.container
+	}
+	return c.Code
+}
@@ -18,13 +18,23 @@ package vm
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 // precompiledTest defines the input/output pairs for precompiled contract tests.
@@ -322,6 +332,178 @@ func BenchmarkPrecompiledBLS12381Pairing(b *testing.B)    { benchJson("blsPairin
 func BenchmarkPrecompiledBLS12381MapG1(b *testing.B)      { benchJson("blsMapG1", "11", b) }
 func BenchmarkPrecompiledBLS12381MapG2(b *testing.B)      { benchJson("blsMapG2", "12", b) }
 
+// Tests that the BLS12-381 precompiles are only active from Prague on.
+func TestPrecompiledBLS12381Activation(t *testing.T) {
+	config := *params.TestChainConfig
+	config.CancunBlock = big.NewInt(5)
+	config.PragueBlock = big.NewInt(10)
+
+	for _, tt := range []struct {
+		number int64
+		kzg    bool
+		bls    bool
+	}{{4, false, false}, {9, true, false}, {10, true, true}} {
+		evm := NewEVM(BlockContext{BlockNumber: big.NewInt(tt.number)}, TxContext{}, nil, &config, Config{})
+		if p, ok := evm.precompile(common.BytesToAddress([]byte{10})); ok != tt.kzg {
+			t.Errorf("block %d: point evaluation activation mismatch: have %v, want %v", tt.number, ok, tt.kzg)
+		} else if ok {
+			if _, isKZG := p.(*kzgPointEvaluation); !isKZG {
+				t.Errorf("block %d: unexpected precompile at 0x0a: %T", tt.number, p)
+			}
+		}
+		for i := byte(11); i <= 19; i++ {
+			if _, ok := evm.precompile(common.BytesToAddress([]byte{i})); ok != tt.bls {
+				t.Errorf("block %d: precompile %x activation mismatch: have %v, want %v", tt.number, i, ok, tt.bls)
+			}
+		}
+		want := len(PrecompiledContractsBerlin)
+		if tt.kzg {
+			want++
+		}
+		if tt.bls {
+			want += len(PrecompiledContractsBLS)
+		}
+		if have := len(ActivePrecompiles(config.Rules(big.NewInt(tt.number), false))); have != want {
+			t.Errorf("block %d: active precompile count mismatch: have %d, want %d", tt.number, have, want)
+		}
+	}
+}
+
+func TestPrecompiledPointEvaluation(t *testing.T) {
+	// Install an insecure trusted setup with τ = 2 and commit to p(x) = 3 + x,
+	// which evaluates to 8 at z = 5 with a constant quotient of 1.
+	g1, g2 := bls12381.NewG1(), bls12381.NewG2()
+	tau := g2.ToCompressed(g2.MulScalar(g2.New(), g2.One(), big.NewInt(2)))
+	setup := fmt.Sprintf(`{"g2_monomial": ["0x%x", "0x%x"]}`, g2.ToCompressed(g2.One()), tau)
+	if err := kzg4844.LoadTrustedSetup(strings.NewReader(setup)); err != nil {
+		t.Fatalf("failed to load trusted setup: %v", err)
+	}
+	var commitment kzg4844.Commitment
+	copy(commitment[:], g1.ToCompressed(g1.MulScalar(g1.New(), g1.One(), big.NewInt(5))))
+	versionedHash := kzg4844.CalcBlobHashV1(sha256.New(), &commitment)
+
+	input := make([]byte, 0, 192)
+	input = append(input, versionedHash[:]...)
+	input = append(input, common.LeftPadBytes([]byte{5}, 32)...)
+	input = append(input, common.LeftPadBytes([]byte{8}, 32)...)
+	input = append(input, commitment[:]...)
+	input = append(input, g1.ToCompressed(g1.One())...)
+
+	p := &kzgPointEvaluation{}
+	if gas := p.RequiredGas(input); gas != params.BlobTxPointEvaluationPrecompileGas {
+		t.Fatalf("gas mismatch: have %d, want %d", gas, params.BlobTxPointEvaluationPrecompileGas)
+	}
+	out, err := p.Run(input)
+	if err != nil {
+		t.Fatalf("valid point evaluation failed: %v", err)
+	}
+	if !bytes.Equal(out, blobPrecompileReturnValue) {
+		t.Fatalf("output mismatch: have %x, want %x", out, blobPrecompileReturnValue)
+	}
+	// Malformed inputs must be rejected
+	if _, err := p.Run(input[:191]); err != errBlobVerifyInvalidInputLength {
+		t.Errorf("short input: have %v, want %v", err, errBlobVerifyInvalidInputLength)
+	}
+	bad := common.CopyBytes(input)
+	bad[0] = 0x02
+	if _, err := p.Run(bad); err != errBlobVerifyMismatchedVersion {
+		t.Errorf("bad version: have %v, want %v", err, errBlobVerifyMismatchedVersion)
+	}
+	bad = common.CopyBytes(input)
+	bad[95] = 9
+	if _, err := p.Run(bad); !errors.Is(err, errBlobVerifyKZGProof) {
+		t.Errorf("bad claim: have %v, want %v", err, errBlobVerifyKZGProof)
+	}
+}
+
+func TestPrecompiledP256Verify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	hash := sha256.Sum256([]byte("p256"))
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	input := make([]byte, 160)
+	copy(input, hash[:])
+	r.FillBytes(input[32:64])
+	s.FillBytes(input[64:96])
+	key.X.FillBytes(input[96:128])
+	key.Y.FillBytes(input[128:160])
+
+	p := &p256Verify{}
+	if out, err := p.Run(input); err != nil || !bytes.Equal(out, true32Byte) {
+		t.Fatalf("valid signature: have %x (%v), want %x", out, err, true32Byte)
+	}
+	bad := common.CopyBytes(input)
+	bad[0] ^= 1
+	if out, err := p.Run(bad); err != nil || len(out) != 0 {
+		t.Fatalf("invalid signature: have %x (%v), want empty", out, err)
+	}
+	if out, err := p.Run(input[:159]); err != nil || len(out) != 0 {
+		t.Fatalf("short input: have %x (%v), want empty", out, err)
+	}
+}
+
+func TestPrecompiledP256VerifyActivation(t *testing.T) {
+	config := *params.TestChainConfig
+	config.P256VerifyBlock = big.NewInt(10)
+
+	for _, tt := range []struct {
+		number int64
+		active bool
+	}{{9, false}, {10, true}} {
+		evm := NewEVM(BlockContext{BlockNumber: big.NewInt(tt.number)}, TxContext{}, nil, &config, Config{})
+		if _, ok := evm.precompile(p256VerifyAddress); ok != tt.active {
+			t.Errorf("block %d: activation mismatch: have %v, want %v", tt.number, ok, tt.active)
+		}
+		var found bool
+		for _, addr := range ActivePrecompiles(config.Rules(big.NewInt(tt.number), false)) {
+			found = found || addr == p256VerifyAddress
+		}
+		if found != tt.active {
+			t.Errorf("block %d: active precompile mismatch: have %v, want %v", tt.number, found, tt.active)
+		}
+	}
+}
+
+func TestCustomPrecompiles(t *testing.T) {
+	var (
+		custom   = common.BytesToAddress([]byte{0xff})
+		override = common.BytesToAddress([]byte{1})
+	)
+	evm := NewEVM(BlockContext{BlockNumber: big.NewInt(0)}, TxContext{}, nil, params.TestChainConfig, Config{
+		Precompiles: map[common.Address]PrecompiledContract{
+			custom:   &dataCopy{},
+			override: &sha256hash{},
+		},
+	})
+	if p, ok := evm.precompile(custom); !ok {
+		t.Fatal("custom precompile not found")
+	} else if _, isCopy := p.(*dataCopy); !isCopy {
+		t.Fatalf("custom precompile mismatch: have %T", p)
+	}
+	if p, _ := evm.precompile(override); p == nil {
+		t.Fatal("overridden precompile not found")
+	} else if _, isSha := p.(*sha256hash); !isSha {
+		t.Fatalf("overridden precompile mismatch: have %T", p)
+	}
+	// The custom address is added once, the overridden one not duplicated
+	have := evm.ActivePrecompiles()
+	if want := len(ActivePrecompiles(evm.chainRules)) + 1; len(have) != want {
+		t.Fatalf("active precompile count mismatch: have %d, want %d", len(have), want)
+	}
+	var found bool
+	for _, addr := range have {
+		found = found || addr == custom
+	}
+	if !found {
+		t.Fatal("custom precompile not active")
+	}
+}
+
 // Failure tests
 func TestPrecompiledBLS12381G1AddFail(t *testing.T)      { testJsonFail("blsG1Add", "0a", t) }
 func TestPrecompiledBLS12381G1MulFail(t *testing.T)      { testJsonFail("blsG1Mul", "0b", t) }
@@ -0,0 +1,136 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// EVM Object Format (EIP-3540) constants.
+const (
+	eofFormat  byte = 0xef
+	eofMagic   byte = 0x00
+	eofVersion byte = 0x01
+
+	eofKindTerminator byte = 0x00
+	eofKindCode       byte = 0x01
+	eofKindData       byte = 0x02
+)
+
+var (
+	errEOFMissingMagic      = errors.New("missing magic")
+	errEOFInvalidVersion    = errors.New("invalid version")
+	errEOFMissingCode       = errors.New("missing code section")
+	errEOFInvalidHeader     = errors.New("invalid section header")
+	errEOFEmptySection      = errors.New("empty section")
+	errEOFSizeMismatch      = errors.New("container size mismatch")
+	errEOFUndefinedOpcode   = errors.New("undefined opcode")
+	errEOFTruncatedPushData = errors.New("truncated push data")
+)
+
+// Container is an EVM Object Format (EIP-3540) container, separating the code
+// from the data of a contract.
+type Container struct {
+	Code []byte // Code section, the only part executed
+	Data []byte // Data section, only accessible via CODECOPY and EXTCODECOPY
+}
+
+// hasEOFMagic reports whether the code is meant to be an EOF container.
+func hasEOFMagic(code []byte) bool {
+	return len(code) >= 2 && code[0] == eofFormat && code[1] == eofMagic
+}
+
+// ParseEOF parses an EOF container, checking its header and that the sizes of
+// its sections add up. The code itself is not validated.
+func ParseEOF(b []byte) (*Container, error) {
+	if !hasEOFMagic(b) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEOF, errEOFMissingMagic)
+	}
+	if len(b) < 3 || b[2] != eofVersion {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEOF, errEOFInvalidVersion)
+	}
+	var (
+		pos                = 3
+		codeSize, dataSize int
+		hasCode, hasData   bool
+	)
+	for {
+		if pos >= len(b) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidEOF, errEOFInvalidHeader)
+		}
+		kind := b[pos]
+		pos++
+		if kind == eofKindTerminator {
+			break
+		}
+		if pos+2 > len(b) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidEOF, errEOFInvalidHeader)
+		}
+		size := int(binary.BigEndian.Uint16(b[pos:]))
+		pos += 2
+		if size == 0 {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidEOF, errEOFEmptySection)
+		}
+		// The code section comes first, followed by at most one data section
+		switch {
+		case kind == eofKindCode && !hasCode:
+			codeSize, hasCode = size, true
+		case kind == eofKindData && hasCode && !hasData:
+			dataSize, hasData = size, true
+		default:
+			return nil, fmt.Errorf("%w: %v", ErrInvalidEOF, errEOFInvalidHeader)
+		}
+	}
+	if !hasCode {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEOF, errEOFMissingCode)
+	}
+	if len(b) != pos+codeSize+dataSize {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEOF, errEOFSizeMismatch)
+	}
+	return &Container{
+		Code: b[pos : pos+codeSize],
+		Data: b[pos+codeSize:],
+	}, nil
+}
+
+// ValidateEOF parses an EOF container and validates its code section against
+// the given instruction set according to EIP-3670: it must not contain any
+// undefined opcode nor end with truncated push data.
+func ValidateEOF(b []byte, jt *JumpTable) (*Container, error) {
+	c, err := ParseEOF(b)
+	if err != nil {
+		return nil, err
+	}
+	pc := 0
+	for pc < len(c.Code) {
+		op := OpCode(c.Code[pc])
+		if jt[op].undefined {
+			return nil, fmt.Errorf("%w: %v %#x at %d", ErrInvalidEOF, errEOFUndefinedOpcode, byte(op), pc)
+		}
+		pc++
+		if op.IsPush() {
+			pc += int(op - PUSH1 + 1)
+		}
+	}
+	// Truncated push data of the last opcode would skip past the code
+	if pc > len(c.Code) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEOF, errEOFTruncatedPushData)
+	}
+	return c, nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestParseEOF(t *testing.T) {
+	for i, tt := range []struct {
+		container string
+		code      string
+		data      string
+		err       error
+	}{
+		{container: "ef00010100010000", code: "00"},
+		{container: "ef000101000102000200" + "00" + "aabb", code: "00", data: "aabb"},
+		{container: "ef01010100010000", err: errEOFMissingMagic},
+		{container: "ef00020100010000", err: errEOFInvalidVersion},
+		{container: "ef000100", err: errEOFMissingCode},
+		{container: "ef0001020001000000", err: errEOFInvalidHeader},     // data before code
+		{container: "ef00010100010100010000", err: errEOFInvalidHeader}, // two code sections
+		{container: "ef00010100000000", err: errEOFEmptySection},
+		{container: "ef0001010001", err: errEOFInvalidHeader},           // missing terminator
+		{container: "ef000101000200" + "00", err: errEOFSizeMismatch},   // short code
+		{container: "ef00010100010000" + "00", err: errEOFSizeMismatch}, // trailing bytes
+	} {
+		c, err := ParseEOF(common.FromHex(tt.container))
+		if tt.err != nil {
+			if !errors.Is(err, ErrInvalidEOF) || err.Error() != ErrInvalidEOF.Error()+": "+tt.err.Error() {
+				t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test %d: failed to parse: %v", i, err)
+		}
+		if !bytes.Equal(c.Code, common.FromHex(tt.code)) || !bytes.Equal(c.Data, common.FromHex(tt.data)) {
+			t.Errorf("test %d: sections mismatch: have %x/%x, want %s/%s", i, c.Code, c.Data, tt.code, tt.data)
+		}
+	}
+}
+
+func TestValidateEOF(t *testing.T) {
+	jt := &londonInstructionSet
+	for i, tt := range []struct {
+		container string
+		valid     bool
+	}{
+		{"ef0001010005006001600055", true}, // PUSH1 1 PUSH1 0 SSTORE
+		{"ef000101000100" + "0c", false},   // undefined opcode
+		{"ef0001010002006001", true},       // PUSH1 1
+		{"ef000101000200" + "6101", false}, // truncated PUSH2
+		{"ef000101000100" + "60", false},   // truncated PUSH1
+	} {
+		_, err := ValidateEOF(common.FromHex(tt.container), jt)
+		if (err == nil) != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want valid %v", i, err, tt.valid)
+		}
+	}
+}
+
+func TestCreateEOF(t *testing.T) {
+	// deployer returns the given 8 byte code from legacy initcode
+	deployer := func(code string) []byte {
+		return common.FromHex("67" + code + "600052" + "6008" + "6018" + "f3")
+	}
+	for i, tt := range []struct {
+		eof      bool
+		initcode []byte
+		err      error
+	}{
+		{true, deployer("ef00010100010000"), nil},           // valid container
+		{true, deployer("ef0001010001000c"), ErrInvalidEOF}, // undefined opcode
+		{false, deployer("ef00010100010000"), ErrInvalidCode},
+		{true, common.FromHex("ef0001010001000c"), ErrInvalidEOF},                              // invalid EOF initcode
+		{true, common.FromHex("ef00010100010000"), ErrInvalidEOF},                              // EOF initcode deploying legacy code
+		{true, append(common.FromHex("ef000101001100"), deployer("ef00010100010000")...), nil}, // EOF initcode deploying EOF code
+	} {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		config := *params.AllEthashProtocolChanges
+		if tt.eof {
+			config.EOFBlock = big.NewInt(0)
+		}
+		vmctx := BlockContext{
+			BlockNumber: big.NewInt(0),
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		}
+		evm := NewEVM(vmctx, TxContext{}, statedb, &config, Config{})
+
+		_, addr, _, err := evm.Create(AccountRef(common.Address{1}), tt.initcode, 1000000, new(big.Int))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			continue
+		}
+		if err == nil && len(statedb.GetCode(addr)) == 0 {
+			t.Errorf("test %d: no code deployed", i)
+		}
+	}
+}
@@ -35,7 +35,11 @@ var (
 	ErrReturnDataOutOfBounds    = errors.New("return data out of bounds")
 	ErrGasUintOverflow          = errors.New("gas uint64 overflow")
 	ErrInvalidCode              = errors.New("invalid code: must not begin with 0xef")
+	ErrInvalidEOF               = errors.New("invalid EOF container")
 	ErrNonceUintOverflow        = errors.New("nonce uint64 overflow")
+	ErrCallDenied               = errors.New("call to denylisted address")
+	ErrExecutionAborted         = errors.New("execution aborted")
+	ErrOpcodeDisabled           = errors.New("opcode disabled")
 
 	// errStopToken is an internal token indicating interpreter loop termination,
 	// never returned to outside callers.
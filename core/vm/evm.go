@@ -17,11 +17,15 @@
 package vm
 
 import (
+	"context"
+	"fmt"
 	"math/big"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/holiman/uint256"
@@ -42,8 +46,15 @@ type (
 )
 
 func (evm *EVM) precompile(addr common.Address) (PrecompiledContract, bool) {
+	if p, ok := evm.Config.Precompiles[addr]; ok {
+		return p, true
+	}
 	var precompiles map[common.Address]PrecompiledContract
 	switch {
+	case evm.chainRules.IsPrague:
+		precompiles = PrecompiledContractsPrague
+	case evm.chainRules.IsCancun:
+		precompiles = PrecompiledContractsCancun
 	case evm.chainRules.IsBerlin:
 		precompiles = PrecompiledContractsBerlin
 	case evm.chainRules.IsIstanbul:
@@ -54,6 +65,9 @@ func (evm *EVM) precompile(addr common.Address) (PrecompiledContract, bool) {
 		precompiles = PrecompiledContractsHomestead
 	}
 	p, ok := precompiles[addr]
+	if !ok && evm.chainRules.IsP256Verify && addr == p256VerifyAddress {
+		return &p256Verify{}, true
+	}
 	return p, ok
 }
 
@@ -75,7 +89,11 @@ type BlockContext struct {
 	Time        *big.Int       // Provides information for TIME
 	Difficulty  *big.Int       // Provides information for DIFFICULTY
 	BaseFee     *big.Int       // Provides information for BASEFEE
+	BlobBaseFee *big.Int       // Blob gas price of the block (EIP-4844), nil before Cancun
 	Random      *common.Hash   // Provides information for RANDOM
+	ChainID     *big.Int       // Overrides the chain ID for CHAINID (nil means use the chain config)
+
+	ExcessBlobGas *uint64 // Excess blob gas of the block (EIP-4844), nil before Cancun
 }
 
 // TxContext provides the EVM with information about a transaction.
@@ -103,6 +121,8 @@ type EVM struct {
 	StateDB StateDB
 	// Depth is the current call stack
 	depth int
+	// frames are the call frames executing code, from the outermost one
+	frames []frame
 
 	// chainConfig contains information about the current chain
 	chainConfig *params.ChainConfig
@@ -121,6 +141,24 @@ type EVM struct {
 	// available gas is calculated in gasCall* according to the 63/64 rule and later
 	// applied in opCall*.
 	callGasTemp uint64
+	// blockHashOutOfRange is set if BLOCKHASH was invoked for a block outside
+	// the window of recent blocks since the last reset.
+	blockHashOutOfRange bool
+	// createCount and create2Count count the CREATE and CREATE2 operations
+	// executed since the last reset.
+	createCount, create2Count int
+	// stipendCalls counts the calls granted the value transfer gas stipend
+	// since the last reset.
+	stipendCalls int
+	// caughtReverts holds the revert data of the internal calls reverted
+	// since the last reset, if recording is enabled.
+	caughtReverts [][]byte
+	// profile accumulates the opcodes executed since the last reset, if
+	// profiling is enabled.
+	profile *OpcodeProfile
+	// deniedCall is set if a call to a denylisted address was refused
+	// since the last reset.
+	deniedCall bool
 }
 
 // NewEVM returns a new EVM. The returned EVM is not thread safe and should
@@ -134,23 +172,143 @@ func NewEVM(blockCtx BlockContext, txCtx TxContext, statedb StateDB, chainConfig
 		chainConfig: chainConfig,
 		chainRules:  chainConfig.Rules(blockCtx.BlockNumber, blockCtx.Random != nil),
 	}
+	if config.ProfileOpcodes {
+		evm.profile = new(OpcodeProfile)
+	}
 	evm.interpreter = NewEVMInterpreter(evm, config)
 	return evm
 }
 
+// ActivePrecompiles returns the addresses of the precompiles enabled by the
+// chain rules, along with those registered through the config.
+func (evm *EVM) ActivePrecompiles() []common.Address {
+	addrs := ActivePrecompiles(evm.chainRules)
+	if len(evm.Config.Precompiles) == 0 {
+		return addrs
+	}
+	active := make(map[common.Address]bool, len(addrs))
+	for _, addr := range addrs {
+		active[addr] = true
+	}
+	addrs = append(make([]common.Address, 0, len(addrs)+len(evm.Config.Precompiles)), addrs...)
+	for addr := range evm.Config.Precompiles {
+		if !active[addr] {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
 // Reset resets the EVM with a new transaction context.Reset
 // This is not threadsafe and should only be done very cautiously.
 func (evm *EVM) Reset(txCtx TxContext, statedb StateDB) {
 	evm.TxContext = txCtx
 	evm.StateDB = statedb
+	evm.blockHashOutOfRange = false
+	evm.createCount, evm.create2Count = 0, 0
+	evm.stipendCalls = 0
+	evm.caughtReverts = nil
+	evm.deniedCall = false
+	if evm.profile != nil {
+		evm.profile = new(OpcodeProfile)
+	}
+}
+
+// callDenied reports whether internal calls to addr are forbidden by the
+// recipient denylist, remembering the refusal for CallDenied.
+func (evm *EVM) callDenied(addr common.Address) bool {
+	if !evm.Config.DenyInternalCalls || !evm.Config.DenylistedRecipients[addr] {
+		return false
+	}
+	evm.deniedCall = true
+	return true
 }
 
-// Cancel cancels any running EVM operation. This may be called concurrently and
-// it's safe to be called multiple times.
+// CallDenied reports whether a call to a denylisted address was refused since
+// the EVM was created or reset. The execution then differs from that of nodes
+// without the policy, so the transaction must not be included in a block.
+func (evm *EVM) CallDenied() bool {
+	return evm.deniedCall
+}
+
+// resolveCode returns the code to execute when calling addr. Once Prague is
+// active, an EIP-7702 delegation designator resolves to the code of its target.
+func (evm *EVM) resolveCode(addr common.Address) []byte {
+	code := evm.StateDB.GetCode(addr)
+	if !evm.chainRules.IsPrague {
+		return code
+	}
+	if target, ok := types.ParseDelegation(code); ok {
+		return evm.StateDB.GetCode(target)
+	}
+	return code
+}
+
+// resolveCodeHash returns the hash of the code returned by resolveCode.
+func (evm *EVM) resolveCodeHash(addr common.Address) common.Hash {
+	if evm.chainRules.IsPrague {
+		if target, ok := types.ParseDelegation(evm.StateDB.GetCode(addr)); ok {
+			return evm.StateDB.GetCodeHash(target)
+		}
+	}
+	return evm.StateDB.GetCodeHash(addr)
+}
+
+// BlockHashOutOfRange reports whether BLOCKHASH was invoked for a block outside
+// the window of the 256 most recent blocks since the EVM was created or reset,
+// resolving to zero instead of an actual hash.
+func (evm *EVM) BlockHashOutOfRange() bool {
+	return evm.blockHashOutOfRange
+}
+
+// CreateCounts returns the number of CREATE and CREATE2 operations executed at
+// any call depth since the EVM was created or reset, including those in frames
+// that were later reverted.
+func (evm *EVM) CreateCounts() (create, create2 int) {
+	return evm.createCount, evm.create2Count
+}
+
+// StipendCalls returns the number of value transferring calls that were granted
+// the call stipend since the EVM was created or reset.
+func (evm *EVM) StipendCalls() int {
+	return evm.stipendCalls
+}
+
+// OpcodeProfile returns the opcodes executed since the last reset, or nil if
+// Config.ProfileOpcodes is not set.
+func (evm *EVM) OpcodeProfile() *OpcodeProfile {
+	return evm.profile
+}
+
+// CaughtReverts returns the revert data of the internal calls and creations
+// that reverted since the EVM was created or reset, in execution order. It is
+// only populated if Config.RecordCaughtReverts is set.
+func (evm *EVM) CaughtReverts() [][]byte {
+	return evm.caughtReverts
+}
+
+// Cancel cancels any running EVM operation, which then fails with
+// ErrExecutionAborted. This may be called concurrently and it's safe to be
+// called multiple times.
 func (evm *EVM) Cancel() {
 	atomic.StoreInt32(&evm.abort, 1)
 }
 
+// CancelWith cancels the EVM once ctx is done. The returned function releases
+// the watcher of the context and should be called once the execution is over.
+func (evm *EVM) CancelWith(ctx context.Context) (release func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			evm.Cancel()
+		case <-done:
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
 // Cancelled returns true if Cancel has been called
 func (evm *EVM) Cancelled() bool {
 	return atomic.LoadInt32(&evm.abort) == 1
@@ -170,6 +328,10 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	// Fail if the node policy forbids calling the address
+	if evm.callDenied(addr) {
+		return nil, gas, ErrCallDenied
+	}
 	// Fail if we're trying to transfer more than the available balance
 	if value.Sign() != 0 && !evm.Context.CanTransfer(evm.StateDB, caller.Address(), value) {
 		return nil, gas, ErrInsufficientBalance
@@ -216,7 +378,7 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	} else {
 		// Initialise a new contract and set the code that is to be used by the EVM.
 		// The contract is a scoped environment for this execution context only.
-		code := evm.StateDB.GetCode(addr)
+		code := evm.resolveCode(addr)
 		if len(code) == 0 {
 			ret, err = nil, nil // gas is unchanged
 		} else {
@@ -224,7 +386,7 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 			// If the account has no code, we can abort here
 			// The depth-check is already done, and precompiles handled above
 			contract := NewContract(caller, AccountRef(addrCopy), value, gas)
-			contract.SetCallCode(&addrCopy, evm.StateDB.GetCodeHash(addrCopy), code)
+			contract.SetCallCode(&addrCopy, evm.resolveCodeHash(addrCopy), code)
 			ret, err = evm.interpreter.Run(contract, input, false)
 			gas = contract.Gas
 		}
@@ -256,6 +418,10 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	// Fail if the node policy forbids calling the address
+	if evm.callDenied(addr) {
+		return nil, gas, ErrCallDenied
+	}
 	// Fail if we're trying to transfer more than the available balance
 	// Note although it's noop to transfer X ether to caller itself. But
 	// if caller doesn't have enough balance, it would be an error to allow
@@ -281,7 +447,7 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 		// Initialise a new contract and set the code that is to be used by the EVM.
 		// The contract is a scoped environment for this execution context only.
 		contract := NewContract(caller, AccountRef(caller.Address()), value, gas)
-		contract.SetCallCode(&addrCopy, evm.StateDB.GetCodeHash(addrCopy), evm.StateDB.GetCode(addrCopy))
+		contract.SetCallCode(&addrCopy, evm.resolveCodeHash(addrCopy), evm.resolveCode(addrCopy))
 		ret, err = evm.interpreter.Run(contract, input, false)
 		gas = contract.Gas
 	}
@@ -304,6 +470,10 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	// Fail if the node policy forbids calling the address
+	if evm.callDenied(addr) {
+		return nil, gas, ErrCallDenied
+	}
 	var snapshot = evm.StateDB.Snapshot()
 
 	// Invoke tracer hooks that signal entering/exiting a call frame
@@ -321,7 +491,7 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 		addrCopy := addr
 		// Initialise a new contract and make initialise the delegate values
 		contract := NewContract(caller, AccountRef(caller.Address()), nil, gas).AsDelegate()
-		contract.SetCallCode(&addrCopy, evm.StateDB.GetCodeHash(addrCopy), evm.StateDB.GetCode(addrCopy))
+		contract.SetCallCode(&addrCopy, evm.resolveCodeHash(addrCopy), evm.resolveCode(addrCopy))
 		ret, err = evm.interpreter.Run(contract, input, false)
 		gas = contract.Gas
 	}
@@ -343,6 +513,10 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	// Fail if the node policy forbids calling the address
+	if evm.callDenied(addr) {
+		return nil, gas, ErrCallDenied
+	}
 	// We take a snapshot here. This is a bit counter-intuitive, and could probably be skipped.
 	// However, even a staticcall is considered a 'touch'. On mainnet, static calls were introduced
 	// after all empty accounts were deleted, so this is not required. However, if we omit this,
@@ -374,7 +548,7 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 		// Initialise a new contract and set the code that is to be used by the EVM.
 		// The contract is a scoped environment for this execution context only.
 		contract := NewContract(caller, AccountRef(addrCopy), new(big.Int), gas)
-		contract.SetCallCode(&addrCopy, evm.StateDB.GetCodeHash(addrCopy), evm.StateDB.GetCode(addrCopy))
+		contract.SetCallCode(&addrCopy, evm.resolveCodeHash(addrCopy), evm.resolveCode(addrCopy))
 		// When an error was returned by the EVM or when setting the creation code
 		// above we revert to the snapshot and consume any gas remaining. Additionally
 		// when we're in Homestead this also counts for code storage gas errors.
@@ -430,6 +604,7 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	// Create a new account on the state
 	snapshot := evm.StateDB.Snapshot()
 	evm.StateDB.CreateAccount(address)
+	evm.StateDB.CreateContract(address)
 	if evm.chainRules.IsEIP158 {
 		evm.StateDB.SetNonce(address, 1)
 	}
@@ -450,16 +625,36 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 
 	start := time.Now()
 
-	ret, err := evm.interpreter.Run(contract, nil, false)
+	// Once EOF is active, initcode in an EOF container must be valid
+	var (
+		ret     []byte
+		err     error
+		eofInit = evm.chainRules.IsEOF && hasEOFMagic(codeAndHash.code)
+	)
+	if eofInit {
+		_, err = ValidateEOF(codeAndHash.code, evm.interpreter.cfg.JumpTable)
+	}
+	if err == nil {
+		ret, err = evm.interpreter.Run(contract, nil, false)
+	}
 
 	// Check whether the max code size has been exceeded, assign err if the case.
-	if err == nil && evm.chainRules.IsEIP158 && len(ret) > params.MaxCodeSize {
+	if err == nil && evm.chainRules.IsEIP158 && len(ret) > evm.chainConfig.CodeSizeLimit() {
 		err = ErrMaxCodeSizeExceeded
 	}
 
-	// Reject code starting with 0xEF if EIP-3541 is enabled.
+	// Reject code starting with 0xEF if EIP-3541 is enabled, unless it is a
+	// valid EOF container.
 	if err == nil && len(ret) >= 1 && ret[0] == 0xEF && evm.chainRules.IsLondon {
-		err = ErrInvalidCode
+		if evm.chainRules.IsEOF && hasEOFMagic(ret) {
+			_, err = ValidateEOF(ret, evm.interpreter.cfg.JumpTable)
+		} else {
+			err = ErrInvalidCode
+		}
+	}
+	// EOF initcode may only deploy EOF code.
+	if err == nil && eofInit && !hasEOFMagic(ret) {
+		err = fmt.Errorf("%w: initcode deployed legacy code", ErrInvalidEOF)
 	}
 
 	// if the contract creation ran successfully and no errors were returned
@@ -42,11 +42,7 @@ func memoryGasCost(mem *Memory, newMemSize uint64) (uint64, error) {
 	newMemSize = newMemSizeWords * 32
 
 	if newMemSize > uint64(mem.Len()) {
-		square := newMemSizeWords * newMemSizeWords
-		linCoef := newMemSizeWords * params.MemoryGas
-		quadCoef := square / params.QuadCoeffDiv
-		newTotalFee := linCoef + quadCoef
-
+		newTotalFee := memoryFee(newMemSizeWords)
 		fee := newTotalFee - mem.lastGasCost
 		mem.lastGasCost = newTotalFee
 
@@ -55,6 +51,12 @@ func memoryGasCost(mem *Memory, newMemSize uint64) (uint64, error) {
 	return 0, nil
 }
 
+// memoryFee returns the total cost of a memory of the given number of words,
+// which for the sizes allowed by memoryGasCost fits into an uint64.
+func memoryFee(words uint64) uint64 {
+	return words*params.MemoryGas + words*words/params.QuadCoeffDiv
+}
+
 // memoryCopierGas creates the gas functions for the following opcodes, and takes
 // the stack position of the operand which determines the size of the data to copy
 // as argument:
@@ -91,32 +93,39 @@ var (
 	gasCodeCopy       = memoryCopierGas(2)
 	gasExtCodeCopy    = memoryCopierGas(3)
 	gasReturnDataCopy = memoryCopierGas(2)
+	gasMcopy          = memoryCopierGas(2)
 )
 
+// gasSStore implements the legacy gas metering of SSTORE, which only takes into
+// consideration the current state. It applies before Constantinople and again
+// from Petersburg on, which removed EIP-1283.
 func gasSStore(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
 	var (
 		y, x    = stack.Back(1), stack.Back(0)
 		current = evm.StateDB.GetState(contract.Address(), x.Bytes32())
 	)
-	// The legacy gas metering only takes into consideration the current state
-	// Legacy rules should be applied if we are in Petersburg (removal of EIP-1283)
-	// OR Constantinople is not active
-	if evm.chainRules.IsPetersburg || !evm.chainRules.IsConstantinople {
-		// This checks for 3 scenario's and calculates gas accordingly:
-		//
-		// 1. From a zero-value address to a non-zero value         (NEW VALUE)
-		// 2. From a non-zero value address to a zero-value address (DELETE)
-		// 3. From a non-zero to a non-zero                         (CHANGE)
-		switch {
-		case current == (common.Hash{}) && y.Sign() != 0: // 0 => non 0
-			return params.SstoreSetGas, nil
-		case current != (common.Hash{}) && y.Sign() == 0: // non 0 => 0
-			evm.StateDB.AddRefund(params.SstoreRefundGas)
-			return params.SstoreClearGas, nil
-		default: // non 0 => non 0 (or 0 => 0)
-			return params.SstoreResetGas, nil
-		}
+	// This checks for 3 scenario's and calculates gas accordingly:
+	//
+	// 1. From a zero-value address to a non-zero value         (NEW VALUE)
+	// 2. From a non-zero value address to a zero-value address (DELETE)
+	// 3. From a non-zero to a non-zero                         (CHANGE)
+	switch {
+	case current == (common.Hash{}) && y.Sign() != 0: // 0 => non 0
+		return params.SstoreSetGas, nil
+	case current != (common.Hash{}) && y.Sign() == 0: // non 0 => 0
+		evm.StateDB.AddRefund(params.SstoreRefundGas)
+		return params.SstoreClearGas, nil
+	default: // non 0 => non 0 (or 0 => 0)
+		return params.SstoreResetGas, nil
 	}
+}
+
+// gasSStoreEIP1283 implements the net gas metering of SSTORE of Constantinople.
+func gasSStoreEIP1283(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	var (
+		y, x    = stack.Back(1), stack.Back(0)
+		current = evm.StateDB.GetState(contract.Address(), x.Bytes32())
+	)
 	// The new gas metering is based on net gas costs (EIP-1283):
 	//
 	// 1. If current value equals new value (this is a no-op), 200 gas is deducted.
@@ -325,13 +334,35 @@ func gasExpEIP158(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memor
 	return gas, nil
 }
 
-func gasCall(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+var (
+	// gasCall charges for the creation of any inexistent callee.
+	gasCall = makeGasCallFn(false, false)
+	// gasCallEIP150 only forwards all but one 64th of the available gas.
+	gasCallEIP150 = makeGasCallFn(true, false)
+	// gasCallEIP158 only charges for the creation of empty callees receiving
+	// value, as empty accounts are removed since EIP-158.
+	gasCallEIP158 = makeGasCallFn(true, true)
+
+	gasCallCode       = makeGasCallCodeFn(false)
+	gasCallCodeEIP150 = makeGasCallCodeFn(true)
+
+	gasDelegateCall       = makeGasDelegateCallFn(false)
+	gasDelegateCallEIP150 = makeGasDelegateCallFn(true)
+)
+
+func makeGasCallFn(eip150, eip158 bool) gasFunc {
+	return func(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+		return gasCallVariant(eip150, eip158, evm, contract, stack, mem, memorySize)
+	}
+}
+
+func gasCallVariant(eip150, eip158 bool, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
 	var (
 		gas            uint64
 		transfersValue = !stack.Back(2).IsZero()
 		address        = common.Address(stack.Back(1).Bytes20())
 	)
-	if evm.chainRules.IsEIP158 {
+	if eip158 {
 		if transfersValue && evm.StateDB.Empty(address) {
 			gas += params.CallNewAccountGas
 		}
@@ -350,7 +381,7 @@ func gasCall(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize
 		return 0, ErrGasUintOverflow
 	}
 
-	evm.callGasTemp, err = callGas(evm.chainRules.IsEIP150, contract.Gas, gas, stack.Back(0))
+	evm.callGasTemp, err = callGas(eip150, contract.Gas, gas, stack.Back(0))
 	if err != nil {
 		return 0, err
 	}
@@ -360,53 +391,58 @@ func gasCall(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize
 	return gas, nil
 }
 
-func gasCallCode(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
-	memoryGas, err := memoryGasCost(mem, memorySize)
-	if err != nil {
-		return 0, err
-	}
-	var (
-		gas      uint64
-		overflow bool
-	)
-	if stack.Back(2).Sign() != 0 {
-		gas += params.CallValueTransferGas
-	}
-	if gas, overflow = math.SafeAdd(gas, memoryGas); overflow {
-		return 0, ErrGasUintOverflow
-	}
-	evm.callGasTemp, err = callGas(evm.chainRules.IsEIP150, contract.Gas, gas, stack.Back(0))
-	if err != nil {
-		return 0, err
-	}
-	if gas, overflow = math.SafeAdd(gas, evm.callGasTemp); overflow {
-		return 0, ErrGasUintOverflow
+func makeGasCallCodeFn(eip150 bool) gasFunc {
+	return func(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+		memoryGas, err := memoryGasCost(mem, memorySize)
+		if err != nil {
+			return 0, err
+		}
+		var (
+			gas      uint64
+			overflow bool
+		)
+		if stack.Back(2).Sign() != 0 {
+			gas += params.CallValueTransferGas
+		}
+		if gas, overflow = math.SafeAdd(gas, memoryGas); overflow {
+			return 0, ErrGasUintOverflow
+		}
+		evm.callGasTemp, err = callGas(eip150, contract.Gas, gas, stack.Back(0))
+		if err != nil {
+			return 0, err
+		}
+		if gas, overflow = math.SafeAdd(gas, evm.callGasTemp); overflow {
+			return 0, ErrGasUintOverflow
+		}
+		return gas, nil
 	}
-	return gas, nil
 }
 
-func gasDelegateCall(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
-	gas, err := memoryGasCost(mem, memorySize)
-	if err != nil {
-		return 0, err
-	}
-	evm.callGasTemp, err = callGas(evm.chainRules.IsEIP150, contract.Gas, gas, stack.Back(0))
-	if err != nil {
-		return 0, err
-	}
-	var overflow bool
-	if gas, overflow = math.SafeAdd(gas, evm.callGasTemp); overflow {
-		return 0, ErrGasUintOverflow
+func makeGasDelegateCallFn(eip150 bool) gasFunc {
+	return func(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+		gas, err := memoryGasCost(mem, memorySize)
+		if err != nil {
+			return 0, err
+		}
+		evm.callGasTemp, err = callGas(eip150, contract.Gas, gas, stack.Back(0))
+		if err != nil {
+			return 0, err
+		}
+		var overflow bool
+		if gas, overflow = math.SafeAdd(gas, evm.callGasTemp); overflow {
+			return 0, ErrGasUintOverflow
+		}
+		return gas, nil
 	}
-	return gas, nil
 }
 
+// gasStaticCall only exists since Byzantium, so EIP-150 always applies.
 func gasStaticCall(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
 	gas, err := memoryGasCost(mem, memorySize)
 	if err != nil {
 		return 0, err
 	}
-	evm.callGasTemp, err = callGas(evm.chainRules.IsEIP150, contract.Gas, gas, stack.Back(0))
+	evm.callGasTemp, err = callGas(true, contract.Gas, gas, stack.Back(0))
 	if err != nil {
 		return 0, err
 	}
@@ -417,14 +453,30 @@ func gasStaticCall(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memo
 	return gas, nil
 }
 
+// gasSelfdestruct only grants the refund of SELFDESTRUCT, which was free
+// before the EIP-150 repricing.
 func gasSelfdestruct(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
-	var gas uint64
-	// EIP150 homestead gas reprice fork:
-	if evm.chainRules.IsEIP150 {
-		gas = params.SelfdestructGasEIP150
-		var address = common.Address(stack.Back(0).Bytes20())
+	if !evm.StateDB.HasSuicided(contract.Address()) {
+		evm.StateDB.AddRefund(params.SelfdestructRefundGas)
+	}
+	return 0, nil
+}
 
-		if evm.chainRules.IsEIP158 {
+var (
+	// gasSelfdestructEIP150 implements the EIP-150 repricing of SELFDESTRUCT.
+	gasSelfdestructEIP150 = makeSelfdestructGasEIP150Fn(false)
+	// gasSelfdestructEIP158 only charges for the creation of empty
+	// beneficiaries receiving value, as empty accounts are removed since EIP-158.
+	gasSelfdestructEIP158 = makeSelfdestructGasEIP150Fn(true)
+)
+
+func makeSelfdestructGasEIP150Fn(eip158 bool) gasFunc {
+	return func(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+		var (
+			gas     = params.SelfdestructGasEIP150
+			address = common.Address(stack.Back(0).Bytes20())
+		)
+		if eip158 {
 			// if empty and transfers value
 			if evm.StateDB.Empty(address) && evm.StateDB.GetBalance(contract.Address()).Sign() != 0 {
 				gas += params.CreateBySelfdestructGas
@@ -432,10 +484,9 @@ func gasSelfdestruct(evm *EVM, contract *Contract, stack *Stack, mem *Memory, me
 		} else if !evm.StateDB.Exist(address) {
 			gas += params.CreateBySelfdestructGas
 		}
+		if !evm.StateDB.HasSuicided(contract.Address()) {
+			evm.StateDB.AddRefund(params.SelfdestructRefundGas)
+		}
+		return gas, nil
 	}
-
-	if !evm.StateDB.HasSuicided(contract.Address()) {
-		evm.StateDB.AddRefund(params.SelfdestructRefundGas)
-	}
-	return gas, nil
 }
@@ -48,6 +48,171 @@ func TestMemoryGasCost(t *testing.T) {
 	}
 }
 
+func TestSStoreForkMetering(t *testing.T) {
+	for i, tt := range []struct {
+		petersburg int64
+		used       uint64
+	}{
+		{100, 412}, // Constantinople: EIP-1283 net metering, 2x noop + 4xPUSH
+		{0, 10012}, // Petersburg: legacy metering, 2x reset + 4xPUSH
+	} {
+		address := common.BytesToAddress([]byte("contract"))
+
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb.CreateAccount(address)
+		statedb.SetCode(address, hexutil.MustDecode("0x60006000556000600055")) // 0 -> 0 -> 0
+		statedb.Finalise(true)
+
+		config := &params.ChainConfig{
+			ChainID:             big.NewInt(1),
+			HomesteadBlock:      big.NewInt(0),
+			EIP150Block:         big.NewInt(0),
+			EIP155Block:         big.NewInt(0),
+			EIP158Block:         big.NewInt(0),
+			ByzantiumBlock:      big.NewInt(0),
+			ConstantinopleBlock: big.NewInt(0),
+			PetersburgBlock:     big.NewInt(tt.petersburg),
+		}
+		vmctx := BlockContext{
+			BlockNumber: big.NewInt(0),
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		}
+		vmenv := NewEVM(vmctx, TxContext{}, statedb, config, Config{})
+
+		_, gas, err := vmenv.Call(AccountRef(common.Address{}), address, nil, math.MaxUint64, new(big.Int))
+		if err != nil {
+			t.Fatalf("test %d: execution failed: %v", i, err)
+		}
+		if used := math.MaxUint64 - gas; used != tt.used {
+			t.Errorf("test %d: gas used mismatch: have %v, want %v", i, used, tt.used)
+		}
+	}
+}
+
+// gasForks are the forks changing the gas of SSTORE, CALL or SELFDESTRUCT.
+var gasForks = []string{"Frontier", "Homestead", "TangerineWhistle", "SpuriousDragon", "Byzantium", "Constantinople", "Petersburg", "Istanbul", "Berlin", "London"}
+
+// gasForkConfig returns a chain config with the forks up to gasForks[fork]
+// active at genesis.
+func gasForkConfig(fork int) *params.ChainConfig {
+	config := &params.ChainConfig{ChainID: big.NewInt(1)}
+	for i, block := range []**big.Int{&config.HomesteadBlock, &config.EIP150Block, &config.EIP158Block, &config.ByzantiumBlock, &config.ConstantinopleBlock, &config.PetersburgBlock, &config.IstanbulBlock, &config.BerlinBlock, &config.LondonBlock} {
+		if i < fork {
+			*block = big.NewInt(0)
+		}
+	}
+	config.EIP155Block = config.EIP158Block
+
+	// A nil Petersburg block counts as activated along with Constantinople
+	if config.PetersburgBlock == nil {
+		config.PetersburgBlock = big.NewInt(1)
+	}
+	return config
+}
+
+// Tests the gas charged and refunded by the fork dependent gas functions of
+// SSTORE, CALL and SELFDESTRUCT in every fork.
+func TestForkGasFunctions(t *testing.T) {
+	var (
+		contract = common.HexToAddress("0xc0de")
+		empty    = common.HexToAddress("0xee")  // existing empty account
+		failing  = common.HexToAddress("0xbad") // consumes all gas it is given
+	)
+	for _, tt := range []struct {
+		name   string
+		code   string
+		stored bool // whether slot 0 holds 1 before execution
+		funded bool // whether the contract holds a balance
+		gas    [10]uint64
+		refund [10]uint64
+	}{
+		{name: "sstore set", code: "0x600160005500",
+			gas:    [10]uint64{20006, 20006, 20006, 20006, 20006, 20006, 20006, 20006, 22106, 22106},
+			refund: [10]uint64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{name: "sstore clear", code: "0x600060005500", stored: true,
+			gas:    [10]uint64{5006, 5006, 5006, 5006, 5006, 5006, 5006, 5006, 5006, 5006},
+			refund: [10]uint64{15000, 15000, 15000, 15000, 15000, 15000, 15000, 15000, 15000, 4800}},
+		{name: "sstore reset", code: "0x600260005500", stored: true,
+			gas:    [10]uint64{5006, 5006, 5006, 5006, 5006, 5006, 5006, 5006, 5006, 5006},
+			refund: [10]uint64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{name: "sstore noop", code: "0x600160005500", stored: true,
+			gas:    [10]uint64{5006, 5006, 5006, 5006, 5006, 206, 5006, 806, 2206, 2206},
+			refund: [10]uint64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{name: "sstore set and clear", code: "0x6001600055600060005500",
+			gas:    [10]uint64{25012, 25012, 25012, 25012, 25012, 20212, 25012, 20812, 22212, 22212},
+			refund: [10]uint64{15000, 15000, 15000, 15000, 15000, 19800, 15000, 19200, 19900, 19900}},
+		{name: "call with value to new account", code: "0x6000600060006000600161dead612710f100", funded: true,
+			gas:    [10]uint64{31761, 31761, 32421, 32421, 32421, 32421, 32421, 32421, 34321, 34321},
+			refund: [10]uint64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{name: "call without value to new account", code: "0x6000600060006000600061dead612710f100", funded: true,
+			gas:    [10]uint64{25061, 25061, 25721, 721, 721, 721, 721, 721, 2621, 2621},
+			refund: [10]uint64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{name: "call with value to empty account", code: "0x6000600060006000600160ee612710f100", funded: true,
+			gas:    [10]uint64{6761, 6761, 7421, 32421, 32421, 32421, 32421, 32421, 34321, 34321},
+			refund: [10]uint64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{name: "call forwarding more gas than available", code: "0x60006000600060006000610bad620182b8f100", funded: true,
+			gas:    [10]uint64{99061, 99061, 98449, 98449, 98449, 98449, 98449, 98449, 98479, 98479},
+			refund: [10]uint64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{name: "callcode forwarding more gas than available", code: "0x60006000600060006000610bad620182b8f200", funded: true,
+			gas:    [10]uint64{99061, 99061, 98449, 98449, 98449, 98449, 98449, 98449, 98479, 98479},
+			refund: [10]uint64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{name: "delegatecall forwarding more gas than available", code: "0x6000600060006000610bad620182b8f400",
+			gas:    [10]uint64{100000, 99058, 98449, 98449, 98449, 98449, 98449, 98449, 98479, 98479},
+			refund: [10]uint64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{name: "selfdestruct to new account", code: "0x61deadff", funded: true,
+			gas:    [10]uint64{3, 3, 30003, 30003, 30003, 30003, 30003, 30003, 32603, 32603},
+			refund: [10]uint64{24000, 24000, 24000, 24000, 24000, 24000, 24000, 24000, 24000, 0}},
+		{name: "selfdestruct to new account without balance", code: "0x61deadff",
+			gas:    [10]uint64{3, 3, 30003, 5003, 5003, 5003, 5003, 5003, 7603, 7603},
+			refund: [10]uint64{24000, 24000, 24000, 24000, 24000, 24000, 24000, 24000, 24000, 0}},
+		{name: "selfdestruct to empty account", code: "0x60eeff", funded: true,
+			gas:    [10]uint64{3, 3, 5003, 30003, 30003, 30003, 30003, 30003, 32603, 32603},
+			refund: [10]uint64{24000, 24000, 24000, 24000, 24000, 24000, 24000, 24000, 24000, 0}},
+		{name: "selfdestruct to itself", code: "0x30ff", funded: true,
+			gas:    [10]uint64{2, 2, 5002, 5002, 5002, 5002, 5002, 5002, 5002, 5002},
+			refund: [10]uint64{24000, 24000, 24000, 24000, 24000, 24000, 24000, 24000, 24000, 0}},
+	} {
+		for fork := range gasForks {
+			statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+			statedb.SetCode(contract, common.FromHex(tt.code))
+			if tt.stored {
+				statedb.SetState(contract, common.Hash{}, common.BigToHash(common.Big1))
+			}
+			if tt.funded {
+				statedb.AddBalance(contract, big.NewInt(params.Ether))
+			}
+			statedb.CreateAccount(empty)
+			statedb.SetCode(failing, []byte{byte(INVALID)})
+			statedb.IntermediateRoot(false)
+
+			vmctx := BlockContext{
+				BlockNumber: big.NewInt(0),
+				CanTransfer: func(db StateDB, addr common.Address, amount *big.Int) bool {
+					return db.GetBalance(addr).Cmp(amount) >= 0
+				},
+				Transfer: func(db StateDB, from, to common.Address, amount *big.Int) {
+					db.SubBalance(from, amount)
+					db.AddBalance(to, amount)
+				},
+			}
+			vmenv := NewEVM(vmctx, TxContext{}, statedb, gasForkConfig(fork), Config{})
+			if vmenv.chainRules.IsBerlin {
+				statedb.PrepareAccessList(common.Address{}, &contract, vmenv.ActivePrecompiles(), nil)
+			}
+			// Executions failing in some forks (e.g. due to an undefined opcode)
+			// consume all gas, which is reflected by the expected gas used
+			_, left, _ := vmenv.Call(AccountRef(common.Address{}), contract, nil, 100000, new(big.Int))
+			if used := 100000 - left; used != tt.gas[fork] {
+				t.Errorf("%s/%s: gas used mismatch: have %d, want %d", tt.name, gasForks[fork], used, tt.gas[fork])
+			}
+			if refund := statedb.GetRefund(); refund != tt.refund[fork] {
+				t.Errorf("%s/%s: refund mismatch: have %d, want %d", tt.name, gasForks[fork], refund, tt.refund[fork])
+			}
+		}
+	}
+}
+
 var eip2200Tests = []struct {
 	original byte
 	gaspool  uint64
@@ -17,6 +17,7 @@
 package vm
 
 import (
+	"fmt"
 	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -349,7 +350,7 @@ func opExtCodeSize(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext)
 
 func opCodeSize(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
 	l := new(uint256.Int)
-	l.SetUint64(uint64(len(scope.Contract.Code)))
+	l.SetUint64(uint64(len(scope.Contract.fullCode())))
 	scope.Stack.push(l)
 	return nil, nil
 }
@@ -364,7 +365,7 @@ func opCodeCopy(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([
 	if overflow {
 		uint64CodeOffset = 0xffffffffffffffff
 	}
-	codeCopy := getData(scope.Contract.Code, uint64CodeOffset, length.Uint64())
+	codeCopy := getData(scope.Contract.fullCode(), uint64CodeOffset, length.Uint64())
 	scope.Memory.Set(memOffset.Uint64(), length.Uint64(), codeCopy)
 
 	return nil, nil
@@ -436,6 +437,7 @@ func opBlockhash(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) (
 	num := scope.Stack.peek()
 	num64, overflow := num.Uint64WithOverflow()
 	if overflow {
+		blockHashOutOfRange(interpreter, num64)
 		num.Clear()
 		return nil, nil
 	}
@@ -449,11 +451,42 @@ func opBlockhash(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) (
 	if num64 >= lower && num64 < upper {
 		num.SetBytes(interpreter.evm.Context.GetHash(num64).Bytes())
 	} else {
+		blockHashOutOfRange(interpreter, num64)
 		num.Clear()
 	}
 	return nil, nil
 }
 
+// caughtRevert records the revert data of a call or creation reverted inside
+// the execution, if requested by the configuration.
+func caughtRevert(interpreter *EVMInterpreter, data []byte) {
+	if interpreter.cfg.RecordCaughtReverts {
+		interpreter.evm.caughtReverts = append(interpreter.evm.caughtReverts, common.CopyBytes(data))
+	}
+}
+
+// callStipend counts a value transferring call granted the call stipend,
+// notifying the tracer if it is interested.
+func callStipend(interpreter *EVMInterpreter, to common.Address) {
+	interpreter.evm.stipendCalls++
+	if interpreter.cfg.Debug {
+		if tracer, ok := interpreter.cfg.Tracer.(StipendLogger); ok {
+			tracer.CaptureCallStipend(to, params.CallStipend)
+		}
+	}
+}
+
+// blockHashOutOfRange flags that BLOCKHASH was invoked for a block whose hash
+// is unavailable, notifying the tracer if it is interested.
+func blockHashOutOfRange(interpreter *EVMInterpreter, number uint64) {
+	interpreter.evm.blockHashOutOfRange = true
+	if interpreter.cfg.Debug {
+		if tracer, ok := interpreter.cfg.Tracer.(BlockHashLogger); ok {
+			tracer.CaptureBlockHashOutOfRange(number, interpreter.evm.Context.BlockNumber.Uint64())
+		}
+	}
+}
+
 func opCoinbase(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
 	scope.Stack.push(new(uint256.Int).SetBytes(interpreter.evm.Context.Coinbase.Bytes()))
 	return nil, nil
@@ -534,7 +567,7 @@ func opSstore(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]b
 
 func opJump(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
 	if atomic.LoadInt32(&interpreter.evm.abort) != 0 {
-		return nil, errStopToken
+		return nil, ErrExecutionAborted
 	}
 	pos := scope.Stack.pop()
 	if !scope.Contract.validJumpdest(&pos) {
@@ -546,7 +579,7 @@ func opJump(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byt
 
 func opJumpi(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
 	if atomic.LoadInt32(&interpreter.evm.abort) != 0 {
-		return nil, errStopToken
+		return nil, ErrExecutionAborted
 	}
 	pos, cond := scope.Stack.pop(), scope.Stack.pop()
 	if !cond.IsZero() {
@@ -581,6 +614,7 @@ func opCreate(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]b
 	if interpreter.readOnly {
 		return nil, ErrWriteProtection
 	}
+	interpreter.evm.createCount++
 	var (
 		value        = scope.Stack.pop()
 		offset, size = scope.Stack.pop(), scope.Stack.pop()
@@ -616,6 +650,7 @@ func opCreate(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]b
 	scope.Contract.Gas += returnGas
 
 	if suberr == ErrExecutionReverted {
+		caughtRevert(interpreter, res)
 		interpreter.returnData = res // set REVERT data to return data buffer
 		return res, nil
 	}
@@ -627,6 +662,7 @@ func opCreate2(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]
 	if interpreter.readOnly {
 		return nil, ErrWriteProtection
 	}
+	interpreter.evm.create2Count++
 	var (
 		endowment    = scope.Stack.pop()
 		offset, size = scope.Stack.pop(), scope.Stack.pop()
@@ -657,6 +693,7 @@ func opCreate2(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]
 	scope.Contract.Gas += returnGas
 
 	if suberr == ErrExecutionReverted {
+		caughtRevert(interpreter, res)
 		interpreter.returnData = res // set REVERT data to return data buffer
 		return res, nil
 	}
@@ -686,6 +723,7 @@ func opCall(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byt
 	if !value.IsZero() {
 		gas += params.CallStipend
 		bigVal = value.ToBig()
+		callStipend(interpreter, toAddr)
 	}
 
 	ret, returnGas, err := interpreter.evm.Call(scope.Contract, toAddr, args, gas, bigVal)
@@ -700,6 +738,9 @@ func opCall(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byt
 		ret = common.CopyBytes(ret)
 		scope.Memory.Set(retOffset.Uint64(), retSize.Uint64(), ret)
 	}
+	if err == ErrExecutionReverted {
+		caughtRevert(interpreter, ret)
+	}
 	scope.Contract.Gas += returnGas
 
 	interpreter.returnData = ret
@@ -723,6 +764,7 @@ func opCallCode(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([
 	if !value.IsZero() {
 		gas += params.CallStipend
 		bigVal = value.ToBig()
+		callStipend(interpreter, toAddr)
 	}
 
 	ret, returnGas, err := interpreter.evm.CallCode(scope.Contract, toAddr, args, gas, bigVal)
@@ -736,6 +778,9 @@ func opCallCode(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([
 		ret = common.CopyBytes(ret)
 		scope.Memory.Set(retOffset.Uint64(), retSize.Uint64(), ret)
 	}
+	if err == ErrExecutionReverted {
+		caughtRevert(interpreter, ret)
+	}
 	scope.Contract.Gas += returnGas
 
 	interpreter.returnData = ret
@@ -765,6 +810,9 @@ func opDelegateCall(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext
 		ret = common.CopyBytes(ret)
 		scope.Memory.Set(retOffset.Uint64(), retSize.Uint64(), ret)
 	}
+	if err == ErrExecutionReverted {
+		caughtRevert(interpreter, ret)
+	}
 	scope.Contract.Gas += returnGas
 
 	interpreter.returnData = ret
@@ -794,6 +842,9 @@ func opStaticCall(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext)
 		ret = common.CopyBytes(ret)
 		scope.Memory.Set(retOffset.Uint64(), retSize.Uint64(), ret)
 	}
+	if err == ErrExecutionReverted {
+		caughtRevert(interpreter, ret)
+	}
 	scope.Contract.Gas += returnGas
 
 	interpreter.returnData = ret
@@ -802,14 +853,14 @@ func opStaticCall(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext)
 
 func opReturn(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
 	offset, size := scope.Stack.pop(), scope.Stack.pop()
-	ret := scope.Memory.GetPtr(int64(offset.Uint64()), int64(size.Uint64()))
+	ret := scope.Memory.GetCopy(int64(offset.Uint64()), int64(size.Uint64()))
 
 	return ret, errStopToken
 }
 
 func opRevert(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
 	offset, size := scope.Stack.pop(), scope.Stack.pop()
-	ret := scope.Memory.GetPtr(int64(offset.Uint64()), int64(size.Uint64()))
+	ret := scope.Memory.GetCopy(int64(offset.Uint64()), int64(size.Uint64()))
 
 	interpreter.returnData = ret
 	return ret, ErrExecutionReverted
@@ -840,6 +891,13 @@ func opSelfdestruct(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext
 
 // following functions are used by the instruction jump  table
 
+// makeDisabled returns the implementation of an opcode disabled by the config.
+func makeDisabled(op OpCode) executionFunc {
+	return func(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+		return nil, fmt.Errorf("%w: %v", ErrOpcodeDisabled, op)
+	}
+}
+
 // make log instruction function
 func makeLog(size int) executionFunc {
 	return func(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
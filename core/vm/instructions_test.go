@@ -22,9 +22,12 @@ import (
 	"fmt"
 	"math/big"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/holiman/uint256"
@@ -547,6 +550,76 @@ func TestOpMstore(t *testing.T) {
 	}
 }
 
+func TestOpMCopy(t *testing.T) {
+	// Test cases from https://eips.ethereum.org/EIPS/eip-5656#test-cases
+	for i, tc := range []struct {
+		dst, src, len string
+		pre           string
+		want          string
+		wantSize      uint64
+	}{
+		{ // MCOPY 0 32 32 - copy 32 bytes from offset 32 to offset 0.
+			dst: "0x0", src: "0x20", len: "0x20",
+			pre:      "0000000000000000000000000000000000000000000000000000000000000000 000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			want:     "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f 000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			wantSize: 64,
+		},
+		{ // MCOPY 0 0 32 - copy 32 bytes from offset 0 to offset 0.
+			dst: "0x0", src: "0x0", len: "0x20",
+			pre:      "0101010101010101010101010101010101010101010101010101010101010101",
+			want:     "0101010101010101010101010101010101010101010101010101010101010101",
+			wantSize: 32,
+		},
+		{ // MCOPY 0 1 8 - copy 8 bytes from offset 1 to offset 0 (overlapping).
+			dst: "0x0", src: "0x1", len: "0x8",
+			pre:      "000102030405060708 000000000000000000000000000000000000000000000000",
+			want:     "010203040506070808 000000000000000000000000000000000000000000000000",
+			wantSize: 9,
+		},
+		{ // MCOPY 1 0 8 - copy 8 bytes from offset 0 to offset 1 (overlapping).
+			dst: "0x1", src: "0x0", len: "0x8",
+			pre:      "000102030405060708 000000000000000000000000000000000000000000000000",
+			want:     "000001020304050607 000000000000000000000000000000000000000000000000",
+			wantSize: 9,
+		},
+		{ // A zero length copy does not touch nor expand the memory.
+			dst: "0xFFFFFFFFFFFF", src: "0xFFFFFFFFFFFF", len: "0x0",
+			pre:      "11",
+			want:     "11",
+			wantSize: 0,
+		},
+	} {
+		var (
+			env            = NewEVM(BlockContext{}, TxContext{}, nil, params.TestChainConfig, Config{})
+			stack          = newstack()
+			pc             = uint64(0)
+			evmInterpreter = NewEVMInterpreter(env, env.Config)
+		)
+		data := common.FromHex(strings.ReplaceAll(tc.pre, " ", ""))
+		// Set pre
+		mem := NewMemory()
+		mem.Resize(uint64(len(data)))
+		mem.Set(0, uint64(len(data)), data)
+		// Push stack args
+		stack.push(new(uint256.Int).SetBytes(common.FromHex(tc.len)))
+		stack.push(new(uint256.Int).SetBytes(common.FromHex(tc.src)))
+		stack.push(new(uint256.Int).SetBytes(common.FromHex(tc.dst)))
+
+		size, overflow := memoryMcopy(stack)
+		if overflow {
+			t.Fatalf("test %d: memory size overflow", i)
+		}
+		if size != tc.wantSize {
+			t.Errorf("test %d: memory size mismatch: have %d, want %d", i, size, tc.wantSize)
+		}
+		opMcopy(&pc, evmInterpreter, &ScopeContext{mem, stack, nil})
+		want := common.FromHex(strings.ReplaceAll(tc.want, " ", ""))
+		if have := mem.store; !bytes.Equal(want, have) {
+			t.Errorf("test %d: memory mismatch:\nhave %x\nwant %x", i, have, want)
+		}
+	}
+}
+
 func BenchmarkOpMstore(bench *testing.B) {
 	var (
 		env            = NewEVM(BlockContext{}, TxContext{}, nil, params.TestChainConfig, Config{})
@@ -695,3 +768,37 @@ func TestRandom(t *testing.T) {
 		}
 	}
 }
+
+func TestChainIDOverride(t *testing.T) {
+	// CHAINID PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	code := common.FromHex("0x4660005260206000f3")
+	address := common.BytesToAddress([]byte("contract"))
+
+	for i, tt := range []struct {
+		override *big.Int
+		want     *big.Int
+	}{
+		{nil, params.AllEthashProtocolChanges.ChainID},
+		{big.NewInt(10), big.NewInt(10)},
+		{big.NewInt(137), big.NewInt(137)},
+	} {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb.CreateAccount(address)
+		statedb.SetCode(address, code)
+
+		vmctx := BlockContext{
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+			BlockNumber: big.NewInt(0),
+			ChainID:     tt.override,
+		}
+		vmenv := NewEVM(vmctx, TxContext{}, statedb, params.AllEthashProtocolChanges, Config{})
+		ret, _, err := vmenv.Call(AccountRef(common.Address{}), address, nil, 100000, new(big.Int))
+		if err != nil {
+			t.Fatalf("test %d: execution failed: %v", i, err)
+		}
+		if have := new(big.Int).SetBytes(ret); have.Cmp(tt.want) != 0 {
+			t.Errorf("test %d: chain id mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
@@ -26,6 +26,7 @@ import (
 // StateDB is an EVM database for full state querying.
 type StateDB interface {
 	CreateAccount(common.Address)
+	CreateContract(common.Address)
 
 	SubBalance(common.Address, *big.Int)
 	AddBalance(common.Address, *big.Int)
@@ -47,7 +48,11 @@ type StateDB interface {
 	GetState(common.Address, common.Hash) common.Hash
 	SetState(common.Address, common.Hash, common.Hash)
 
+	GetTransientState(addr common.Address, key common.Hash) common.Hash
+	SetTransientState(addr common.Address, key, value common.Hash)
+
 	Suicide(common.Address) bool
+	Suicide6780(common.Address) bool
 	HasSuicided(common.Address) bool
 
 	// Exist reports whether the given account exists in state.
@@ -56,6 +61,9 @@ type StateDB interface {
 	// Empty returns whether the given account is empty. Empty
 	// is defined according to EIP161 (balance = nonce = code = 0).
 	Empty(common.Address) bool
+	// EmptyTouchedAccounts returns the empty accounts touched by the
+	// current transaction, which EIP161 state clearing will delete.
+	EmptyTouchedAccounts() []common.Address
 
 	PrepareAccessList(sender common.Address, dest *common.Address, precompiles []common.Address, txAccesses types.AccessList)
 	AddressInAccessList(addr common.Address) bool
@@ -69,6 +77,9 @@ type StateDB interface {
 
 	RevertToSnapshot(int)
 	Snapshot() int
+	// ModifiedSince reports whether any balance, nonce, code or storage
+	// change made after the given snapshot is still in effect.
+	ModifiedSince(int) bool
 
 	AddLog(*types.Log)
 	AddPreimage(common.Hash, []byte)
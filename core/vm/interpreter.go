@@ -18,10 +18,13 @@ package vm
 
 import (
 	"hash"
+	"math/big"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 )
 
 // Config are the configuration options for the Interpreter
@@ -34,6 +37,111 @@ type Config struct {
 	JumpTable *JumpTable // EVM instruction table, automatically populated if unset
 
 	ExtraEips []int // Additional EIPS that are to be enabled
+
+	// Precompiles registers additional precompiled contracts, taking precedence
+	// over the built-in ones at the same addresses. Like those, they are warm
+	// from the start of a transaction once Berlin is active.
+	Precompiles map[common.Address]PrecompiledContract
+
+	// FeeHook, if set, determines the amount credited to the coinbase for a
+	// transaction instead of the default effectivePrice * gasUsed. Whatever
+	// the sender paid beyond the returned amount is burned. Amounts outside of
+	// [0, effectivePrice * gasUsed], as well as nil, are clamped to that range.
+	FeeHook func(effectivePrice, gasUsed *big.Int) (coinbaseAmount *big.Int)
+
+	// SkipCoinbasePayment suppresses the fee credited to the coinbase, so the
+	// state diffs of traced or simulated messages exclude miner payments. The
+	// sender is still charged for its gas. This is not a consensus rule and
+	// must not be enabled when processing blocks.
+	SkipCoinbasePayment bool
+
+	// DenylistedRecipients lists addresses calls to which fail with
+	// ErrCallDenied if DenyInternalCalls is set, as reported afterwards by
+	// EVM.CallDenied. This is a node policy rather than a consensus rule: the
+	// miner uses it to leave transactions calling into these addresses out of
+	// its blocks, and it must never be enabled when processing blocks.
+	DenylistedRecipients map[common.Address]bool
+	DenyInternalCalls    bool
+
+	// RecordCaughtReverts records the revert data of every call or creation
+	// reverting within the execution without aborting it.
+	RecordCaughtReverts bool
+
+	// DetailedResults fills in the statistics of the state transition result
+	// that are not needed to apply a message, e.g. whether it modified the
+	// state or the accounts EIP-161 clears. They are left zero otherwise, as
+	// block processing would compute them for every transaction in vain.
+	DetailedResults bool
+
+	// DisableEIP3529 applies the refund rules from before EIP-3529 regardless
+	// of the active fork, for analysing its impact: SSTORE clears and
+	// self-destructs are refunded as in Berlin and the refund cap is half of
+	// the gas used. It is not a consensus setting.
+	DisableEIP3529 bool
+
+	// RecordBalanceChanges records every balance mutation of a transaction in
+	// order, along with its reason, in the result of the state transition.
+	RecordBalanceChanges bool
+
+	// CheckDeterminism executes every transaction twice, the first time on a
+	// reverted snapshot, and compares the return data and gas left of both
+	// runs. It is a costly diagnostic for custom precompiles; tracers and the
+	// balance transfer hook observe both runs.
+	CheckDeterminism bool
+
+	// Hooks are callbacks fired by the state transition while applying a
+	// message, e.g. for custom accounting. Unset hooks are skipped.
+	Hooks TxHooks
+
+	// ProfileOpcodes accumulates the number of executions of every opcode and
+	// the time spent on them, reported in the execution result. Timing every
+	// instruction slows down execution considerably.
+	ProfileOpcodes bool
+
+	// OperatorFee, if set, charges every transaction a fee on top of its gas,
+	// allowing rollups to recover their L1 data costs without forking the
+	// state transition.
+	OperatorFee OperatorFee
+
+	// DisabledOpcodes lists opcodes which fail with ErrOpcodeDisabled when
+	// executed, consuming all the gas remaining in the frame like an invalid
+	// opcode, e.g. SELFDESTRUCT on a consortium chain. OpcodeOverrides
+	// substitutes the implementations of opcodes, keeping their gas costs and
+	// stack requirements. Disabling takes precedence over overriding. Neither
+	// is a consensus setting.
+	DisabledOpcodes []OpCode
+	OpcodeOverrides map[OpCode]OpcodeFunc
+}
+
+// OperatorFee computes an additional fee charged to the gas payer of every
+// transaction, e.g. the L1 data fee of a rollup.
+type OperatorFee interface {
+	// Fee returns the fee of a transaction given its RLP encoded size. It is
+	// charged along with the gas and is not refunded. A nil fee charges none.
+	Fee(size uint64) *big.Int
+
+	// Vault returns the account credited with the collected fees.
+	Vault() common.Address
+}
+
+// TxHooks are optional callbacks fired while a message is applied.
+type TxHooks struct {
+	// OnTxStart is called before the message is checked, with its sender, its
+	// recipient (nil for contract creations) and its gas limit.
+	OnTxStart func(from common.Address, to *common.Address, gas uint64)
+
+	// OnTxEnd is called after the message was applied, with the gas used after
+	// refunds and the execution error. If the message was rejected, err is the
+	// consensus error and no gas is used.
+	OnTxEnd func(gasUsed uint64, err error)
+
+	// OnGasConsumed is called when the intrinsic gas is charged and after the
+	// execution, with the amount of gas consumed by each.
+	OnGasConsumed func(gas uint64)
+
+	// OnBalanceTransfer is called after every value transfer, including the
+	// transfer of the message value and those made during execution.
+	OnBalanceTransfer func(from, to common.Address, amount *big.Int)
 }
 
 // ScopeContext contains the things that are per-call, such as stack and memory,
@@ -52,6 +160,10 @@ type keccakState interface {
 	Read([]byte) (int, error)
 }
 
+// abortCheckInterval is the number of operations executed between checks of
+// whether the execution was cancelled, besides those done on every jump.
+const abortCheckInterval = 1024
+
 // EVMInterpreter represents an EVM interpreter
 type EVMInterpreter struct {
 	evm *EVM
@@ -68,28 +180,7 @@ type EVMInterpreter struct {
 func NewEVMInterpreter(evm *EVM, cfg Config) *EVMInterpreter {
 	// If jump table was not initialised we set the default one.
 	if cfg.JumpTable == nil {
-		switch {
-		case evm.chainRules.IsMerge:
-			cfg.JumpTable = &mergeInstructionSet
-		case evm.chainRules.IsLondon:
-			cfg.JumpTable = &londonInstructionSet
-		case evm.chainRules.IsBerlin:
-			cfg.JumpTable = &berlinInstructionSet
-		case evm.chainRules.IsIstanbul:
-			cfg.JumpTable = &istanbulInstructionSet
-		case evm.chainRules.IsConstantinople:
-			cfg.JumpTable = &constantinopleInstructionSet
-		case evm.chainRules.IsByzantium:
-			cfg.JumpTable = &byzantiumInstructionSet
-		case evm.chainRules.IsEIP158:
-			cfg.JumpTable = &spuriousDragonInstructionSet
-		case evm.chainRules.IsEIP150:
-			cfg.JumpTable = &tangerineWhistleInstructionSet
-		case evm.chainRules.IsHomestead:
-			cfg.JumpTable = &homesteadInstructionSet
-		default:
-			cfg.JumpTable = &frontierInstructionSet
-		}
+		cfg.JumpTable = instructionSetForRules(evm.chainRules)
 		for i, eip := range cfg.ExtraEips {
 			copy := *cfg.JumpTable
 			if err := EnableEIP(eip, &copy); err != nil {
@@ -99,6 +190,16 @@ func NewEVMInterpreter(evm *EVM, cfg Config) *EVMInterpreter {
 			}
 			cfg.JumpTable = &copy
 		}
+		if cfg.DisableEIP3529 && evm.chainRules.IsLondon {
+			copy := *cfg.JumpTable
+			disable3529(&copy)
+			cfg.JumpTable = &copy
+		}
+	}
+	if len(cfg.DisabledOpcodes) > 0 || len(cfg.OpcodeOverrides) > 0 {
+		copy := *cfg.JumpTable
+		overrideOpcodes(&copy, cfg.DisabledOpcodes, cfg.OpcodeOverrides)
+		cfg.JumpTable = &copy
 	}
 
 	return &EVMInterpreter{
@@ -125,6 +226,9 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		in.readOnly = true
 		defer func() { in.readOnly = false }()
 	}
+	// Track the frame for introspection through the EVM
+	in.evm.frames = append(in.evm.frames, frame{contract: contract, readOnly: in.readOnly})
+	defer func() { in.evm.frames = in.evm.frames[:len(in.evm.frames)-1] }()
 
 	// Reset the previous call's return data. It's unimportant to preserve the old buffer
 	// as every returning call will return new data anyway.
@@ -134,6 +238,18 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 	if len(contract.Code) == 0 {
 		return nil, nil
 	}
+	// Only execute the code section of EOF containers.
+	if in.evm.chainRules.IsEOF && contract.container == nil && hasEOFMagic(contract.Code) {
+		c, err := ParseEOF(contract.Code)
+		if err != nil {
+			return nil, err
+		}
+		contract.container, contract.Code = contract.Code, c.Code
+	}
+	// Don't start new frames once the execution was aborted.
+	if in.evm.Cancelled() {
+		return nil, ErrExecutionAborted
+	}
 
 	var (
 		op          OpCode        // current opcode
@@ -147,8 +263,9 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		// For optimisation reason we're using uint64 as the program counter.
 		// It's theoretically possible to go above 2^64. The YP defines the PC
 		// to be uint256. Practically much less so feasible.
-		pc   = uint64(0) // program counter
-		cost uint64
+		pc    = uint64(0) // program counter
+		steps uint64      // executed operations, for periodic abort checks
+		cost  uint64
 		// copies used by tracer
 		pcCopy  uint64 // needed for the deferred EVMLogger
 		gasCopy uint64 // for EVMLogger to log gas remaining before execution
@@ -156,13 +273,19 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		res     []byte // result of the opcode execution function
 	)
 	// Don't move this deferred function, it's placed before the capturestate-deferred method,
-	// so that it get's executed _after_: the capturestate needs the stacks before
-	// they are returned to the pools
+	// so that it get's executed _after_: the capturestate needs the stacks and
+	// memory before they are returned to the pools
 	defer func() {
 		returnStack(stack)
+		returnMemory(mem)
 	}()
 	contract.Input = input
 
+	// Only time the opcodes if someone is collecting the measurements, keeping
+	// the checks out of the loop below.
+	profile := in.evm.profile
+	profiling := metrics.EnabledExpensive || profile != nil
+
 	if in.cfg.Debug {
 		defer func() {
 			if err != nil {
@@ -179,6 +302,9 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 	// the execution of one of the operations or until the done flag is set by the
 	// parent context.
 	for {
+		if steps++; steps%abortCheckInterval == 0 && in.evm.Cancelled() {
+			return nil, ErrExecutionAborted
+		}
 		if in.cfg.Debug {
 			// Capture pre-execution values for tracing.
 			logged, pcCopy, gasCopy = false, pc, contract.Gas
@@ -236,7 +362,18 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 			logged = true
 		}
 		// execute the operation
-		res, err = operation.execute(&pc, in, callContext)
+		if profiling {
+			start := time.Now()
+			res, err = operation.execute(&pc, in, callContext)
+			if timer := opcodeTimers[op]; timer != nil {
+				timer.UpdateSince(start)
+			}
+			if profile != nil {
+				profile.record(op, time.Since(start))
+			}
+		} else {
+			res, err = operation.execute(&pc, in, callContext)
+		}
 		if err != nil {
 			break
 		}
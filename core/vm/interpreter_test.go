@@ -17,6 +17,8 @@
 package vm
 
 import (
+	"context"
+	"errors"
 	"math/big"
 	"testing"
 	"time"
@@ -68,10 +70,159 @@ func TestLoopInterrupt(t *testing.T) {
 		case <-timeout:
 			t.Errorf("test %d timed out", i)
 		case err := <-errChannel:
-			if err != nil {
-				t.Errorf("test %d failure: %v", i, err)
+			if err != ErrExecutionAborted {
+				t.Errorf("test %d failure: have %v, want %v", i, err, ErrExecutionAborted)
 			}
 		}
 	}
 
 }
+
+func TestLoopInterruptContext(t *testing.T) {
+	address := common.BytesToAddress([]byte("contract"))
+	vmctx := BlockContext{
+		Transfer: func(StateDB, common.Address, common.Address, *big.Int) {},
+	}
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.CreateAccount(address)
+	statedb.SetCode(address, common.Hex2Bytes(loopInterruptTests[0]))
+	statedb.Finalise(true)
+
+	evm := NewEVM(vmctx, TxContext{}, statedb, params.AllEthashProtocolChanges, Config{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	defer evm.CancelWith(ctx)()
+
+	errChannel := make(chan error, 1)
+	go func() {
+		_, _, err := evm.Call(AccountRef(common.Address{}), address, nil, math.MaxUint64, new(big.Int))
+		errChannel <- err
+	}()
+	select {
+	case <-time.After(time.Second):
+		t.Fatal("execution not aborted")
+	case err := <-errChannel:
+		if err != ErrExecutionAborted {
+			t.Fatalf("error mismatch: have %v, want %v", err, ErrExecutionAborted)
+		}
+	}
+}
+
+func TestOpcodeProfile(t *testing.T) {
+	address := common.BytesToAddress([]byte("contract"))
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.CreateAccount(address)
+	// PUSH1 1 PUSH1 2 ADD POP STOP
+	statedb.SetCode(address, []byte{byte(PUSH1), 1, byte(PUSH1), 2, byte(ADD), byte(POP), byte(STOP)})
+
+	vmctx := BlockContext{
+		Transfer: func(StateDB, common.Address, common.Address, *big.Int) {},
+	}
+	evm := NewEVM(vmctx, TxContext{}, statedb, params.AllEthashProtocolChanges, Config{ProfileOpcodes: true})
+	if _, _, err := evm.Call(AccountRef(common.Address{}), address, nil, 100000, new(big.Int)); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	stats := evm.OpcodeProfile().Stats()
+	want := map[OpCode]uint64{PUSH1: 2, ADD: 1, POP: 1, STOP: 1}
+	if len(stats) != len(want) {
+		t.Fatalf("profiled opcode count mismatch: have %d, want %d", len(stats), len(want))
+	}
+	for op, count := range want {
+		if stats[op].Count != count {
+			t.Errorf("%v count mismatch: have %d, want %d", op, stats[op].Count, count)
+		}
+	}
+	// Profiles are per transaction and only kept if enabled
+	evm.Reset(TxContext{}, statedb)
+	if stats := evm.OpcodeProfile().Stats(); len(stats) != 0 {
+		t.Errorf("profile not reset: %v", stats)
+	}
+	evm = NewEVM(vmctx, TxContext{}, statedb, params.AllEthashProtocolChanges, Config{})
+	if evm.OpcodeProfile() != nil {
+		t.Error("profile kept without profiling")
+	}
+}
+
+func TestOpcodeOverrides(t *testing.T) {
+	var (
+		adder     = common.BytesToAddress([]byte("adder"))
+		destroyer = common.BytesToAddress([]byte("destroyer"))
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.CreateAccount(adder)
+	statedb.CreateAccount(destroyer)
+	// PUSH1 2 PUSH1 3 ADD PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	statedb.SetCode(adder, common.Hex2Bytes("600260030160005260206000f3"))
+	// PUSH1 0 SELFDESTRUCT
+	statedb.SetCode(destroyer, common.Hex2Bytes("6000ff"))
+
+	vmctx := BlockContext{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+	}
+	config := Config{
+		DisabledOpcodes: []OpCode{SELFDESTRUCT},
+		OpcodeOverrides: map[OpCode]OpcodeFunc{
+			// Multiply instead of adding
+			ADD: func(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+				x, y := scope.Stack.Pop(), scope.Stack.Back(0)
+				y.Mul(&x, y)
+				return nil, nil
+			},
+		},
+	}
+	evm := NewEVM(vmctx, TxContext{}, statedb, params.AllEthashProtocolChanges, config)
+	ret, _, err := evm.Call(AccountRef(common.Address{}), adder, nil, 100000, new(big.Int))
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if have := new(big.Int).SetBytes(ret); have.Uint64() != 6 {
+		t.Errorf("overridden ADD result mismatch: have %v, want 6", have)
+	}
+	_, left, err := evm.Call(AccountRef(common.Address{}), destroyer, nil, 100000, new(big.Int))
+	if !errors.Is(err, ErrOpcodeDisabled) {
+		t.Errorf("disabled SELFDESTRUCT error mismatch: have %v, want %v", err, ErrOpcodeDisabled)
+	}
+	if left != 0 {
+		t.Errorf("disabled SELFDESTRUCT gas left mismatch: have %d, want 0", left)
+	}
+	// The default instruction sets must not be affected
+	evm = NewEVM(vmctx, TxContext{}, statedb, params.AllEthashProtocolChanges, Config{})
+	if ret, _, _ = evm.Call(AccountRef(common.Address{}), adder, nil, 100000, new(big.Int)); new(big.Int).SetBytes(ret).Uint64() != 5 {
+		t.Errorf("default ADD result mismatch: have %x, want 5", ret)
+	}
+	if _, _, err := evm.Call(AccountRef(common.Address{}), destroyer, nil, 100000, new(big.Int)); err != nil {
+		t.Errorf("default SELFDESTRUCT failed: %v", err)
+	}
+}
+
+// Tests that the output of RETURN and REVERT remains intact once the memory of
+// the frame producing it is reused by a later frame.
+func TestReturnDataOutlivesMemory(t *testing.T) {
+	var (
+		returner  = common.BytesToAddress([]byte("returner"))
+		scribbler = common.BytesToAddress([]byte("scribbler"))
+		vmctx     = BlockContext{
+			BlockNumber: big.NewInt(0),
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		}
+	)
+	for _, op := range []OpCode{RETURN, REVERT} {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		// MSTORE(0, 0x11) RETURN/REVERT(0, 32)
+		statedb.SetCode(returner, append(common.FromHex("0x601160005260206000"), byte(op)))
+		// MSTORE(0, 0xff) STOP
+		statedb.SetCode(scribbler, common.FromHex("0x60ff60005200"))
+
+		evm := NewEVM(vmctx, TxContext{}, statedb, params.AllEthashProtocolChanges, Config{})
+		ret, _, _ := evm.Call(AccountRef(common.Address{}), returner, nil, 100000, new(big.Int))
+		if _, _, err := evm.Call(AccountRef(common.Address{}), scribbler, nil, 100000, new(big.Int)); err != nil {
+			t.Fatalf("%v: failed to call scribbler: %v", op, err)
+		}
+		if len(ret) != 32 || ret[31] != 0x11 {
+			t.Errorf("%v: output overwritten: %x", op, ret)
+		}
+	}
+}
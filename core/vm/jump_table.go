@@ -42,6 +42,9 @@ type operation struct {
 
 	// memorySize returns the memory size required for the operation
 	memorySize memorySizeFunc
+
+	// undefined denotes if the instruction is not officially defined in the jump table
+	undefined bool
 }
 
 var (
@@ -51,15 +54,72 @@ var (
 	spuriousDragonInstructionSet   = newSpuriousDragonInstructionSet()
 	byzantiumInstructionSet        = newByzantiumInstructionSet()
 	constantinopleInstructionSet   = newConstantinopleInstructionSet()
+	petersburgInstructionSet       = newPetersburgInstructionSet()
 	istanbulInstructionSet         = newIstanbulInstructionSet()
 	berlinInstructionSet           = newBerlinInstructionSet()
 	londonInstructionSet           = newLondonInstructionSet()
 	mergeInstructionSet            = newMergeInstructionSet()
+	shanghaiInstructionSet         = newShanghaiInstructionSet()
+	cancunInstructionSet           = newCancunInstructionSet()
 )
 
+// OpcodeFunc implements the execution of an opcode. It receives the program
+// counter, which it may advance past immediate data, and returns the output
+// of the opcode for halting instructions.
+type OpcodeFunc func(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error)
+
 // JumpTable contains the EVM opcodes supported at a given fork.
 type JumpTable [256]*operation
 
+// overrideOpcodes replaces the implementations of the overridden opcodes,
+// keeping their gas and stack requirements, and makes the disabled ones fail
+// with ErrOpcodeDisabled. Like an invalid opcode, this consumes all the gas
+// remaining in the frame.
+func overrideOpcodes(jt *JumpTable, disabled []OpCode, overrides map[OpCode]OpcodeFunc) {
+	for op, fn := range overrides {
+		override := *jt[op]
+		override.execute = executionFunc(fn)
+		jt[op] = &override
+	}
+	for _, op := range disabled {
+		jt[op] = &operation{execute: makeDisabled(op), maxStack: maxStack(0, 0)}
+	}
+}
+
+// instructionSetForRules returns the instruction set of the latest fork active
+// under the given rules. Each instruction set installs the gas functions of its
+// fork, so the gas costs of the opcodes don't depend on the rules at runtime.
+func instructionSetForRules(rules params.Rules) *JumpTable {
+	switch {
+	case rules.IsCancun:
+		return &cancunInstructionSet
+	case rules.IsShanghai:
+		return &shanghaiInstructionSet
+	case rules.IsMerge:
+		return &mergeInstructionSet
+	case rules.IsLondon:
+		return &londonInstructionSet
+	case rules.IsBerlin:
+		return &berlinInstructionSet
+	case rules.IsIstanbul:
+		return &istanbulInstructionSet
+	case rules.IsPetersburg:
+		return &petersburgInstructionSet
+	case rules.IsConstantinople:
+		return &constantinopleInstructionSet
+	case rules.IsByzantium:
+		return &byzantiumInstructionSet
+	case rules.IsEIP158:
+		return &spuriousDragonInstructionSet
+	case rules.IsEIP150:
+		return &tangerineWhistleInstructionSet
+	case rules.IsHomestead:
+		return &homesteadInstructionSet
+	default:
+		return &frontierInstructionSet
+	}
+}
+
 func validate(jt JumpTable) JumpTable {
 	for i, op := range jt {
 		if op == nil {
@@ -78,6 +138,26 @@ func validate(jt JumpTable) JumpTable {
 	return jt
 }
 
+// newCancunInstructionSet returns the frontier, homestead, byzantium,
+// contantinople, istanbul, petersburg, berlin, london, merge, shanghai and
+// cancun instructions.
+func newCancunInstructionSet() JumpTable {
+	instructionSet := newShanghaiInstructionSet()
+	enable1153(&instructionSet) // EIP-1153 "Transient Storage"
+	enable5656(&instructionSet) // EIP-5656 (MCOPY opcode)
+	enable6780(&instructionSet) // EIP-6780 SELFDESTRUCT only in same transaction
+	return validate(instructionSet)
+}
+
+// newShanghaiInstructionSet returns the frontier, homestead, byzantium,
+// contantinople, istanbul, petersburg, berlin, london, merge and shanghai
+// instructions.
+func newShanghaiInstructionSet() JumpTable {
+	instructionSet := newMergeInstructionSet()
+	enable3855(&instructionSet) // PUSH0 instruction
+	return validate(instructionSet)
+}
+
 func newMergeInstructionSet() JumpTable {
 	instructionSet := newLondonInstructionSet()
 	instructionSet[RANDOM] = &operation{
@@ -109,7 +189,7 @@ func newBerlinInstructionSet() JumpTable {
 // newIstanbulInstructionSet returns the frontier, homestead, byzantium,
 // contantinople, istanbul and petersburg instructions.
 func newIstanbulInstructionSet() JumpTable {
-	instructionSet := newConstantinopleInstructionSet()
+	instructionSet := newPetersburgInstructionSet()
 
 	enable1344(&instructionSet) // ChainID opcode - https://eips.ethereum.org/EIPS/eip-1344
 	enable1884(&instructionSet) // Reprice reader opcodes - https://eips.ethereum.org/EIPS/eip-1884
@@ -118,10 +198,20 @@ func newIstanbulInstructionSet() JumpTable {
 	return validate(instructionSet)
 }
 
+// newPetersburgInstructionSet returns the frontier, homestead, byzantium,
+// contantinople and petersburg instructions, the latter reverting the net gas
+// metering of SSTORE.
+func newPetersburgInstructionSet() JumpTable {
+	instructionSet := newConstantinopleInstructionSet()
+	instructionSet[SSTORE].dynamicGas = gasSStore
+	return validate(instructionSet)
+}
+
 // newConstantinopleInstructionSet returns the frontier, homestead,
 // byzantium and contantinople instructions.
 func newConstantinopleInstructionSet() JumpTable {
 	instructionSet := newByzantiumInstructionSet()
+	instructionSet[SSTORE].dynamicGas = gasSStoreEIP1283 // Net metered SSTORE - https://eips.ethereum.org/EIPS/eip-1283
 	instructionSet[SHL] = &operation{
 		execute:     opSHL,
 		constantGas: GasFastestStep,
@@ -197,6 +287,8 @@ func newByzantiumInstructionSet() JumpTable {
 func newSpuriousDragonInstructionSet() JumpTable {
 	instructionSet := newTangerineWhistleInstructionSet()
 	instructionSet[EXP].dynamicGas = gasExpEIP158
+	instructionSet[CALL].dynamicGas = gasCallEIP158
+	instructionSet[SELFDESTRUCT].dynamicGas = gasSelfdestructEIP158
 	return validate(instructionSet)
 
 }
@@ -211,6 +303,10 @@ func newTangerineWhistleInstructionSet() JumpTable {
 	instructionSet[CALL].constantGas = params.CallGasEIP150
 	instructionSet[CALLCODE].constantGas = params.CallGasEIP150
 	instructionSet[DELEGATECALL].constantGas = params.CallGasEIP150
+	instructionSet[CALL].dynamicGas = gasCallEIP150
+	instructionSet[CALLCODE].dynamicGas = gasCallCodeEIP150
+	instructionSet[DELEGATECALL].dynamicGas = gasDelegateCallEIP150
+	instructionSet[SELFDESTRUCT].dynamicGas = gasSelfdestructEIP150
 	return validate(instructionSet)
 }
 
@@ -1038,7 +1134,7 @@ func newFrontierInstructionSet() JumpTable {
 	// Fill all unassigned slots with opUndefined.
 	for i, entry := range tbl {
 		if entry == nil {
-			tbl[i] = &operation{execute: opUndefined, maxStack: maxStack(0, 0)}
+			tbl[i] = &operation{execute: opUndefined, maxStack: maxStack(0, 0), undefined: true}
 		}
 	}
 
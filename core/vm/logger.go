@@ -42,3 +42,16 @@ type EVMLogger interface {
 	CaptureState(pc uint64, op OpCode, gas, cost uint64, scope *ScopeContext, rData []byte, depth int, err error)
 	CaptureFault(pc uint64, op OpCode, gas, cost uint64, scope *ScopeContext, depth int, err error)
 }
+
+// BlockHashLogger can be implemented by an EVMLogger to be notified whenever
+// BLOCKHASH is invoked for a block outside the window of recent blocks, which
+// yields zero instead of a hash. Numbers not fitting in 64 bits are truncated.
+type BlockHashLogger interface {
+	CaptureBlockHashOutOfRange(number uint64, current uint64)
+}
+
+// StipendLogger can be implemented by an EVMLogger to be notified whenever a
+// value transferring CALL or CALLCODE grants the callee the gas stipend.
+type StipendLogger interface {
+	CaptureCallStipend(to common.Address, stipend uint64)
+}
@@ -17,9 +17,22 @@
 package vm
 
 import (
+	"sync"
+
 	"github.com/holiman/uint256"
 )
 
+// maxPooledMemory is the capacity above which memory buffers are dropped
+// instead of being returned to the pool, so that a single memory-hungry call
+// doesn't keep a huge allocation alive.
+const maxPooledMemory = 4 * 1024 * 1024
+
+var memoryPool = sync.Pool{
+	New: func() interface{} {
+		return &Memory{}
+	},
+}
+
 // Memory implements a simple memory model for the ethereum virtual machine.
 type Memory struct {
 	store       []byte
@@ -28,7 +41,18 @@ type Memory struct {
 
 // NewMemory returns a new memory model.
 func NewMemory() *Memory {
-	return &Memory{}
+	return memoryPool.Get().(*Memory)
+}
+
+// returnMemory releases the memory back into the pool. The memory must not be
+// used (nor any slice obtained from it retained) afterwards.
+func returnMemory(m *Memory) {
+	if cap(m.store) > maxPooledMemory {
+		return
+	}
+	m.store = m.store[:0]
+	m.lastGasCost = 0
+	memoryPool.Put(m)
 }
 
 // Set sets offset + size to value
@@ -60,9 +84,20 @@ func (m *Memory) Set32(offset uint64, val *uint256.Int) {
 
 // Resize resizes the memory to size
 func (m *Memory) Resize(size uint64) {
-	if uint64(m.Len()) < size {
-		m.store = append(m.store, make([]byte, size-uint64(m.Len()))...)
+	if uint64(m.Len()) >= size {
+		return
+	}
+	if uint64(cap(m.store)) >= size {
+		// Reuse the spare capacity, which may hold stale data from a previous
+		// user of the pooled buffer.
+		stale := m.store[len(m.store):size]
+		for i := range stale {
+			stale[i] = 0
+		}
+		m.store = m.store[:size]
+		return
 	}
+	m.store = append(m.store, make([]byte, size-uint64(m.Len()))...)
 }
 
 // GetCopy returns offset + size as a new slice
@@ -81,7 +116,9 @@ func (m *Memory) GetCopy(offset, size int64) (cpy []byte) {
 	return
 }
 
-// GetPtr returns the offset + size
+// GetPtr returns the offset + size. The slice aliases the memory, which is
+// reused by other call frames once the current one ends, so it must not be
+// retained beyond that (use GetCopy instead).
 func (m *Memory) GetPtr(offset, size int64) []byte {
 	if size == 0 {
 		return nil
@@ -99,7 +136,19 @@ func (m *Memory) Len() int {
 	return len(m.store)
 }
 
-// Data returns the backing slice
+// Data returns the backing slice, which is subject to the same lifetime
+// restriction as the slices returned by GetPtr.
 func (m *Memory) Data() []byte {
 	return m.store
 }
+
+// Copy copies data from the src position slice into the dst position.
+// The source and destination may overlap.
+// OBS: This operation assumes that any necessary memory expansion has already been performed,
+// and this method may panic otherwise.
+func (m *Memory) Copy(dst, src, length uint64) {
+	if length == 0 {
+		return
+	}
+	copy(m.store[dst:], m.store[src:src+length])
+}
@@ -0,0 +1,89 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+// Tests that memory taken from the pool is cleared of any data written by a
+// previous user of the same buffer.
+func TestMemoryPoolReuse(t *testing.T) {
+	mem := NewMemory()
+	mem.Resize(64)
+	mem.Set32(0, new(uint256.Int).SetAllOne())
+	mem.Set32(32, new(uint256.Int).SetAllOne())
+	returnMemory(mem)
+
+	for i := 0; i < 4; i++ {
+		mem = NewMemory()
+		if mem.Len() != 0 || mem.lastGasCost != 0 {
+			t.Fatalf("pooled memory not reset: len %d, gas %d", mem.Len(), mem.lastGasCost)
+		}
+		mem.Resize(96)
+		if !bytes.Equal(mem.Data(), make([]byte, 96)) {
+			t.Fatalf("pooled memory not zeroed: %x", mem.Data())
+		}
+		returnMemory(mem)
+	}
+}
+
+func TestMemoryFee(t *testing.T) {
+	for _, tt := range []struct {
+		words, fee uint64
+	}{
+		{0, 0},
+		{1, 3},
+		{32, 98},
+		{1024, 5120},
+		{0xFFFFFFFF, 36028809887088637},
+	} {
+		if fee := memoryFee(tt.words); fee != tt.fee {
+			t.Errorf("words %d: fee mismatch: have %d, want %d", tt.words, fee, tt.fee)
+		}
+	}
+}
+
+var memorySink []byte
+
+// Benchmarks the memory of a call frame returning a word, freshly allocated
+// (the RETURN output aliasing it) against pooled (the output being copied).
+func BenchmarkMemoryFrame(b *testing.B) {
+	for _, size := range []uint64{256, 4096, 65536} {
+		b.Run(fmt.Sprintf("fresh-%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				mem := &Memory{}
+				mem.Resize(size)
+				memorySink = mem.GetPtr(0, 32)
+			}
+		})
+		b.Run(fmt.Sprintf("pooled-%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				mem := NewMemory()
+				mem.Resize(size)
+				memorySink = mem.GetCopy(0, 32)
+				returnMemory(mem)
+			}
+		})
+	}
+}
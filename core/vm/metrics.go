@@ -0,0 +1,38 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// opcodeTimers measure the time spent executing each defined opcode, excluding
+// gas accounting. The time of the call and create opcodes includes the
+// execution of the called code. They are only registered if expensive metrics
+// are enabled, as timing every instruction slows down execution considerably.
+var opcodeTimers [256]metrics.Timer
+
+func init() {
+	if !metrics.EnabledExpensive {
+		return
+	}
+	for op, name := range opCodeToString {
+		opcodeTimers[op] = metrics.NewRegisteredTimer("evm/opcodes/"+strings.ToLower(name), nil)
+	}
+}
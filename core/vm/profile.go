@@ -0,0 +1,59 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"time"
+)
+
+// OpcodeStat is the number of executions of an opcode and the time spent on
+// them.
+type OpcodeStat struct {
+	Count uint64
+	Time  time.Duration
+}
+
+// OpcodeProfile accumulates the executions of each opcode, indexed by opcode.
+// Like the opcode timers, it excludes gas accounting and the time of the call
+// and create opcodes includes the execution of the called code.
+type OpcodeProfile [256]OpcodeStat
+
+// record accounts one execution of op taking the given time.
+func (p *OpcodeProfile) record(op OpCode, elapsed time.Duration) {
+	p[op].Count++
+	p[op].Time += elapsed
+}
+
+// Add accumulates the stats of another profile into p, e.g. to aggregate the
+// profiles of all transactions in a block.
+func (p *OpcodeProfile) Add(other *OpcodeProfile) {
+	for i := range other {
+		p[i].Count += other[i].Count
+		p[i].Time += other[i].Time
+	}
+}
+
+// Stats returns the stats of the executed opcodes, keyed by opcode.
+func (p *OpcodeProfile) Stats() map[OpCode]OpcodeStat {
+	stats := make(map[OpCode]OpcodeStat)
+	for i, stat := range p {
+		if stat.Count > 0 {
+			stats[OpCode(i)] = stat
+		}
+	}
+	return stats
+}
@@ -17,6 +17,7 @@
 package runtime
 
 import (
+	"bytes"
 	"fmt"
 	"math/big"
 	"os"
@@ -704,6 +705,45 @@ func TestColdAccountAccessCost(t *testing.T) {
 	}
 }
 
+// answerPrecompile is a custom precompile returning 42 as a 32 byte word.
+type answerPrecompile struct{}
+
+func (answerPrecompile) RequiredGas(input []byte) uint64 { return 10 }
+func (answerPrecompile) Run(input []byte) ([]byte, error) {
+	return common.LeftPadBytes([]byte{42}, 32), nil
+}
+
+func TestCustomPrecompile(t *testing.T) {
+	precompiles := map[common.Address]vm.PrecompiledContract{
+		common.BytesToAddress([]byte{0xff}): answerPrecompile{},
+	}
+	// STATICCALL(0xff) and return its output
+	code := []byte{
+		byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.PUSH1), 0xff, byte(vm.GAS), byte(vm.STATICCALL), byte(vm.POP),
+		byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN),
+	}
+	ret, _, err := Execute(code, nil, &Config{EVMConfig: vm.Config{Precompiles: precompiles}})
+	if err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	if want := common.LeftPadBytes([]byte{42}, 32); !bytes.Equal(ret, want) {
+		t.Fatalf("output mismatch: have %x, want %x", ret, want)
+	}
+	// Custom precompiles are warm from the start, like the built-in ones
+	tracer := logger.NewStructLogger(nil)
+	Execute([]byte{byte(vm.PUSH1), 0xff, byte(vm.BALANCE), byte(vm.POP)}, nil, &Config{
+		EVMConfig: vm.Config{
+			Debug:       true,
+			Tracer:      tracer,
+			Precompiles: precompiles,
+		},
+	})
+	if have, want := tracer.StructLogs()[1].GasCost, params.WarmStorageReadCostEIP2929; have != want {
+		t.Fatalf("BALANCE gas mismatch: have %d, want %d", have, want)
+	}
+}
+
 func TestRuntimeJSTracer(t *testing.T) {
 	jsTracers := []string{
 		`{enters: 0, exits: 0, enterGas: 0, gasUsed: 0, steps:0,
@@ -76,6 +76,18 @@ func (st *Stack) peek() *uint256.Int {
 	return &st.data[st.len()-1]
 }
 
+// Push pushes a copy of d onto the stack, for use by overridden opcodes. The
+// stack bounds of the opcode are checked before it executes.
+func (st *Stack) Push(d *uint256.Int) {
+	st.push(d)
+}
+
+// Pop removes and returns the top item of the stack, for use by overridden
+// opcodes.
+func (st *Stack) Pop() uint256.Int {
+	return st.pop()
+}
+
 // Back returns the n'th item in stack
 func (st *Stack) Back(n int) *uint256.Int {
 	return &st.data[st.len()-n-1]
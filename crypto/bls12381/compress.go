@@ -0,0 +1,185 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls12381
+
+import (
+	"errors"
+)
+
+// Flags of the compressed point encoding (zcash format) in the most
+// significant bits of the first byte.
+const (
+	compressedFlag = 1 << 7 // Set for compressed encodings
+	infinityFlag   = 1 << 6 // Set for the point at infinity
+	largestFlag    = 1 << 5 // Set if y is the lexicographically largest root
+	flagsMask      = compressedFlag | infinityFlag | largestFlag
+)
+
+var (
+	errNotCompressed      = errors.New("point is not in compressed form")
+	errInvalidInfinity    = errors.New("invalid encoding of the point at infinity")
+	errNotOnCurve         = errors.New("point is not on curve")
+	errNotInSubgroup      = errors.New("point is not in correct subgroup")
+	errG1CompressedLength = errors.New("compressed g1 point must be 48 bytes")
+	errG2CompressedLength = errors.New("compressed g2 point must be 96 bytes")
+)
+
+// isLexicographicallyLargest reports whether the field element is larger than
+// its negation.
+func isLexicographicallyLargest(e *fe) bool {
+	return toBig(e).Cmp(pMinus1Over2) > 0
+}
+
+// isLexicographicallyLargest2 reports whether the quadratic extension field
+// element is larger than its negation, comparing the imaginary parts first.
+func isLexicographicallyLargest2(e *fe2) bool {
+	if !e[1].isZero() {
+		return isLexicographicallyLargest(&e[1])
+	}
+	return isLexicographicallyLargest(&e[0])
+}
+
+// decodeFlags strips the flags from a compressed encoding, returning a copy of
+// the x coordinate and whether it encodes infinity or the largest y.
+func decodeFlags(in []byte) (x []byte, infinity bool, largest bool, err error) {
+	if in[0]&compressedFlag == 0 {
+		return nil, false, false, errNotCompressed
+	}
+	infinity, largest = in[0]&infinityFlag != 0, in[0]&largestFlag != 0
+
+	x = make([]byte, len(in))
+	copy(x, in)
+	x[0] &^= flagsMask
+
+	if infinity {
+		if largest {
+			return nil, false, false, errInvalidInfinity
+		}
+		for _, b := range x {
+			if b != 0 {
+				return nil, false, false, errInvalidInfinity
+			}
+		}
+	}
+	return x, infinity, largest, nil
+}
+
+// FromCompressed constructs a new point given its 48 byte compressed encoding
+// in zcash format. The point is checked to be on the curve and in the correct
+// subgroup.
+func (g *G1) FromCompressed(in []byte) (*PointG1, error) {
+	if len(in) != 48 {
+		return nil, errG1CompressedLength
+	}
+	xBytes, infinity, largest, err := decodeFlags(in)
+	if err != nil {
+		return nil, err
+	}
+	if infinity {
+		return g.Zero(), nil
+	}
+	x, err := fromBytes(xBytes)
+	if err != nil {
+		return nil, err
+	}
+	// Recover y from the curve equation y^2 = x^3 + b
+	y, y2 := new(fe), new(fe)
+	square(y2, x)
+	mul(y2, y2, x)
+	add(y2, y2, b)
+	if !sqrt(y, y2) {
+		return nil, errNotOnCurve
+	}
+	if isLexicographicallyLargest(y) != largest {
+		neg(y, y)
+	}
+	p := &PointG1{*x, *y, *new(fe).one()}
+	if !g.InCorrectSubgroup(p) {
+		return nil, errNotInSubgroup
+	}
+	return p, nil
+}
+
+// ToCompressed serializes a point into its 48 byte compressed encoding in
+// zcash format.
+func (g *G1) ToCompressed(p *PointG1) []byte {
+	out := make([]byte, 48)
+	if g.IsZero(p) {
+		out[0] = compressedFlag | infinityFlag
+		return out
+	}
+	a := g.Affine(new(PointG1).Set(p))
+	copy(out, toBytes(&a[0]))
+	out[0] |= compressedFlag
+	if isLexicographicallyLargest(&a[1]) {
+		out[0] |= largestFlag
+	}
+	return out
+}
+
+// FromCompressed constructs a new point given its 96 byte compressed encoding
+// in zcash format. The point is checked to be on the curve and in the correct
+// subgroup.
+func (g *G2) FromCompressed(in []byte) (*PointG2, error) {
+	if len(in) != 96 {
+		return nil, errG2CompressedLength
+	}
+	xBytes, infinity, largest, err := decodeFlags(in)
+	if err != nil {
+		return nil, err
+	}
+	if infinity {
+		return g.Zero(), nil
+	}
+	x, err := g.f.fromBytes(xBytes)
+	if err != nil {
+		return nil, err
+	}
+	// Recover y from the curve equation y^2 = x^3 + b2
+	y, y2 := new(fe2), new(fe2)
+	g.f.square(y2, x)
+	g.f.mul(y2, y2, x)
+	g.f.add(y2, y2, b2)
+	if !g.f.sqrt(y, y2) {
+		return nil, errNotOnCurve
+	}
+	if isLexicographicallyLargest2(y) != largest {
+		g.f.neg(y, y)
+	}
+	p := &PointG2{*x, *y, *new(fe2).one()}
+	if !g.InCorrectSubgroup(p) {
+		return nil, errNotInSubgroup
+	}
+	return p, nil
+}
+
+// ToCompressed serializes a point into its 96 byte compressed encoding in
+// zcash format.
+func (g *G2) ToCompressed(p *PointG2) []byte {
+	out := make([]byte, 96)
+	if g.IsZero(p) {
+		out[0] = compressedFlag | infinityFlag
+		return out
+	}
+	a := g.Affine(new(PointG2).Set(p))
+	copy(out, g.f.toBytes(&a[0]))
+	out[0] |= compressedFlag
+	if isLexicographicallyLargest2(&a[1]) {
+		out[0] |= largestFlag
+	}
+	return out
+}
@@ -27,6 +27,35 @@ func (g *G1) rand() *PointG1 {
 	return g.MulScalar(&PointG1{}, g.one(), k)
 }
 
+func TestG1CompressedSerialization(t *testing.T) {
+	g1 := NewG1()
+	for i := 0; i < fuz; i++ {
+		a := g1.rand()
+		b, err := g1.FromCompressed(g1.ToCompressed(a))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !g1.Equal(a, b) {
+			t.Fatal("bad serialization compress/decompress")
+		}
+	}
+	// Generator and point at infinity
+	one := common.FromHex("0x97f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb")
+	if !bytes.Equal(g1.ToCompressed(g1.One()), one) {
+		t.Fatal("bad compressed generator")
+	}
+	if p, err := g1.FromCompressed(one); err != nil || !g1.Equal(p, g1.One()) {
+		t.Fatal("bad decompressed generator", err)
+	}
+	zero := g1.ToCompressed(g1.Zero())
+	if p, err := g1.FromCompressed(zero); err != nil || !g1.IsZero(p) {
+		t.Fatal("bad decompressed infinity", err)
+	}
+	if _, err := g1.FromCompressed(g1.ToBytes(g1.One())[:48]); err == nil {
+		t.Fatal("uncompressed encoding accepted")
+	}
+}
+
 func TestG1Serialization(t *testing.T) {
 	g1 := NewG1()
 	for i := 0; i < fuz; i++ {
@@ -0,0 +1,153 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package kzg4844 implements the KZG crypto for EIP-4844.
+package kzg4844
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+)
+
+// FieldElementsPerBlob is the number of field elements in a blob.
+const FieldElementsPerBlob = 4096
+
+// BlobHashVersion is the version byte of a KZG versioned hash.
+const BlobHashVersion = 0x01
+
+var (
+	// BLSModulus is the order of the BLS12-381 scalar field, above which no
+	// evaluation point or claimed value may lie.
+	BLSModulus, _ = new(big.Int).SetString("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001", 16)
+
+	errNoTrustedSetup    = errors.New("kzg trusted setup not loaded")
+	errInvalidScalar     = errors.New("scalar is not a canonical field element")
+	errInvalidProof      = errors.New("invalid kzg proof")
+	errInvalidSetupPoint = errors.New("trusted setup is missing the [τ]G2 point")
+)
+
+// Commitment is a serialized commitment to a polynomial.
+type Commitment [48]byte
+
+// Proof is a serialized commitment to the quotient polynomial.
+type Proof [48]byte
+
+// Point is a BLS field element.
+type Point [32]byte
+
+// Claim is a claimed evaluation value in a specific point.
+type Claim [32]byte
+
+// trustedSetup is the subset of the ceremony output needed to verify proofs.
+var trustedSetup struct {
+	tau  *bls12381.PointG2 // [τ]G2 from the monomial form of the setup
+	lock sync.RWMutex
+}
+
+// setupJSON is the on-disk format of the trusted setup, as published by the
+// KZG ceremony. Only the monomial G2 points are needed for verification.
+type setupJSON struct {
+	G2Monomial []hexutil.Bytes `json:"g2_monomial"`
+}
+
+// LoadTrustedSetup reads a JSON encoded KZG trusted setup and installs it as
+// the one used to verify proofs. The mainnet ceremony output is not bundled
+// and needs to be loaded before any point evaluation can succeed.
+func LoadTrustedSetup(r io.Reader) error {
+	var setup setupJSON
+	if err := json.NewDecoder(r).Decode(&setup); err != nil {
+		return fmt.Errorf("invalid trusted setup: %v", err)
+	}
+	if len(setup.G2Monomial) < 2 {
+		return errInvalidSetupPoint
+	}
+	tau, err := bls12381.NewG2().FromCompressed(setup.G2Monomial[1])
+	if err != nil {
+		return fmt.Errorf("invalid trusted setup: %v", err)
+	}
+	trustedSetup.lock.Lock()
+	trustedSetup.tau = tau
+	trustedSetup.lock.Unlock()
+	return nil
+}
+
+// CalcBlobHashV1 calculates the 'versioned blob hash' of a commitment.
+// The given hasher must be a sha256 hash instance, otherwise the result will be invalid!
+func CalcBlobHashV1(hasher hash.Hash, commit *Commitment) (vh common.Hash) {
+	if hasher.Size() != 32 {
+		panic("wrong hash size")
+	}
+	hasher.Reset()
+	hasher.Write(commit[:])
+	hasher.Sum(vh[:0])
+	vh[0] = BlobHashVersion
+	return vh
+}
+
+// IsValidVersionedHash checks that h is a structurally-valid versioned blob hash.
+func IsValidVersionedHash(h []byte) bool {
+	return len(h) == 32 && h[0] == BlobHashVersion
+}
+
+// VerifyProof verifies the KZG proof that the polynomial represented by the
+// commitment evaluates to the claimed value at the given point, i.e. checks
+// that e(C - [y]G1, G2) == e(π, [τ - z]G2).
+func VerifyProof(commitment Commitment, point Point, claim Claim, proof Proof) error {
+	trustedSetup.lock.RLock()
+	tau := trustedSetup.tau
+	trustedSetup.lock.RUnlock()
+	if tau == nil {
+		return errNoTrustedSetup
+	}
+	z, y := new(big.Int).SetBytes(point[:]), new(big.Int).SetBytes(claim[:])
+	if z.Cmp(BLSModulus) >= 0 || y.Cmp(BLSModulus) >= 0 {
+		return errInvalidScalar
+	}
+	g1, g2 := bls12381.NewG1(), bls12381.NewG2()
+
+	c, err := g1.FromCompressed(commitment[:])
+	if err != nil {
+		return fmt.Errorf("invalid commitment: %v", err)
+	}
+	pi, err := g1.FromCompressed(proof[:])
+	if err != nil {
+		return fmt.Errorf("invalid proof: %v", err)
+	}
+	// C - [y]G1
+	lhs := g1.MulScalar(g1.New(), g1.One(), y)
+	g1.Sub(lhs, c, lhs)
+
+	// [τ]G2 - [z]G2
+	rhs := g2.MulScalar(g2.New(), g2.One(), z)
+	g2.Sub(rhs, tau, rhs)
+
+	engine := bls12381.NewPairingEngine()
+	engine.AddPair(lhs, g2.One())
+	engine.AddPairInv(pi, rhs)
+	if !engine.Check() {
+		return errInvalidProof
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kzg4844
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+)
+
+// insecureSetup installs a trusted setup with a publicly known τ, which is
+// only good enough to produce proofs for tests.
+func insecureSetup(t *testing.T, tau *big.Int) {
+	g2 := bls12381.NewG2()
+	point := g2.ToCompressed(g2.MulScalar(g2.New(), g2.One(), tau))
+	setup := fmt.Sprintf(`{"g2_monomial": ["%s", "%s"]}`, hexutil.Encode(g2.ToCompressed(g2.One())), hexutil.Encode(point))
+	if err := LoadTrustedSetup(strings.NewReader(setup)); err != nil {
+		t.Fatalf("failed to load setup: %v", err)
+	}
+}
+
+// commitLinear commits to p(x) = a + b*x and opens it at z, returning the
+// commitment, the claimed value p(z) and the proof.
+func commitLinear(tau, a, b, z *big.Int) (Commitment, Claim, Proof) {
+	g1 := bls12381.NewG1()
+
+	pTau := new(big.Int).Mul(b, tau)
+	pTau.Add(pTau, a).Mod(pTau, BLSModulus)
+	y := new(big.Int).Mul(b, z)
+	y.Add(y, a).Mod(y, BLSModulus)
+
+	var (
+		commitment Commitment
+		claim      Claim
+		proof      Proof
+	)
+	copy(commitment[:], g1.ToCompressed(g1.MulScalar(g1.New(), g1.One(), pTau)))
+	copy(proof[:], g1.ToCompressed(g1.MulScalar(g1.New(), g1.One(), b)))
+	y.FillBytes(claim[:])
+	return commitment, claim, proof
+}
+
+func TestVerifyProof(t *testing.T) {
+	var (
+		tau = big.NewInt(1337)
+		a   = big.NewInt(42)
+		b   = big.NewInt(7)
+		z   = big.NewInt(5)
+	)
+	insecureSetup(t, tau)
+
+	commitment, claim, proof := commitLinear(tau, a, b, z)
+	var point Point
+	z.FillBytes(point[:])
+
+	if err := VerifyProof(commitment, point, claim, proof); err != nil {
+		t.Fatalf("valid proof rejected: %v", err)
+	}
+	// A wrong claim must not verify
+	bad := claim
+	bad[31] ^= 1
+	if err := VerifyProof(commitment, point, bad, proof); err == nil {
+		t.Fatal("invalid claim accepted")
+	}
+	// Out-of-field points must be rejected
+	var over Point
+	BLSModulus.FillBytes(over[:])
+	if err := VerifyProof(commitment, over, claim, proof); err != errInvalidScalar {
+		t.Fatalf("out of field point: have %v, want %v", err, errInvalidScalar)
+	}
+}
+
+func TestCalcBlobHashV1(t *testing.T) {
+	var commitment Commitment
+	commitment[0] = 0xc0 // compressed point at infinity
+
+	vh := CalcBlobHashV1(sha256.New(), &commitment)
+	if !IsValidVersionedHash(vh[:]) {
+		t.Fatalf("invalid versioned hash: %x", vh)
+	}
+	want := sha256.Sum256(commitment[:])
+	if string(vh[1:]) != string(want[1:]) {
+		t.Fatalf("hash mismatch: have %x, want %x", vh, want)
+	}
+}
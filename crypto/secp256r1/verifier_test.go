@@ -0,0 +1,54 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package secp256r1
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	hash := sha256.Sum256([]byte("hello world"))
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if !Verify(hash[:], r, s, key.X, key.Y) {
+		t.Fatal("valid signature rejected")
+	}
+	// Tampered hash
+	other := sha256.Sum256([]byte("goodbye world"))
+	if Verify(other[:], r, s, key.X, key.Y) {
+		t.Fatal("signature of another hash accepted")
+	}
+	// Public key off the curve
+	if Verify(hash[:], r, s, key.X, new(big.Int).Add(key.Y, big.NewInt(1))) {
+		t.Fatal("public key off the curve accepted")
+	}
+	// Point at infinity
+	if Verify(hash[:], r, s, new(big.Int), new(big.Int)) {
+		t.Fatal("point at infinity accepted")
+	}
+}
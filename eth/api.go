@@ -19,6 +19,7 @@ package eth
 import (
 	"compress/gzip"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -322,9 +323,11 @@ func (api *PrivateDebugAPI) Preimage(ctx context.Context, hash common.Hash) (hex
 
 // BadBlockArgs represents the entries in the list returned when bad blocks are queried.
 type BadBlockArgs struct {
-	Hash  common.Hash            `json:"hash"`
-	Block map[string]interface{} `json:"block"`
-	RLP   string                 `json:"rlp"`
+	Hash     common.Hash            `json:"hash"`
+	Block    map[string]interface{} `json:"block"`
+	RLP      string                 `json:"rlp"`
+	Receipts types.Receipts         `json:"receipts,omitempty"` // Receipts computed locally, if any
+	Report   json.RawMessage        `json:"report,omitempty"`   // Summary of the failure, see core.BadBlockReport
 }
 
 // GetBadBlocks returns a list of the last 'bad blocks' that the client has seen on the network
@@ -348,10 +351,13 @@ func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]*BadBlockArgs,
 		if blockJSON, err = ethapi.RPCMarshalBlock(block, true, true, api.eth.APIBackend.ChainConfig()); err != nil {
 			blockJSON = map[string]interface{}{"error": err.Error()}
 		}
+		receipts, report := rawdb.ReadBadBlockReport(api.eth.chainDb, block.Hash())
 		results = append(results, &BadBlockArgs{
-			Hash:  block.Hash(),
-			RLP:   blockRlp,
-			Block: blockJSON,
+			Hash:     block.Hash(),
+			RLP:      blockRlp,
+			Block:    blockJSON,
+			Receipts: receipts,
+			Report:   report,
 		})
 	}
 	return results, nil
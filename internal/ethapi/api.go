@@ -954,12 +954,8 @@ func DoCall(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash
 	if err != nil {
 		return nil, err
 	}
-	// Wait for the context to be done and cancel the evm. Even if the
-	// EVM has finished, cancelling may be done (repeatedly)
-	go func() {
-		<-ctx.Done()
-		evm.Cancel()
-	}()
+	// Cancel the evm once the context is done
+	defer evm.CancelWith(ctx)()
 
 	// Execute the message.
 	gp := new(core.GasPool).AddGas(math.MaxUint64)
@@ -1139,13 +1135,46 @@ func DoEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNr
 				}
 				return 0, result.Err
 			}
-			// Otherwise, the specified gas cap is too low
+			// Otherwise, the specified gas cap is too low. If the call would
+			// succeed within the block gas limit, report the gas it needs.
+			if needed, ok := requiredGasAboveCap(ctx, b, args, blockNrOrHash, cap); ok {
+				return 0, fmt.Errorf("gas required exceeds allowance (%d), would need %d", cap, needed)
+			}
 			return 0, fmt.Errorf("gas required exceeds allowance (%d)", cap)
 		}
 	}
 	return hexutil.Uint64(hi), nil
 }
 
+// requiredGasAboveCap searches for the gas a call failing with the allowance
+// cap would need to succeed, ignoring the RPC gas cap but staying within the
+// block gas limit. It returns false if the call fails even at the block limit.
+func requiredGasAboveCap(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, cap uint64) (uint64, bool) {
+	header, err := b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil || header == nil || header.GasLimit <= cap {
+		return 0, false
+	}
+	executable := func(gas uint64) bool {
+		args.Gas = (*hexutil.Uint64)(&gas)
+
+		result, err := DoCall(ctx, b, args, blockNrOrHash, nil, 0, 0)
+		return err == nil && !result.Failed()
+	}
+	if !executable(header.GasLimit) {
+		return 0, false
+	}
+	lo, hi := cap, header.GasLimit
+	for lo+1 < hi {
+		mid := (hi + lo) / 2
+		if executable(mid) {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi, true
+}
+
 // EstimateGas returns an estimate of the amount of gas needed to execute the
 // given transaction against the current pending block.
 func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash) (hexutil.Uint64, error) {
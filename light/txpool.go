@@ -67,8 +67,10 @@ type TxPool struct {
 	mined        map[common.Hash][]*types.Transaction // mined transactions by block hash
 	clearIdx     uint64                               // earliest block nr that can contain mined tx info
 
-	istanbul bool // Fork indicator whether we are in the istanbul stage.
+	shanghai bool // Fork indicator whether we are in the shanghai stage.
 	eip2718  bool // Fork indicator whether we are in the eip2718 stage.
+
+	gasTable params.GasTable // Intrinsic gas prices of the next block
 }
 
 // TxRelayBackend provides an interface to the mechanism that forwards transacions
@@ -313,8 +315,9 @@ func (pool *TxPool) setNewHead(head *types.Header) {
 
 	// Update fork indicator by next pending block number
 	next := new(big.Int).Add(head.Number, big.NewInt(1))
-	pool.istanbul = pool.config.IsIstanbul(next)
+	pool.shanghai = pool.config.IsShanghai(next)
 	pool.eip2718 = pool.config.IsBerlin(next)
+	pool.gasTable = pool.config.IntrinsicGasTable(next)
 }
 
 // Stop stops the light transaction pool
@@ -382,7 +385,7 @@ func (pool *TxPool) validateTx(ctx context.Context, tx *types.Transaction) error
 	}
 
 	// Should supply enough intrinsic gas
-	gas, err := core.IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, true, pool.istanbul)
+	gas, err := core.IntrinsicGasWithTable(pool.gasTable, tx.Data(), tx.AccessList(), tx.SetCodeAuthorizations(), tx.To() == nil, pool.shanghai)
 	if err != nil {
 		return err
 	}
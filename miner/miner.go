@@ -54,6 +54,9 @@ type Config struct {
 	GasPrice   *big.Int       // Minimum gas price for mining a transaction
 	Recommit   time.Duration  // The time interval for miner to re-create mining work.
 	Noverify   bool           // Disable remote mining solution verification(only useful in ethash).
+
+	DenylistedRecipients map[common.Address]bool `toml:",omitempty"` // Recipients whose transactions are left out of mined blocks
+	DenyInternalCalls    bool                    // Also leave out transactions calling into denylisted recipients
 }
 
 // Miner creates blocks and searches for proof-of-work values.
@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
@@ -832,10 +833,17 @@ func (w *worker) updateSnapshot(env *environment) {
 	w.snapshotState = env.state.Copy()
 }
 
-func (w *worker) commitTransaction(env *environment, tx *types.Transaction) ([]*types.Log, error) {
+func (w *worker) commitTransaction(env *environment, tx *types.Transaction, evm *vm.EVM) ([]*types.Log, error) {
+	msg, err := tx.AsMessage(env.signer, env.header.BaseFee)
+	if err != nil {
+		return nil, err
+	}
+	if to := tx.To(); to != nil && w.config.DenylistedRecipients[*to] {
+		return nil, fmt.Errorf("%w: address %v", core.ErrRecipientDenied, to.Hex())
+	}
 	snap := env.state.Snapshot()
 
-	receipt, err := core.ApplyTransaction(w.chainConfig, w.chain, &env.coinbase, env.gasPool, env.state, env.header, tx, &env.header.GasUsed, *w.chain.GetVMConfig())
+	receipt, err := core.ApplyTransactionWithEVM(msg, w.chainConfig, env.gasPool, env.state, env.header.Number, env.header.Hash(), tx, &env.header.GasUsed, evm)
 	if err != nil {
 		env.state.RevertToSnapshot(snap)
 		return nil, err
@@ -853,6 +861,12 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 	}
 	var coalescedLogs []*types.Log
 
+	// Share a single EVM across all transactions of the batch
+	vmConfig := *w.chain.GetVMConfig()
+	vmConfig.DenylistedRecipients = w.config.DenylistedRecipients
+	vmConfig.DenyInternalCalls = w.config.DenyInternalCalls
+	evm := vm.NewEVM(core.NewEVMBlockContext(env.header, w.chain, &env.coinbase), vm.TxContext{}, env.state, w.chainConfig, vmConfig)
+
 	for {
 		// In the following three cases, we will interrupt the execution of the transaction.
 		// (1) new head block event arrival, the interrupt signal is 1
@@ -901,7 +915,7 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 		// Start executing the transaction
 		env.state.Prepare(tx.Hash(), env.tcount)
 
-		logs, err := w.commitTransaction(env, tx)
+		logs, err := w.commitTransaction(env, tx, evm)
 		switch {
 		case errors.Is(err, core.ErrGasLimitReached):
 			// Pop the current out-of-gas transaction without shifting in the next from the account
@@ -929,6 +943,11 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 			log.Trace("Skipping unsupported transaction type", "sender", from, "type", tx.Type())
 			txs.Pop()
 
+		case errors.Is(err, core.ErrRecipientDenied):
+			// Pop the denied transaction without shifting in the next from the account
+			log.Trace("Skipping transaction to denylisted recipient", "sender", from, "hash", tx.Hash(), "err", err)
+			txs.Pop()
+
 		default:
 			// Strange error, discard the transaction and get the next in line (note, the
 			// nonce-too-high clause will prevent us from executing in vain).
@@ -1020,6 +1039,16 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 			header.GasLimit = core.CalcGasLimit(parentGasLimit, w.config.GasCeil)
 		}
 	}
+	// Set the blob gas fields if we are on a Cancun chain, no blob transactions
+	// are included by the miner
+	if w.chainConfig.IsCancun(header.Number) {
+		var parentExcessBlobGas, parentBlobGasUsed uint64
+		if excess, used := parent.Header().ExcessBlobGas, parent.Header().BlobGasUsed; excess != nil && used != nil {
+			parentExcessBlobGas, parentBlobGasUsed = *excess, *used
+		}
+		excessBlobGas := misc.CalcExcessBlobGas(parentExcessBlobGas, parentBlobGasUsed)
+		header.ExcessBlobGas, header.BlobGasUsed = &excessBlobGas, new(uint64)
+	}
 	// Run the consensus preparation with the default or customized consensus engine.
 	if err := w.engine.Prepare(w.chain, header); err != nil {
 		log.Error("Failed to prepare header for sealing", "err", err)
@@ -1033,6 +1062,11 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 		log.Error("Failed to create sealing context", "err", err)
 		return nil, err
 	}
+	// Mutate the state according to any hard-fork specs, and update the system
+	// contracts before any transaction is included
+	core.ApplyIrregularStateChanges(w.chainConfig, header.Number, env.state)
+	core.ApplySystemCalls(w.chainConfig, header, core.NewEVMBlockContext(header, w.chain, &env.coinbase), env.state, *w.chain.GetVMConfig())
+
 	// Accumulate the uncles for the sealing work only if it's allowed.
 	if !genParams.noUncle {
 		commitUncles := func(blocks map[common.Hash]*types.Block) {
@@ -1094,7 +1128,7 @@ func (w *worker) generateWork(params *generateParams) (*types.Block, error) {
 	if !params.noTxs {
 		w.fillTransactions(nil, work)
 	}
-	return w.engine.FinalizeAndAssemble(w.chain, work.header, work.state, work.txs, work.unclelist(), work.receipts)
+	return w.engine.FinalizeAndAssemble(w.chain, work.header, work.state, work.txs, work.unclelist(), work.receipts, nil)
 }
 
 // commitWork generates several new sealing tasks based on the parent block
@@ -1152,7 +1186,7 @@ func (w *worker) commit(env *environment, interval func(), update bool, start ti
 		// Create a local environment copy, avoid the data race with snapshot state.
 		// https://github.com/ethereum/go-ethereum/issues/24299
 		env := env.copy()
-		block, err := w.engine.FinalizeAndAssemble(w.chain, env.header, env.state, env.txs, env.unclelist(), env.receipts)
+		block, err := w.engine.FinalizeAndAssemble(w.chain, env.header, env.state, env.txs, env.unclelist(), env.receipts, nil)
 		if err != nil {
 			return err
 		}
@@ -17,6 +17,7 @@
 package miner
 
 import (
+	"crypto/ecdsa"
 	"errors"
 	"math/big"
 	"math/rand"
@@ -671,3 +672,74 @@ func testGetSealingWork(t *testing.T, chainConfig *params.ChainConfig, engine co
 		}
 	}
 }
+
+// TestDenylistedRecipients tests that the miner leaves transactions sent to or
+// calling into denylisted recipients out of its blocks, while blocks of other
+// nodes including such transactions are still imported.
+func TestDenylistedRecipients(t *testing.T) {
+	var (
+		engine = ethash.NewFaker()
+		denied = common.HexToAddress("0xdead")
+		config = *testConfig
+
+		// Init code executing CALL(GAS, 0xdead, 0, 0, 0, 0, 0)
+		callDenied = common.FromHex("0x6000600060006000600061dead5af100")
+
+		otherKey, _  = crypto.GenerateKey()
+		otherAddress = crypto.PubkeyToAddress(otherKey.PublicKey)
+		gasPrice     = big.NewInt(10 * params.InitialBaseFee)
+	)
+	defer engine.Close()
+
+	config.DenylistedRecipients = map[common.Address]bool{denied: true}
+	config.DenyInternalCalls = true
+
+	b := newTestWorkerBackend(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	w := newWorker(&config, ethashChainConfig, engine, b, new(event.TypeMux), nil, false)
+	defer w.close()
+
+	signer := types.LatestSigner(ethashChainConfig)
+	sign := func(key *ecdsa.PrivateKey, nonce uint64, to *common.Address, value *big.Int, data []byte) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: nonce, To: to, Value: value, Gas: 100000, GasPrice: gasPrice, Data: data})
+	}
+	// Blocks including transactions to denylisted recipients are imported
+	funds := big.NewInt(params.Ether / 10)
+	blocks, _ := core.GenerateChain(ethashChainConfig, b.chain.Genesis(), engine, b.db, 1, func(i int, gen *core.BlockGen) {
+		gen.AddTx(sign(testBankKey, 0, &denied, big.NewInt(1), nil))
+		gen.AddTx(sign(testBankKey, 1, nil, new(big.Int), callDenied))
+		gen.AddTx(sign(testBankKey, 2, &testUserAddress, funds, nil))
+		gen.AddTx(sign(testBankKey, 3, &otherAddress, funds, nil))
+	})
+	if _, err := b.chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import block with denylisted recipients: %v", err)
+	}
+	statedb, _ := b.chain.State()
+	if balance := statedb.GetBalance(denied); balance.Cmp(big.NewInt(1)) != 0 {
+		t.Fatalf("denylisted recipient balance mismatch: have %v, want 1", balance)
+	}
+	for b.txPool.Nonce(testBankAddress) != 4 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Mined blocks only include the transactions not touching them
+	allowed := sign(otherKey, 0, &testUserAddress, big.NewInt(1), nil)
+	for _, err := range b.txPool.AddLocals([]*types.Transaction{
+		sign(testBankKey, 4, &denied, big.NewInt(1), nil),
+		sign(testUserKey, 0, nil, new(big.Int), callDenied),
+		allowed,
+	}) {
+		if err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	for pending, _ := b.txPool.Stats(); pending != 3; pending, _ = b.txPool.Stats() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	resCh, errCh, _ := w.getSealingBlock(b.chain.CurrentBlock().Hash(), uint64(time.Now().Unix()), testBankAddress, common.Hash{}, false)
+	block := <-resCh
+	if err := <-errCh; err != nil {
+		t.Fatalf("failed to generate block: %v", err)
+	}
+	if txs := block.Transactions(); len(txs) != 1 || txs[0].Hash() != allowed.Hash() {
+		t.Fatalf("mined transactions mismatch: have %d, want only %x", len(txs), allowed.Hash())
+	}
+}
@@ -259,16 +259,16 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, new(EthashConfig), nil}
+	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil, nil, 0, 0, nil, nil, new(EthashConfig), nil}
 
 	// AllCliqueProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Ethereum core developers into the Clique consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil, nil, nil, 0, 0, nil, nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, new(EthashConfig), nil}
+	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil, nil, 0, 0, nil, nil, new(EthashConfig), nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int), false)
 )
 
@@ -358,24 +358,113 @@ type ChainConfig struct {
 	LondonBlock         *big.Int `json:"londonBlock,omitempty"`         // London switch block (nil = no fork, 0 = already on london)
 	ArrowGlacierBlock   *big.Int `json:"arrowGlacierBlock,omitempty"`   // Eip-4345 (bomb delay) switch block (nil = no fork, 0 = already activated)
 	MergeNetsplitBlock  *big.Int `json:"mergeNetsplitBlock,omitempty"`  // Virtual fork after The Merge to use as a network splitter
+	ShanghaiBlock       *big.Int `json:"shanghaiBlock,omitempty"`       // Shanghai switch block (nil = no fork, 0 = already on shanghai)
+	CancunBlock         *big.Int `json:"cancunBlock,omitempty"`         // Cancun switch block (nil = no fork, 0 = already on cancun)
+	PragueBlock         *big.Int `json:"pragueBlock,omitempty"`         // Prague switch block (nil = no fork, 0 = already on prague)
+	OsakaBlock          *big.Int `json:"osakaBlock,omitempty"`          // Osaka switch block (nil = no fork, 0 = already on osaka)
+	EOFBlock            *big.Int `json:"eofBlock,omitempty"`            // EVM Object Format (EIP-3540/3670) switch block (nil = no fork, 0 = already activated)
 
 	// TerminalTotalDifficulty is the amount of total difficulty reached by
 	// the network that triggers the consensus upgrade.
 	TerminalTotalDifficulty *big.Int `json:"terminalTotalDifficulty,omitempty"`
 
+	// GasTable, if set, reprices the intrinsic gas of transactions, allowing
+	// private networks to deviate from the protocol's schedule.
+	GasTable *GasTable `json:"gasTable,omitempty"`
+
+	// MaxCodeSize and MaxInitCodeSize override the size limits of deployed
+	// contract code (EIP-170) and of init code (EIP-3860), allowing private
+	// networks to raise them (0 = protocol default). The init code limit
+	// defaults to twice the code size limit.
+	MaxCodeSize     uint64 `json:"maxCodeSize,omitempty"`
+	MaxInitCodeSize uint64 `json:"maxInitCodeSize,omitempty"`
+
+	// P256VerifyBlock enables the secp256r1 signature verification precompile
+	// of RIP-7212 from the given block on. It is not part of any mainnet fork,
+	// but an optional feature for rollups and private networks opting into it
+	// (nil = disabled).
+	P256VerifyBlock *big.Int `json:"p256VerifyBlock,omitempty"`
+
+	// IrregularStateChanges schedules state changes outside of the regular state
+	// transition rules, keyed by the block number at the start of which they are
+	// applied, allowing private networks and L2s to e.g. upgrade contracts. Not
+	// being serializable, they have to be set programmatically.
+	IrregularStateChanges map[uint64]IrregularStateChange `json:"-"`
+
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
 }
 
+// IrregularStateChange modifies the state at the start of a block, before any
+// of its transactions, outside of the regular state transition rules. The DAO
+// hard-fork is the canonical example.
+type IrregularStateChange func(statedb IrregularStateDB)
+
+// IrregularStateDB is the state access available to irregular state changes.
+type IrregularStateDB interface {
+	CreateAccount(common.Address)
+	Exist(common.Address) bool
+
+	GetBalance(common.Address) *big.Int
+	AddBalance(common.Address, *big.Int)
+	SubBalance(common.Address, *big.Int)
+	SetBalance(common.Address, *big.Int)
+
+	GetNonce(common.Address) uint64
+	SetNonce(common.Address, uint64)
+
+	GetCode(common.Address) []byte
+	SetCode(common.Address, []byte)
+
+	GetState(common.Address, common.Hash) common.Hash
+	SetState(common.Address, common.Hash, common.Hash)
+}
+
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
-type EthashConfig struct{}
+type EthashConfig struct {
+	// Rewards overrides the mining rewards from the given fork blocks onwards,
+	// allowing private networks to change their issuance. The override with
+	// the highest fork block not above a block's number applies to it.
+	Rewards []*EthashReward `json:"rewards,omitempty"`
+}
+
+// EthashReward is the issuance of a proof-of-work chain from a fork block on.
+type EthashReward struct {
+	Block       *big.Int        `json:"block"`               // Fork block the override applies from
+	BlockReward *big.Int        `json:"blockReward"`         // Static reward for mining a block, uncle rewards are derived from it
+	Recipient   *common.Address `json:"recipient,omitempty"` // Account credited with the block rewards instead of the coinbase, if set
+}
+
+// RewardAt returns the reward override in effect at the given block number, or
+// nil if the default issuance applies.
+func (c *EthashConfig) RewardAt(num *big.Int) *EthashReward {
+	var active *EthashReward
+	for _, reward := range c.Rewards {
+		if isForked(reward.Block, num) && (active == nil || reward.Block.Cmp(active.Block) > 0) {
+			active = reward
+		}
+	}
+	return active
+}
 
 // String implements the stringer interface, returning the consensus engine details.
 func (c *EthashConfig) String() string {
 	return "ethash"
 }
 
+// GasTable holds the intrinsic gas prices of transactions. Within a chain config
+// it overrides the protocol's prices from Block onwards; zero prices keep the
+// price of the active fork.
+type GasTable struct {
+	Block *big.Int `json:"block,omitempty"` // Activation block (nil = 0)
+
+	TxGas                 uint64 `json:"txGas,omitempty"`                 // Per transaction not creating a contract
+	TxGasContractCreation uint64 `json:"txGasContractCreation,omitempty"` // Per transaction creating a contract
+	TxDataZeroGas         uint64 `json:"txDataZeroGas,omitempty"`         // Per zero byte of transaction data
+	TxDataNonZeroGas      uint64 `json:"txDataNonZeroGas,omitempty"`      // Per non-zero byte of transaction data
+}
+
 // CliqueConfig is the consensus engine configs for proof-of-authority based sealing.
 type CliqueConfig struct {
 	Period uint64 `json:"period"` // Number of seconds between blocks to enforce
@@ -438,6 +527,24 @@ func (c *ChainConfig) String() string {
 	if c.ArrowGlacierBlock != nil {
 		banner += fmt.Sprintf(" - Arrow Glacier:               %-8v (https://github.com/ethereum/execution-specs/blob/master/network-upgrades/mainnet-upgrades/arrow-glacier.md)\n", c.ArrowGlacierBlock)
 	}
+	if c.ShanghaiBlock != nil {
+		banner += fmt.Sprintf(" - Shanghai:                    %-8v (https://eips.ethereum.org/EIPS/eip-3860)\n", c.ShanghaiBlock)
+	}
+	if c.CancunBlock != nil {
+		banner += fmt.Sprintf(" - Cancun:                      %-8v (https://eips.ethereum.org/EIPS/eip-4844)\n", c.CancunBlock)
+	}
+	if c.PragueBlock != nil {
+		banner += fmt.Sprintf(" - Prague:                      %-8v (https://eips.ethereum.org/EIPS/eip-7702)\n", c.PragueBlock)
+	}
+	if c.OsakaBlock != nil {
+		banner += fmt.Sprintf(" - Osaka:                       %-8v (https://eips.ethereum.org/EIPS/eip-7825)\n", c.OsakaBlock)
+	}
+	if c.EOFBlock != nil {
+		banner += fmt.Sprintf(" - EVM Object Format:           %-8v (https://eips.ethereum.org/EIPS/eip-3540)\n", c.EOFBlock)
+	}
+	if c.P256VerifyBlock != nil {
+		banner += fmt.Sprintf(" - P256 verification:           %-8v (https://github.com/ethereum/RIPs/blob/master/RIPS/rip-7212.md)\n", c.P256VerifyBlock)
+	}
 	banner += "\n"
 
 	// Add a special section for the merge as it's non-obvious
@@ -520,6 +627,86 @@ func (c *ChainConfig) IsArrowGlacier(num *big.Int) bool {
 	return isForked(c.ArrowGlacierBlock, num)
 }
 
+// IntrinsicGasTable returns the intrinsic gas prices in force at block num: the
+// prices of the active fork, overridden by the configured GasTable if any.
+func (c *ChainConfig) IntrinsicGasTable(num *big.Int) GasTable {
+	table := GasTable{
+		TxGas:                 TxGas,
+		TxGasContractCreation: TxGas,
+		TxDataZeroGas:         TxDataZeroGas,
+		TxDataNonZeroGas:      TxDataNonZeroGasFrontier,
+	}
+	if c.IsHomestead(num) {
+		table.TxGasContractCreation = TxGasContractCreation
+	}
+	if c.IsIstanbul(num) {
+		table.TxDataNonZeroGas = TxDataNonZeroGasEIP2028
+	}
+	if g := c.GasTable; g != nil && (g.Block == nil || isForked(g.Block, num)) {
+		if g.TxGas != 0 {
+			table.TxGas = g.TxGas
+		}
+		if g.TxGasContractCreation != 0 {
+			table.TxGasContractCreation = g.TxGasContractCreation
+		}
+		if g.TxDataZeroGas != 0 {
+			table.TxDataZeroGas = g.TxDataZeroGas
+		}
+		if g.TxDataNonZeroGas != 0 {
+			table.TxDataNonZeroGas = g.TxDataNonZeroGas
+		}
+	}
+	return table
+}
+
+// IsShanghai returns whether num is either equal to the Shanghai fork block or greater.
+func (c *ChainConfig) IsShanghai(num *big.Int) bool {
+	return isForked(c.ShanghaiBlock, num)
+}
+
+// IsCancun returns whether num is either equal to the Cancun fork block or greater.
+func (c *ChainConfig) IsCancun(num *big.Int) bool {
+	return isForked(c.CancunBlock, num)
+}
+
+// IsPrague returns whether num is either equal to the Prague fork block or greater.
+func (c *ChainConfig) IsPrague(num *big.Int) bool {
+	return isForked(c.PragueBlock, num)
+}
+
+// IsOsaka returns whether num is either equal to the Osaka fork block or greater.
+func (c *ChainConfig) IsOsaka(num *big.Int) bool {
+	return isForked(c.OsakaBlock, num)
+}
+
+// CodeSizeLimit returns the maximum size of deployed contract code.
+func (c *ChainConfig) CodeSizeLimit() int {
+	if c.MaxCodeSize != 0 {
+		return int(c.MaxCodeSize)
+	}
+	return MaxCodeSize
+}
+
+// InitCodeSizeLimit returns the maximum size of init code.
+func (c *ChainConfig) InitCodeSizeLimit() int {
+	if c.MaxInitCodeSize != 0 {
+		return int(c.MaxInitCodeSize)
+	}
+	return 2 * c.CodeSizeLimit()
+}
+
+// IsEOF returns whether num is either equal to the EVM Object Format fork block
+// or greater.
+func (c *ChainConfig) IsEOF(num *big.Int) bool {
+	return isForked(c.EOFBlock, num)
+}
+
+// IsP256Verify returns whether num is either equal to the block enabling the
+// secp256r1 verification precompile or greater.
+func (c *ChainConfig) IsP256Verify(num *big.Int) bool {
+	return isForked(c.P256VerifyBlock, num)
+}
+
 // IsTerminalPoWBlock returns whether the given block is the last block of PoW stage.
 func (c *ChainConfig) IsTerminalPoWBlock(parentTotalDiff *big.Int, totalDiff *big.Int) bool {
 	if c.TerminalTotalDifficulty == nil {
@@ -570,6 +757,11 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 		{name: "londonBlock", block: c.LondonBlock},
 		{name: "arrowGlacierBlock", block: c.ArrowGlacierBlock, optional: true},
 		{name: "mergeNetsplitBlock", block: c.MergeNetsplitBlock, optional: true},
+		{name: "shanghaiBlock", block: c.ShanghaiBlock, optional: true},
+		{name: "cancunBlock", block: c.CancunBlock, optional: true},
+		{name: "pragueBlock", block: c.PragueBlock, optional: true},
+		{name: "osakaBlock", block: c.OsakaBlock, optional: true},
+		{name: "eofBlock", block: c.EOFBlock, optional: true},
 	} {
 		if lastFork.name != "" {
 			// Next one must be higher number
@@ -645,6 +837,24 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.MergeNetsplitBlock, newcfg.MergeNetsplitBlock, head) {
 		return newCompatError("Merge netsplit fork block", c.MergeNetsplitBlock, newcfg.MergeNetsplitBlock)
 	}
+	if isForkIncompatible(c.ShanghaiBlock, newcfg.ShanghaiBlock, head) {
+		return newCompatError("Shanghai fork block", c.ShanghaiBlock, newcfg.ShanghaiBlock)
+	}
+	if isForkIncompatible(c.CancunBlock, newcfg.CancunBlock, head) {
+		return newCompatError("Cancun fork block", c.CancunBlock, newcfg.CancunBlock)
+	}
+	if isForkIncompatible(c.PragueBlock, newcfg.PragueBlock, head) {
+		return newCompatError("Prague fork block", c.PragueBlock, newcfg.PragueBlock)
+	}
+	if isForkIncompatible(c.OsakaBlock, newcfg.OsakaBlock, head) {
+		return newCompatError("Osaka fork block", c.OsakaBlock, newcfg.OsakaBlock)
+	}
+	if isForkIncompatible(c.EOFBlock, newcfg.EOFBlock, head) {
+		return newCompatError("EVM Object Format fork block", c.EOFBlock, newcfg.EOFBlock)
+	}
+	if isForkIncompatible(c.P256VerifyBlock, newcfg.P256VerifyBlock, head) {
+		return newCompatError("P256 verification block", c.P256VerifyBlock, newcfg.P256VerifyBlock)
+	}
 	return nil
 }
 
@@ -713,7 +923,8 @@ type Rules struct {
 	IsHomestead, IsEIP150, IsEIP155, IsEIP158               bool
 	IsByzantium, IsConstantinople, IsPetersburg, IsIstanbul bool
 	IsBerlin, IsLondon                                      bool
-	IsMerge                                                 bool
+	IsMerge, IsShanghai, IsCancun, IsPrague, IsOsaka        bool
+	IsEOF, IsP256Verify                                     bool
 }
 
 // Rules ensures c's ChainID is not nil.
@@ -735,5 +946,11 @@ func (c *ChainConfig) Rules(num *big.Int, isMerge bool) Rules {
 		IsBerlin:         c.IsBerlin(num),
 		IsLondon:         c.IsLondon(num),
 		IsMerge:          isMerge,
+		IsShanghai:       c.IsShanghai(num),
+		IsCancun:         c.IsCancun(num),
+		IsPrague:         c.IsPrague(num),
+		IsOsaka:          c.IsOsaka(num),
+		IsEOF:            c.IsEOF(num),
+		IsP256Verify:     c.IsP256Verify(num),
 	}
 }
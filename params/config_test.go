@@ -96,3 +96,42 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestCodeSizeLimits(t *testing.T) {
+	type test struct {
+		config         *ChainConfig
+		code, initCode int
+	}
+	tests := []test{
+		{config: &ChainConfig{}, code: MaxCodeSize, initCode: MaxInitCodeSize},
+		{config: &ChainConfig{MaxCodeSize: 65536}, code: 65536, initCode: 131072},
+		{config: &ChainConfig{MaxInitCodeSize: 100000}, code: MaxCodeSize, initCode: 100000},
+		{config: &ChainConfig{MaxCodeSize: 65536, MaxInitCodeSize: 70000}, code: 65536, initCode: 70000},
+	}
+	for i, test := range tests {
+		if have := test.config.CodeSizeLimit(); have != test.code {
+			t.Errorf("test %d: code size limit mismatch: have %d, want %d", i, have, test.code)
+		}
+		if have := test.config.InitCodeSizeLimit(); have != test.initCode {
+			t.Errorf("test %d: init code size limit mismatch: have %d, want %d", i, have, test.initCode)
+		}
+	}
+}
+
+func TestCheckEOFForkOrder(t *testing.T) {
+	for i, tt := range []struct {
+		osaka, eof *big.Int
+		ok         bool
+	}{
+		{nil, big.NewInt(0), true},
+		{big.NewInt(10), big.NewInt(10), true},
+		{big.NewInt(10), big.NewInt(20), true},
+		{big.NewInt(20), big.NewInt(10), false},
+	} {
+		config := *AllEthashProtocolChanges
+		config.OsakaBlock, config.EOFBlock = tt.osaka, tt.eof
+		if err := config.CheckConfigForkOrder(); (err == nil) != tt.ok {
+			t.Errorf("test %d: fork order error mismatch: have %v, want ok %v", i, err, tt.ok)
+		}
+	}
+}
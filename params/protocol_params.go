@@ -16,13 +16,19 @@
 
 package params
 
-import "math/big"
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
 
 const (
 	GasLimitBoundDivisor uint64 = 1024               // The bound divisor of the gas limit, used in update calculations.
 	MinGasLimit          uint64 = 5000               // Minimum the gas limit may ever be.
 	MaxGasLimit          uint64 = 0x7fffffffffffffff // Maximum the gas limit (2^63-1).
 	GenesisGasLimit      uint64 = 4712388            // Gas limit of the Genesis block.
+	MaxTxGas             uint64 = 1 << 24            // Maximum gas limit of a single transaction from Osaka (EIP-7825).
+	SystemCallGas        uint64 = 30_000_000         // Gas available to a system call executed at the start of a block.
 
 	MaximumExtraDataSize  uint64 = 32    // Maximum size extra data may be after Genesis.
 	ExpByteGas            uint64 = 10    // Times ceil(log256(exponent)) for the EXP instruction.
@@ -72,6 +78,7 @@ const (
 	EpochDuration uint64 = 30000 // Duration between proof-of-work epochs.
 
 	CreateDataGas         uint64 = 200   //
+	InitCodeWordGas       uint64 = 2     // Once per word of the init code when creating a contract (EIP-3860).
 	CallCreateDepth       uint64 = 1024  // Maximum depth of call/create stack.
 	ExpGas                uint64 = 10    // Once per EXP instruction
 	LogGas                uint64 = 375   // Per LOG* operation.
@@ -89,6 +96,11 @@ const (
 	TxAccessListAddressGas    uint64 = 2400 // Per address specified in EIP 2930 access list
 	TxAccessListStorageKeyGas uint64 = 1900 // Per storage key specified in EIP 2930 access list
 
+	TxTokenPerNonZeroByte uint64 = 4  // Token cost per non-zero byte as specified by EIP-7623
+	TxCostFloorPerToken   uint64 = 10 // Cost floor per byte of data as specified by EIP-7623
+
+	TxAuthTupleGas uint64 = 12500 // Per authorization tuple of an EIP 7702 set code transaction whose authority already exists
+
 	// These have been changed during the course of the chain
 	CallGasFrontier              uint64 = 40  // Once per CALL operation & message call transaction.
 	CallGasEIP150                uint64 = 700 // Static portion of gas for CALL-derivates after EIP 150 (Tangerine)
@@ -123,7 +135,15 @@ const (
 	ElasticityMultiplier     = 2          // Bounds the maximum gas limit an EIP-1559 block may have.
 	InitialBaseFee           = 1000000000 // Initial base fee for EIP-1559 blocks.
 
-	MaxCodeSize = 24576 // Maximum bytecode to permit for a contract
+	BlobTxBlobGasPerBlob             = 1 << 17 // Gas consumption of a single data blob (== blob byte size)
+	BlobTxMinBlobGasprice            = 1       // Minimum gas price for data blobs
+	BlobTxBlobGaspriceUpdateFraction = 3338477 // Controls the maximum rate of change for blob gas price
+	BlobTxHashVersion                = 0x01    // Version byte of the commitment hash
+	MaxBlobGasPerBlock               = 786432  // Maximum consumable blob gas for data blobs per block (6 blobs)
+	BlobTxTargetBlobGasPerBlock      = 393216  // Target consumable blob gas for data blobs per block (3 blobs)
+
+	MaxCodeSize     = 24576           // Maximum bytecode to permit for a contract
+	MaxInitCodeSize = 2 * MaxCodeSize // Maximum initcode to permit in a creation transaction (EIP-3860)
 
 	// Precompiled contract gas prices
 
@@ -153,6 +173,10 @@ const (
 	Bls12381MapG1Gas          uint64 = 5500   // Gas price for BLS12-381 mapping field element to G1 operation
 	Bls12381MapG2Gas          uint64 = 110000 // Gas price for BLS12-381 mapping field element to G2 operation
 
+	BlobTxPointEvaluationPrecompileGas uint64 = 50000 // Gas price for the point evaluation precompile
+
+	P256VerifyGas uint64 = 3450 // Gas price for the secp256r1 signature verification precompile
+
 	// The Refund Quotient is the cap on how much of the used gas can be refunded. Before EIP-3529,
 	// up to half the consumed gas could be refunded. Redefined as 1/5th in EIP-3529
 	RefundQuotient        uint64 = 2
@@ -168,3 +192,17 @@ var (
 	MinimumDifficulty      = big.NewInt(131072) // The minimum that the difficulty may ever be.
 	DurationLimit          = big.NewInt(13)     // The decision boundary on the blocktime duration used to determine whether difficulty should go up or not.
 )
+
+var (
+	// SystemAddress is the caller of the system calls executed at the start of
+	// a block to update the system contracts below.
+	SystemAddress = common.HexToAddress("0xfffffffffffffffffffffffffffffffffffffffe")
+
+	// BeaconRootsAddress is the address of the EIP-4788 contract storing the
+	// parent beacon block roots.
+	BeaconRootsAddress = common.HexToAddress("0x000F3df6D732807Ef1319fB7B8bB8522d0Beac02")
+
+	// HistoryStorageAddress is the address of the EIP-2935 contract storing the
+	// historical block hashes.
+	HistoryStorageAddress = common.HexToAddress("0x0000F90827F1C53a10cb7A02335B175320002935")
+)